@@ -3,8 +3,12 @@ package cli
 import (
 	"flag"
 	"fmt"
+	"math"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/GeoffMall/flow/internal/version"
 )
@@ -18,20 +22,51 @@ const (
 	reset = "\x1b[0m"       // reset color
 )
 
+// Supported values for the -from, -to and -compress flags.
+var (
+	inputFormats  = []string{"json", "yaml", "avro", "parquet", "csv", "tsv", "auto"}
+	outputFormats = []string{"json", "yaml", "avro", "parquet", "csv", "tsv"}
+	compressions  = []string{"gzip", "zstd", "snappy", "none"}
+)
+
 // Flags holds all parsed command-line arguments.
 type Flags struct {
+	SchemaMode        bool     // flow schema: describe the input's schema instead of processing it
 	InputFile         string   // file to read from (optional; defaults to stdin)
 	InputDir          string   // directory to read files from (optional; mutually exclusive with InputFile)
 	OutputFile        string   // file to write to (optional; defaults to stdout)
+	Compress          string   // output compression: gzip | zstd | snappy | none (defaults to the OutputFile extension)
+	IncludeGlobs      []string // with InputDir, only process files matching one of these globs
+	ExcludeGlobs      []string // with InputDir, skip files and directories matching any of these globs
+	MaxDepth          int      // with InputDir, how many directory levels to descend (0 = unlimited)
+	FollowSymlinks    bool     // with InputDir, walk into symlinked directories
+	Hidden            bool     // with InputDir, include files and directories starting with a dot
+	Jobs              int      // number of files processed concurrently in directory mode
+	Unordered         bool     // in directory mode, write documents as soon as they are ready instead of in file order
+	InPlace           bool     // rewrite the -in file (or each file under -in-dir) with the output
+	Backup            bool     // with InPlace, keep the original as <file>.bak
+	Force             bool     // with InPlace, allow the output format to differ from the input format
 	PickPaths         []string // list of dotted paths to pick
 	SetPairs          []string // raw key=value strings for --set
 	DeletePaths       []string // list of paths to delete
-	WherePairs        []string // list of key=value filters (AND'ed together)
+	FilterExpr        string   // boolean filter expression (and/or/not, comparisons, in, exists, matches)
+	WherePairs        []string // list of key<op>value filters, op is a comparison (= != > >= < <=) or match (~= *= %=) (AND'ed together)
 	Color             bool     // pretty colorized output (internal use)
 	NoColor           bool     // disable colorized output
 	Compact           bool     // minified output
-	FromFormat        string   // input format: json | yaml (defaults to json, or auto-detected from file extension)
-	ToFormat          string   // convert output format: json | yaml
+	FromFormat        string   // input format: json | yaml | avro | parquet | csv | tsv | auto (detected from the file extension or stdin's content if empty)
+	ToFormat          string   // convert output format: json | yaml | avro | parquet | csv | tsv
+	AvroSchemaFile    string   // path to an .avsc schema for avro output (optional; inferred if empty)
+	AvroReaderSchema  string   // path to an .avsc reader schema that avro input is resolved against (optional)
+	AvroRawUnions     bool     // keep avro union values of named types wrapped as {"TypeName": value}
+	AvroCodec         string   // avro output codec: null | deflate | snappy | zstd
+	AvroSampleSize    int      // number of documents used to infer the avro output schema
+	ParquetCodec      string   // parquet output compression: snappy | zstd | gzip | none
+	ParquetRowGroup   int      // number of rows per parquet output row group
+	ParquetSpoolLimit int64    // max bytes of non-seekable parquet input (stdin, compressed) spooled for reading (0 = unlimited)
+	CSVDelimiter      string   // single-character field delimiter for csv/tsv (defaults to the format's own)
+	CSVNoHeader       bool     // csv/tsv input has no header row, and output omits it
+	CSVInferTypes     bool     // convert csv/tsv cells that look like numbers/booleans into typed values
 	PreserveHierarchy bool     // preserve full path structure in pick output (legacy behavior)
 	ShowHelp          bool     // show help and exit
 	ShowVersion       bool     // show version and exit
@@ -49,26 +84,58 @@ func ParseFlags() *Flags {
 	var setPairs multiStringFlag
 	var deletePaths multiStringFlag
 	var wherePairs multiStringFlag
+	var includeGlobs multiStringFlag
+	var excludeGlobs multiStringFlag
+	var spoolLimit byteSizeFlag
 
 	flag.Var(&pickPaths, "pick", "Pick a key or path from the input (can be used multiple times)")
 	flag.Var(&setPairs, "set", "Set a key to a value (format: path=value, can be used multiple times)")
 	flag.Var(&deletePaths, "delete", "Delete a key or path from the input (can be used multiple times)")
-	flag.Var(&wherePairs, "where", "Filter rows where key=value (can be used multiple times, AND'ed together)")
+	flag.StringVar(&f.FilterExpr, "filter", "", "Keep documents matching a boolean expression, e.g. 'status=ERROR or latency>500' (supports and/or/not, parentheses, in [...], exists(path), matches(path, regex))")
+	flag.Var(&wherePairs, "where", "Filter rows by comparison: key=value, key!=value, key>value, key>=value, key<value, key<=value,\nor match: key~=regex, key*=substring, key%=glob (~i=, *i=, %i= ignore case); a missing key only matches != (can be used multiple times, AND'ed together)")
 
 	flag.StringVar(&f.InputFile, "in", "", "Path to input file (optional, defaults to stdin)")
 	flag.StringVar(&f.InputDir, "in-dir", "", "Path to input directory (process all matching files)")
 	flag.StringVar(&f.OutputFile, "out", "", "Path to output file (optional, defaults to stdout)")
+	flag.Var(&includeGlobs, "include", "With -in-dir, only process files matching a glob relative to the directory, e.g. 'date=2024-*/**/*.parquet' (can be used multiple times)")
+	flag.Var(&excludeGlobs, "exclude", "With -in-dir, skip files and directories matching a glob, e.g. '**/_tmp' (can be used multiple times)")
+	flag.IntVar(&f.MaxDepth, "max-depth", 0, "With -in-dir, descend at most this many directory levels (1 = only files directly in it, 0 = unlimited)")
+	flag.BoolVar(&f.FollowSymlinks, "follow-symlinks", false, "With -in-dir, walk into symlinked directories")
+	flag.BoolVar(&f.Hidden, "hidden", false, "With -in-dir, include files and directories whose names start with a dot")
+	flag.IntVar(&f.Jobs, "jobs", 1, "Number of files to process in parallel with -in-dir")
+	flag.BoolVar(&f.Unordered, "unordered", false, "With -jobs, write each file's results as soon as they are ready instead of in directory order")
+	flag.BoolVar(&f.InPlace, "in-place", false, "Edit the -in file (or every file under -in-dir) in place instead of writing to stdout")
+	flag.BoolVar(&f.Backup, "backup", false, "With -in-place, keep a copy of each original file as <file>.bak")
+	flag.BoolVar(&f.Force, "force", false, "With -in-place, allow -to to change the file's format")
+	flag.StringVar(&f.Compress, "compress", "", "Compress output: gzip | zstd | snappy | none (default: from the -out extension, e.g. .gz or .zst; compressed input is always detected)")
 	flag.BoolVar(&f.NoColor, "no-color", false, "Disable colorized output")
 	flag.BoolVar(&f.Compact, "compact", false, "Minify output instead of pretty-printing")
-	flag.StringVar(&f.FromFormat, "from", "", "Input format: json | yaml (if not specified, detected from file extension or defaults to json)")
-	flag.StringVar(&f.ToFormat, "to", "", "Convert output format: json | yaml")
+	flag.StringVar(&f.FromFormat, "from", "", "Input format: json | yaml | avro | parquet | csv | tsv, or auto to detect each file's format from its extension and content (if not specified, detected from the file extension, or from the content of stdin)")
+	flag.StringVar(&f.ToFormat, "to", "", "Convert output format: json | yaml | avro | parquet | csv | tsv")
+	flag.StringVar(&f.AvroSchemaFile, "avro-schema", "", "Path to an Avro schema (.avsc) for -to avro (inferred from the data if not specified)")
+	flag.StringVar(&f.AvroReaderSchema, "avro-reader-schema", "", "Path to an Avro schema (.avsc) that avro input is resolved against, so files written with older or newer versions of it read the same (default: each file's own schema)")
+	flag.BoolVar(&f.AvroRawUnions, "avro-raw-unions", false, "Keep avro union values of records, enums and fixed types wrapped as {\"TypeName\": value} instead of unwrapping them")
+	flag.StringVar(&f.AvroCodec, "avro-codec", "null", "Compression codec for -to avro: null | deflate | snappy | zstd")
+	flag.IntVar(&f.AvroSampleSize, "avro-sample-size", 100, "Number of documents used to infer the schema for -to avro")
+	flag.StringVar(&f.ParquetCodec, "parquet-compression", "snappy", "Compression codec for -to parquet: snappy | zstd | gzip | none")
+	flag.IntVar(&f.ParquetRowGroup, "parquet-row-group-size", 10000, "Rows per row group for -to parquet (the schema is inferred from the first row group)")
+	flag.Var(&spoolLimit, "parquet-spool-limit", "Largest parquet input read from stdin or a compressed file, which is copied to memory or a temporary file first, e.g. 512MB or 2GB (default: unlimited)")
+	flag.StringVar(&f.CSVDelimiter, "csv-delimiter", "", "Field delimiter for csv/tsv input and output (single character, or 'tab')")
+	flag.BoolVar(&f.CSVNoHeader, "csv-no-header", false, "csv/tsv input has no header row (columns become col1, col2, ...); output omits the header")
+	flag.BoolVar(&f.CSVInferTypes, "csv-infer-types", false, "Convert csv/tsv cells that look like numbers or booleans into typed values")
 	flag.BoolVar(&f.PreserveHierarchy, "preserve-hierarchy", false, "Preserve full path structure in pick output (default: false, outputs values like jq)")
 	flag.BoolVar(&f.ShowHelp, "help", false, "Show usage")
 	flag.BoolVar(&f.ShowVersion, "version", false, "Show version information")
 
 	flag.Usage = usage
 
-	flag.Parse()
+	// "flow schema [flags]" describes the input instead of processing it
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "schema" {
+		f.SchemaMode = true
+		args = args[1:]
+	}
+	_ = flag.CommandLine.Parse(args) // exits on error
 
 	// If help was requested, print and exit
 	if f.ShowHelp {
@@ -86,6 +153,9 @@ func ParseFlags() *Flags {
 	f.SetPairs = setPairs
 	f.DeletePaths = deletePaths
 	f.WherePairs = wherePairs
+	f.IncludeGlobs = includeGlobs
+	f.ExcludeGlobs = excludeGlobs
+	f.ParquetSpoolLimit = int64(spoolLimit)
 
 	// Validate input flags - cannot use both -in and -in-dir
 	if f.InputFile != "" && f.InputDir != "" {
@@ -94,22 +164,88 @@ func ParseFlags() *Flags {
 		os.Exit(1)
 	}
 
+	if f.MaxDepth < 0 {
+		printLinef("Error: invalid --max-depth %d. It must be 0 (unlimited) or more.\n", f.MaxDepth)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if f.Jobs < 1 {
+		printLinef("Error: invalid --jobs %d. It must be at least 1.\n", f.Jobs)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Validate in-place flags - needs a file to rewrite and nowhere else to write
+	if f.InPlace && f.InputFile == "" && f.InputDir == "" {
+		printLinef("Error: -in-place requires -in or -in-dir.\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if f.InPlace && f.OutputFile != "" {
+		printLinef("Error: cannot use -in-place and -out flags together.\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if (f.Backup || f.Force) && !f.InPlace {
+		printLinef("Error: -backup and -force can only be used with -in-place.\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
 	// Validate format flags
-	if f.FromFormat != "" && f.FromFormat != "json" && f.FromFormat != "yaml" && f.FromFormat != "avro" && f.FromFormat != "parquet" {
-		printLinef("Error: invalid format '%s' for --from flag. Supported formats are 'json', 'yaml', 'avro', and 'parquet'.\n", f.FromFormat)
+	if f.FromFormat != "" && !slices.Contains(inputFormats, f.FromFormat) {
+		printLinef("Error: invalid format '%s' for --from flag. Supported formats are: %s.\n", f.FromFormat, strings.Join(inputFormats, ", "))
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if f.ToFormat != "" && !slices.Contains(outputFormats, f.ToFormat) {
+		printLinef("Error: invalid format '%s' for --to flag. Supported formats are: %s.\n", f.ToFormat, strings.Join(outputFormats, ", "))
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	if f.ToFormat != "" && f.ToFormat != "json" && f.ToFormat != "yaml" {
-		printLinef("Error: invalid format '%s' for --to flag. Supported formats are 'json' and 'yaml'.\n", f.ToFormat)
+	if f.Compress != "" && !slices.Contains(compressions, f.Compress) {
+		printLinef("Error: invalid compression '%s' for --compress flag. Supported values are: %s (bzip2 is supported for input only).\n", f.Compress, strings.Join(compressions, ", "))
 		flag.Usage()
 		os.Exit(1)
 	}
 
+	if f.CSVDelimiter == "tab" || f.CSVDelimiter == `\t` {
+		f.CSVDelimiter = "\t"
+	}
+	if f.CSVDelimiter != "" && utf8.RuneCountInString(f.CSVDelimiter) != 1 {
+		printLinef("Error: invalid --csv-delimiter '%s'. It must be a single character.\n", f.CSVDelimiter)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Validate schema mode - it reads a single input and only writes a report
+	if f.SchemaMode {
+		if msg := schemaModeError(f); msg != "" {
+			printLinef("Error: %s\n", msg)
+			flag.Usage()
+			os.Exit(1)
+		}
+	}
+
 	return f
 }
 
+// schemaModeError returns what is wrong with flags given to flow schema, or "".
+func schemaModeError(f *Flags) string {
+	switch {
+	case f.InputDir != "" || f.InPlace:
+		return "flow schema reads a single input (-in or stdin); -in-dir and -in-place are not supported."
+	case len(f.PickPaths) > 0 || len(f.SetPairs) > 0 || len(f.DeletePaths) > 0 || len(f.WherePairs) > 0 || f.FilterExpr != "":
+		return "flow schema describes the whole input; -pick, -set, -delete, -where and -filter are not supported."
+	case f.ToFormat != "" && f.ToFormat != "json" && f.ToFormat != "yaml":
+		return fmt.Sprintf("flow schema writes its report as json or yaml, not '%s'.", f.ToFormat)
+	}
+	return ""
+}
+
 type multiStringFlag []string
 
 func (m *multiStringFlag) String() string {
@@ -122,6 +258,48 @@ func (m *multiStringFlag) Set(value string) error {
 	return nil
 }
 
+// byteSizeFlag is a size in bytes, given as a number with an optional unit:
+// K, M, G or T (powers of 1024), optionally followed by B or iB.
+type byteSizeFlag int64
+
+func (b *byteSizeFlag) String() string {
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *byteSizeFlag) Set(value string) error {
+	size, err := parseByteSize(value)
+	if err != nil {
+		return err
+	}
+	*b = byteSizeFlag(size)
+	return nil
+}
+
+// parseByteSize parses a size such as "1048576", "512K", "64MB" or "2GiB".
+func parseByteSize(s string) (int64, error) {
+	units := map[string]int64{"": 1, "B": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}
+
+	num := strings.TrimSpace(s)
+	i := strings.IndexFunc(num, func(r rune) bool { return r < '0' || r > '9' })
+	unit := ""
+	if i >= 0 {
+		num, unit = num[:i], strings.ToUpper(strings.TrimSpace(num[i:]))
+		if len(unit) > 1 {
+			unit = strings.TrimSuffix(strings.TrimSuffix(unit, "B"), "I")
+		}
+	}
+
+	mult, ok := units[unit]
+	n, err := strconv.ParseInt(num, 10, 64)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("invalid size %q (use a number of bytes with an optional K, M, G or T unit)", s)
+	}
+	if n > math.MaxInt64/mult {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return n * mult, nil
+}
+
 // asciiArt returns the colored ASCII art banner for "flow"
 func asciiArt() string {
 	art := cyan1 + "######## ##        " + cyan2 + "#######  " + cyan3 + "##      ## " + reset + "\n"
@@ -138,11 +316,15 @@ func asciiArt() string {
 func usage() {
 	// Display ASCII art banner at the top
 	printLinef("%s", asciiArt())
-	printLinef("Usage: flow [flags]\n\n")
+	printLinef("Usage: flow [flags]\n")
+	printLinef("       flow schema [flags]  # describe the input's schema instead of processing it\n\n")
 	printLinef("Examples:\n")
 	printLinef("  cat data.json | flow --pick user.name --pick user.id  # outputs: {\"name\": \"alice\", \"id\": 7}\n")
 	printLinef("  cat data.json | flow --pick user.name                 # outputs: \"alice\"\n")
 	printLinef("  flow config.yaml --set server.port=8080 --delete debug --to json\n")
+	printLinef("  flow --in logs.json --filter 'status=ERROR or latency>500'\n")
+	printLinef("  flow --in values.yaml --set image.tag=v2 --in-place --backup\n")
+	printLinef("  flow schema --in events.parquet                      # schema, row groups and column statistics\n")
 	printLinef("\nFlags:\n")
 	flag.PrintDefaults()
 }
@@ -96,6 +96,19 @@ func TestParseFlags_WithWhere(t *testing.T) {
 	})
 }
 
+func TestParseFlags_WithFilter(t *testing.T) {
+	resetGlobalFlags()
+
+	args := []string{
+		"--filter", "status=ERROR or latency>500",
+	}
+
+	withArgs(t, args, func() {
+		f := ParseFlags()
+		assert.Equal(t, "status=ERROR or latency>500", f.FilterExpr)
+	})
+}
+
 func TestParseFlags_WithInputDir(t *testing.T) {
 	resetGlobalFlags()
 
@@ -138,6 +151,42 @@ func TestParseFlags_ParquetFormat(t *testing.T) {
 	})
 }
 
+func TestParseFlags_AvroOutput(t *testing.T) {
+	resetGlobalFlags()
+
+	args := []string{
+		"--to", "avro",
+		"--avro-schema", "user.avsc",
+		"--avro-codec", "snappy",
+		"--avro-sample-size", "10",
+	}
+
+	withArgs(t, args, func() {
+		f := ParseFlags()
+		assert.Equal(t, "avro", f.ToFormat)
+		assert.Equal(t, "user.avsc", f.AvroSchemaFile)
+		assert.Equal(t, "snappy", f.AvroCodec)
+		assert.Equal(t, 10, f.AvroSampleSize)
+	})
+}
+
+func TestParseFlags_ParquetOutput(t *testing.T) {
+	resetGlobalFlags()
+
+	args := []string{
+		"--to", "parquet",
+		"--parquet-compression", "zstd",
+		"--parquet-row-group-size", "500",
+	}
+
+	withArgs(t, args, func() {
+		f := ParseFlags()
+		assert.Equal(t, "parquet", f.ToFormat)
+		assert.Equal(t, "zstd", f.ParquetCodec)
+		assert.Equal(t, 500, f.ParquetRowGroup)
+	})
+}
+
 func TestParseFlags_PreserveHierarchy(t *testing.T) {
 	resetGlobalFlags()
 
@@ -198,3 +247,79 @@ func TestMultiStringFlag_Set(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []string{"value1", "value2"}, []string(msf))
 }
+
+func TestParseFlags_CSVOptions(t *testing.T) {
+	resetGlobalFlags()
+
+	args := []string{
+		"--from", "csv",
+		"--to", "tsv",
+		"--csv-delimiter", "tab",
+		"--csv-no-header",
+		"--csv-infer-types",
+	}
+
+	withArgs(t, args, func() {
+		f := ParseFlags()
+		assert.Equal(t, "csv", f.FromFormat)
+		assert.Equal(t, "tsv", f.ToFormat)
+		assert.Equal(t, "\t", f.CSVDelimiter)
+		assert.True(t, f.CSVNoHeader)
+		assert.True(t, f.CSVInferTypes)
+	})
+}
+
+func TestParseByteSize(t *testing.T) {
+	valid := map[string]int64{
+		"0":       0,
+		"1048576": 1 << 20,
+		"512K":    512 << 10,
+		"64MB":    64 << 20,
+		"2GiB":    2 << 30,
+		"1 tb":    1 << 40,
+		"10b":     10,
+	}
+	for in, want := range valid {
+		got, err := parseByteSize(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	for _, in := range []string{"", "MB", "-1", "1.5G", "12X", "9999999999T"} {
+		_, err := parseByteSize(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestParseFlags_ParquetSpoolLimit(t *testing.T) {
+	resetGlobalFlags()
+
+	withArgs(t, []string{"--from", "parquet", "--parquet-spool-limit", "256MB"}, func() {
+		f := ParseFlags()
+		assert.Equal(t, int64(256<<20), f.ParquetSpoolLimit)
+	})
+}
+
+func TestParseFlags_SchemaMode(t *testing.T) {
+	resetGlobalFlags()
+
+	withArgs(t, []string{"schema", "--in", "events.parquet", "--to", "yaml"}, func() {
+		f := ParseFlags()
+		assert.True(t, f.SchemaMode)
+		assert.Equal(t, "events.parquet", f.InputFile)
+		assert.Equal(t, "yaml", f.ToFormat)
+	})
+
+	resetGlobalFlags()
+	withArgs(t, []string{"--in", "schema"}, func() {
+		f := ParseFlags()
+		assert.False(t, f.SchemaMode, "only the first argument names the mode")
+	})
+}
+
+func TestSchemaModeError(t *testing.T) {
+	assert.Empty(t, schemaModeError(&Flags{SchemaMode: true, InputFile: "a.avro", ToFormat: "json"}))
+	assert.NotEmpty(t, schemaModeError(&Flags{SchemaMode: true, InputDir: "data"}))
+	assert.NotEmpty(t, schemaModeError(&Flags{SchemaMode: true, WherePairs: []string{"a=1"}}))
+	assert.NotEmpty(t, schemaModeError(&Flags{SchemaMode: true, ToFormat: "parquet"}))
+}
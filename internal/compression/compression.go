@@ -0,0 +1,239 @@
+// Package compression handles compressed input and output streams for flow.
+//
+// Input is decompressed transparently: a compressed stream is recognized by
+// its magic bytes, so neither the file name nor a flag is needed. Output is
+// compressed with a codec chosen by -compress or by the output file's
+// extension. Supported codecs are gzip, zstd, bzip2 (input only) and snappy
+// (the framed stream format, as written by most snappy tools).
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec is a compression format.
+type Codec struct {
+	// Name identifies the codec in -compress (e.g. "gzip").
+	Name string
+
+	// Extensions are the file suffixes used for the codec, with the dot.
+	Extensions []string
+
+	magics    [][]byte // any of these starts a stream
+	newReader func(io.Reader) (io.Reader, error)
+	newWriter func(io.Writer) (io.WriteCloser, error) // nil if the codec can't be written
+}
+
+var codecs = []*Codec{
+	{
+		Name:       "gzip",
+		Extensions: []string{".gz", ".gzip"},
+		magics:     [][]byte{{0x1f, 0x8b}},
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+		newWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+	},
+	{
+		Name:       "zstd",
+		Extensions: []string{".zst", ".zstd"},
+		magics:     [][]byte{{0x28, 0xb5, 0x2f, 0xfd}},
+		newReader: func(r io.Reader) (io.Reader, error) {
+			// A single decoder decodes synchronously, so nothing needs closing
+			return zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		},
+		newWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w)
+		},
+	},
+	{
+		Name:       "bzip2",
+		Extensions: []string{".bz2"},
+		magics:     bzip2Magics(),
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return bzip2.NewReader(r), nil
+		},
+	},
+	{
+		Name:       "snappy",
+		Extensions: []string{".sz", ".snappy"},
+		magics:     [][]byte{[]byte("\xff\x06\x00\x00sNaPpY")},
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return snappy.NewReader(r), nil
+		},
+		newWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return snappy.NewBufferedWriter(w), nil
+		},
+	},
+}
+
+// maxMagic is the length of the longest magic number.
+const maxMagic = 10
+
+// bzip2Magics returns the starts of a bzip2 stream: "BZh", the block size
+// '1' to '9', then the magic of the first block or, for empty input, of the
+// end of the stream. "BZh" alone is too common in text to go by.
+func bzip2Magics() [][]byte {
+	var magics [][]byte
+	for size := byte('1'); size <= '9'; size++ {
+		for _, next := range []string{"1AY&SY", "\x17rE8P\x90"} {
+			magics = append(magics, append([]byte{'B', 'Z', 'h', size}, next...))
+		}
+	}
+	return magics
+}
+
+// Get returns the codec called name.
+func Get(name string) (*Codec, error) {
+	for _, c := range codecs {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown compression %q (supported: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Names returns the names of all codecs.
+func Names() []string {
+	names := make([]string, len(codecs))
+	for i, c := range codecs {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// ForPath returns the codec whose extension path ends with, or nil.
+func ForPath(path string) *Codec {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, c := range codecs {
+		for _, e := range c.Extensions {
+			if ext == e {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// TrimExt removes a compression extension from path, so "events.json.gz"
+// becomes "events.json". Other paths are returned unchanged.
+func TrimExt(path string) string {
+	if ForPath(path) == nil {
+		return path
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+// Ext returns the extension of path that names its data format, looking
+// through a compression extension: ".json" for both "a.json" and "a.json.gz".
+// The result is lower case.
+func Ext(path string) string {
+	return strings.ToLower(filepath.Ext(TrimExt(path)))
+}
+
+// NewReader returns a reader of the decompressed content of r, and the codec
+// it was compressed with. If r is not compressed, the codec is nil and r is
+// returned unchanged when it is an io.ReaderAt that can be read (such as a
+// regular *os.File), so formats that need random access keep working. Other
+// readers, like pipes, come back wrapped in a buffer holding the bytes that
+// were inspected.
+func NewReader(r io.Reader) (io.Reader, *Codec, error) {
+	if ra, ok := r.(io.ReaderAt); ok {
+		head := make([]byte, maxMagic)
+		n, err := ra.ReadAt(head, 0)
+		if err == nil || errors.Is(err, io.EOF) {
+			codec := match(head[:n])
+			if codec == nil {
+				return r, nil, nil
+			}
+			return open(codec, r)
+		}
+		// Not actually seekable (e.g. stdin on a pipe), so peek instead
+	}
+
+	br := bufio.NewReader(r)
+	codec, err := peekMagic(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	if codec == nil {
+		return br, nil, nil
+	}
+	return open(codec, br)
+}
+
+// NewWriter returns a writer compressing to w. Closing it flushes the
+// compressed stream but does not close w.
+func (c *Codec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if c.newWriter == nil {
+		return nil, fmt.Errorf("%s compression is only supported for input", c.Name)
+	}
+	return c.newWriter(w)
+}
+
+// open starts decompressing r with codec.
+func open(codec *Codec, r io.Reader) (io.Reader, *Codec, error) {
+	dr, err := codec.newReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", codec.Name, err)
+	}
+	return dr, codec, nil
+}
+
+// match returns the codec whose magic number head starts with, or nil.
+func match(head []byte) *Codec {
+	for _, c := range codecs {
+		for _, magic := range c.magics {
+			if bytes.HasPrefix(head, magic) {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// peekMagic peeks at br one byte at a time for as long as a magic number could
+// still match, so a slow uncompressed stream isn't held up.
+func peekMagic(br *bufio.Reader) (*Codec, error) {
+	for n := 1; n <= maxMagic; n++ {
+		head, err := br.Peek(n)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		if codec := match(head); codec != nil {
+			return codec, nil
+		}
+		if !couldMatch(head) {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// couldMatch reports whether head is the start of some magic number.
+func couldMatch(head []byte) bool {
+	for _, c := range codecs {
+		for _, magic := range c.magics {
+			if bytes.HasPrefix(magic, head) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sample = `{"a": 1}` + "\n"
+
+// bzip2Sample is sample compressed with the bzip2 tool (the standard library can only decompress bzip2).
+const bzip2Sample = "\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\xd8\x0a\xe9\xc6\x00\x00\x03\xd9\x80\x00\x10\x50\x00\x20\x10\x20\x00\x00\x0a\x20\x00\x31\x0c\x08\x20\x33\x49\x19\x19\x44\xf1\x77\x24\x53\x85\x09\x0d\x80\xae\x9c\x60"
+
+func compress(t *testing.T, name, data string) []byte {
+	t.Helper()
+	codec, err := Get(name)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := codec.NewWriter(&buf)
+	require.NoError(t, err)
+	_, err = w.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestNewReader_DetectsCodecs(t *testing.T) {
+	inputs := map[string][]byte{
+		"gzip":   compress(t, "gzip", sample),
+		"zstd":   compress(t, "zstd", sample),
+		"snappy": compress(t, "snappy", sample),
+		"bzip2":  []byte(bzip2Sample),
+	}
+
+	for name, data := range inputs {
+		t.Run(name, func(t *testing.T) {
+			// Seekable input is inspected with ReadAt
+			r, codec, err := NewReader(bytes.NewReader(data))
+			require.NoError(t, err)
+			require.NotNil(t, codec)
+			assert.Equal(t, name, codec.Name)
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, sample, string(got))
+
+			// Streams are peeked at
+			r, codec, err = NewReader(iotest.OneByteReader(bytes.NewReader(data)))
+			require.NoError(t, err)
+			require.NotNil(t, codec)
+			assert.Equal(t, name, codec.Name)
+			got, err = io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, sample, string(got))
+		})
+	}
+}
+
+func TestNewReader_Uncompressed(t *testing.T) {
+	for _, data := range []string{sample, "", "B", "\x1f", "BZ not bzip2", "BZh not bzip2", "BZh9 not bzip2", "BZh0" + bzip2Sample[4:]} {
+		seekable := bytes.NewReader([]byte(data))
+		r, codec, err := NewReader(seekable)
+		require.NoError(t, err)
+		assert.Nil(t, codec)
+		assert.Same(t, seekable, r, "seekable input is returned as is")
+
+		r, codec, err = NewReader(iotest.OneByteReader(bytes.NewReader([]byte(data))))
+		require.NoError(t, err)
+		assert.Nil(t, codec)
+		got, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, data, string(got))
+	}
+}
+
+func TestNewReader_EmptyBzip2(t *testing.T) {
+	// What the bzip2 tool writes for empty input: no blocks, just the end of the stream
+	empty := "BZh9\x17rE8P\x90\x00\x00\x00\x00"
+
+	r, codec, err := NewReader(bytes.NewReader([]byte(empty)))
+	require.NoError(t, err)
+	require.NotNil(t, codec)
+	assert.Equal(t, "bzip2", codec.Name)
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func TestNewReader_CorruptStream(t *testing.T) {
+	_, _, err := NewReader(bytes.NewReader([]byte{0x1f, 0x8b, 0x00}))
+	assert.ErrorContains(t, err, "gzip")
+}
+
+func TestGet(t *testing.T) {
+	_, err := Get("lz4")
+	assert.ErrorContains(t, err, "supported: gzip, zstd, bzip2, snappy")
+
+	codec, err := Get("bzip2")
+	require.NoError(t, err)
+	_, err = codec.NewWriter(io.Discard)
+	assert.ErrorContains(t, err, "only supported for input")
+}
+
+func TestExtensions(t *testing.T) {
+	assert.Equal(t, "gzip", ForPath("events.json.GZ").Name)
+	assert.Equal(t, "zstd", ForPath("events.zst").Name)
+	assert.Nil(t, ForPath("events.json"))
+
+	assert.Equal(t, "logs/events.json", TrimExt("logs/events.json.gz"))
+	assert.Equal(t, "logs/events.json", TrimExt("logs/events.json"))
+
+	assert.Equal(t, ".json", Ext("events.JSON.bz2"))
+	assert.Equal(t, ".yaml", Ext("values.yaml"))
+	assert.Equal(t, "", Ext("events.gz"))
+}
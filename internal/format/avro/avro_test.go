@@ -2,11 +2,14 @@ package avro
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"strings"
 	"testing"
 
 	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/ordered"
+	"github.com/hamba/avro/v2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,7 +19,7 @@ func TestParser_MultipleRecords(t *testing.T) {
 	assert.NoError(t, err)
 	defer f.Close()
 
-	parser, err := NewParser(f)
+	parser, err := NewParser(f, format.ParserOptions{})
 	assert.NoError(t, err)
 
 	// Collect all records
@@ -54,7 +57,7 @@ func TestParser_SingleRecord(t *testing.T) {
 	assert.NoError(t, err)
 	defer f.Close()
 
-	parser, err := NewParser(f)
+	parser, err := NewParser(f, format.ParserOptions{})
 	assert.NoError(t, err)
 
 	// Collect all records
@@ -82,7 +85,7 @@ func TestParser_InvalidFile(t *testing.T) {
 	assert.NoError(t, err)
 	defer f.Close()
 
-	_, err = NewParser(f)
+	_, err = NewParser(f, format.ParserOptions{})
 	assert.Error(t, err, "should fail to parse non-Avro file")
 }
 
@@ -92,7 +95,7 @@ func TestParser_EarlyReturn(t *testing.T) {
 	assert.NoError(t, err)
 	defer f.Close()
 
-	parser, err := NewParser(f)
+	parser, err := NewParser(f, format.ParserOptions{})
 	assert.NoError(t, err)
 
 	// Process only first record
@@ -118,7 +121,7 @@ func TestFormat_NewParser_Success(t *testing.T) {
 	assert.NoError(t, err)
 	defer file.Close()
 
-	parser, err := f.NewParser(file)
+	parser, err := f.NewParser(file, format.ParserOptions{})
 	assert.NoError(t, err)
 	assert.NotNil(t, parser)
 }
@@ -127,15 +130,205 @@ func TestFormat_NewParser_Error(t *testing.T) {
 	f := &Format{}
 	r := strings.NewReader("not avro data")
 
-	_, err := f.NewParser(r)
+	_, err := f.NewParser(r, format.ParserOptions{})
 	assert.Error(t, err)
 }
 
-func TestFormat_NewFormatter_Panics(t *testing.T) {
-	f := &Format{}
+// roundTrip writes docs with the given options and reads them back.
+func roundTrip(t *testing.T, opts format.FormatterOptions, docs ...any) []map[string]any {
+	t.Helper()
+
 	var buf bytes.Buffer
+	f, err := (&Format{}).NewFormatter(&buf, opts)
+	assert.NoError(t, err)
+	for _, doc := range docs {
+		assert.NoError(t, f.Write(doc))
+	}
+	assert.NoError(t, f.Close())
+
+	parser, err := NewParser(&buf, format.ParserOptions{})
+	assert.NoError(t, err)
+
+	var records []map[string]any
+	err = parser.ForEach(func(doc any) error {
+		records = append(records, doc.(map[string]any))
+		return nil
+	})
+	assert.NoError(t, err)
+	return records
+}
+
+func TestFormatter_InferredSchema(t *testing.T) {
+	records := roundTrip(t, format.FormatterOptions{},
+		map[string]any{"name": "Alice", "age": float64(30), "tags": []any{"a"}, "address": map[string]any{"city": "Paris"}},
+		map[string]any{"name": "Bob", "age": float64(25), "tags": []any{}, "address": map[string]any{"city": "Rome"}},
+	)
+
+	assert.Len(t, records, 2)
+	assert.Equal(t, "Alice", records[0]["name"])
+	assert.Equal(t, int64(30), records[0]["age"])
+	assert.Equal(t, []any{"a"}, records[0]["tags"])
+	assert.Equal(t, map[string]any{"city": "Rome"}, records[1]["address"])
+}
+
+func TestFormatter_InferredSchema_NullableAndWidening(t *testing.T) {
+	records := roundTrip(t, format.FormatterOptions{},
+		map[string]any{"id": float64(1), "score": float64(1)},
+		map[string]any{"id": float64(2), "score": 2.5, "note": "late field"},
+		map[string]any{"id": float64(3), "score": nil},
+	)
+
+	assert.Len(t, records, 3)
+	assert.Equal(t, 1.0, records[0]["score"])
+	assert.Equal(t, 2.5, records[1]["score"])
+	assert.Nil(t, records[2]["score"])
+	assert.Nil(t, records[0]["note"])
+	assert.Equal(t, "late field", records[1]["note"])
+}
+
+func TestInferSchema_FieldOrder(t *testing.T) {
+	fieldNames := func(schema avro.Schema) []string {
+		var names []string
+		for _, f := range schema.(*avro.RecordSchema).Fields() {
+			names = append(names, f.Name())
+		}
+		return names
+	}
+
+	address := ordered.NewMap()
+	address.Set("zip", "75001")
+	address.Set("city", "Paris")
+	first := ordered.NewMap()
+	first.Set("name", "Alice")
+	first.Set("address", address)
+	first.Set("age", float64(30))
+	second := ordered.NewMap()
+	second.Set("email", "bob@example.com")
+	second.Set("age", float64(25))
+	second.Set("name", "Bob")
+
+	schema, err := inferSchema([]any{first, second, map[string]any{"b": 1, "a": 2}})
+	assert.NoError(t, err)
+	// New fields follow in the order they appear; map[string]any keys are sorted
+	assert.Equal(t, []string{"name", "address", "age", "email", "a", "b"}, fieldNames(schema))
+
+	nested := schema.(*avro.RecordSchema).Fields()[1].Type().(*avro.UnionSchema).Types()[1]
+	assert.Equal(t, []string{"zip", "city"}, fieldNames(nested))
+}
+
+func TestFormatter_SanitizesFieldNames(t *testing.T) {
+	records := roundTrip(t, format.FormatterOptions{},
+		map[string]any{"_file": "a.avro", "data-set": "x", "1st": true},
+	)
+
+	assert.Len(t, records, 1)
+	assert.Equal(t, "x", records[0]["data_set"])
+	assert.Equal(t, true, records[0]["_1st"])
+}
+
+func TestFormatter_ExplicitSchema(t *testing.T) {
+	schema := `{"type":"record","name":"User","fields":[
+		{"name":"name","type":"string"},
+		{"name":"age","type":"int"},
+		{"name":"role","type":"string","default":"user"}
+	]}`
+
+	records := roundTrip(t, format.FormatterOptions{Schema: schema},
+		map[string]any{"name": "Alice", "age": 30},
+	)
+
+	assert.Len(t, records, 1)
+	assert.Equal(t, "Alice", records[0]["name"])
+	assert.Equal(t, 30, records[0]["age"])
+	assert.Equal(t, "user", records[0]["role"])
+}
+
+func TestFormatter_Codecs(t *testing.T) {
+	for _, codec := range []string{"null", "deflate", "snappy", "zstd"} {
+		t.Run(codec, func(t *testing.T) {
+			records := roundTrip(t, format.FormatterOptions{Codec: codec},
+				map[string]any{"name": "Alice"},
+			)
+			assert.Equal(t, []map[string]any{{"name": "Alice"}}, records)
+		})
+	}
+}
+
+func TestFormatter_UnknownCodec(t *testing.T) {
+	_, err := (&Format{}).NewFormatter(&bytes.Buffer{}, format.FormatterOptions{Codec: "lzma"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported avro codec")
+}
+
+func TestFormatter_InvalidSchema(t *testing.T) {
+	_, err := (&Format{}).NewFormatter(&bytes.Buffer{}, format.FormatterOptions{Schema: `{"type":"nope"}`})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid avro schema")
+}
+
+func TestFormatter_DocumentDoesNotFitSchema(t *testing.T) {
+	var buf bytes.Buffer
+	f, err := NewFormatter(&buf, format.FormatterOptions{SampleSize: 1})
+	assert.NoError(t, err)
+
+	assert.NoError(t, f.Write(map[string]any{"age": float64(30)}))
+
+	err = f.Write(map[string]any{"age": "thirty"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "document 2")
+	assert.Contains(t, err.Error(), `field "age"`)
+
+	err = f.Write(map[string]any{"age": float64(1), "extra": true})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `field "extra" is not in the avro schema`)
+}
+
+func TestFormatter_ConflictingTypesInSample(t *testing.T) {
+	var buf bytes.Buffer
+	f, err := NewFormatter(&buf, format.FormatterOptions{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, f.Write(map[string]any{"id": "a"}))
+	assert.NoError(t, f.Write(map[string]any{"id": float64(1)}))
+
+	err = f.Close()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "conflicting types")
+}
+
+func TestFormatter_NonObjectDocument(t *testing.T) {
+	var buf bytes.Buffer
+	f, err := NewFormatter(&buf, format.FormatterOptions{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, f.Write("just a string"))
+	err = f.Close()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "requires objects")
+}
+
+func TestParser_Describe(t *testing.T) {
+	f, err := os.Open("testdata/users.avro")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	parser, err := NewParser(f, format.ParserOptions{})
+	assert.NoError(t, err)
+
+	desc, err := parser.Describe()
+	assert.NoError(t, err)
+	out, err := json.Marshal(desc)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"format": "avro",
+		"codec": "null",
+		"schema": {"type": "record", "name": "User", "fields": [
+			{"name": "name", "type": "string"},
+			{"name": "age", "type": "int"},
+			{"name": "active", "type": "boolean"}
+		]}
+	}`, string(out))
 
-	assert.Panics(t, func() {
-		_ = f.NewFormatter(&buf, format.FormatterOptions{})
-	}, "NewFormatter should panic as Avro write is not supported")
+	// Describing reads only the header, so the records are still there
+	assert.NoError(t, parser.ForEach(func(any) error { return nil }))
 }
@@ -0,0 +1,153 @@
+package avro
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/ordered"
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/ocf"
+)
+
+// DefaultSampleSize is the number of documents buffered to infer a schema
+// when no explicit schema is given.
+const DefaultSampleSize = 100
+
+// Formatter implements format.Formatter for Avro OCF (Object Container File) output.
+//
+// If no schema is supplied, the first SampleSize documents are buffered and a
+// record schema is inferred from them before anything is written. Documents
+// written after that must fit the inferred schema.
+type Formatter struct {
+	w          io.Writer
+	codec      ocf.CodecName
+	sampleSize int
+
+	schema  avro.Schema
+	enc     *ocf.Encoder
+	pending []any // documents buffered for schema inference
+	written int   // number of documents handed to Write
+}
+
+// NewFormatter creates a new Avro formatter.
+// opts.Schema may hold an Avro schema document; opts.Codec selects the block
+// compression codec (null, deflate, snappy or zstd).
+// Color and Compact options are ignored for this binary format.
+func NewFormatter(w io.Writer, opts format.FormatterOptions) (*Formatter, error) {
+	codec, err := parseCodec(opts.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Formatter{
+		w:          w,
+		codec:      codec,
+		sampleSize: opts.SampleSize,
+	}
+	if f.sampleSize <= 0 {
+		f.sampleSize = DefaultSampleSize
+	}
+
+	if opts.Schema != "" {
+		schema, err := avro.Parse(opts.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("invalid avro schema: %w", err)
+		}
+		if err := f.start(schema); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+// Write encodes a single document as an Avro record.
+// Returns an error if the document does not fit the schema.
+func (f *Formatter) Write(doc any) error {
+	f.written++
+
+	if f.enc == nil {
+		// Buffered with its key order, which sets the order of inferred fields
+		f.pending = append(f.pending, doc)
+		if len(f.pending) < f.sampleSize {
+			return nil
+		}
+		return f.flushPending()
+	}
+
+	return f.encode(doc, f.written)
+}
+
+// Close writes any buffered documents and the final OCF block.
+// Must be called when done writing.
+func (f *Formatter) Close() error {
+	if f.enc == nil {
+		if err := f.flushPending(); err != nil {
+			return err
+		}
+	}
+	return f.enc.Close()
+}
+
+// flushPending infers the schema from the buffered documents, starts the
+// encoder and writes them out.
+func (f *Formatter) flushPending() error {
+	schema, err := inferSchema(f.pending)
+	if err != nil {
+		return fmt.Errorf("failed to infer avro schema: %w", err)
+	}
+	if err := f.start(schema); err != nil {
+		return err
+	}
+
+	first := f.written - len(f.pending) + 1
+	for i, doc := range f.pending {
+		if err := f.encode(doc, first+i); err != nil {
+			return err
+		}
+	}
+	f.pending = nil
+	return nil
+}
+
+// start writes the OCF header for schema.
+func (f *Formatter) start(schema avro.Schema) error {
+	enc, err := ocf.NewEncoderWithSchema(schema, f.w, ocf.WithCodec(f.codec))
+	if err != nil {
+		return fmt.Errorf("failed to create avro encoder: %w", err)
+	}
+	f.schema = schema
+	f.enc = enc
+	return nil
+}
+
+// encode conforms doc to the schema and appends it to the current block.
+// n is the 1-based document number, used in error messages.
+func (f *Formatter) encode(doc any, n int) error {
+	doc = ordered.ToPlain(doc) // Records are matched to the schema by field name, so key order is irrelevant
+	rec, err := conform(f.schema, doc, "")
+	if err != nil {
+		return fmt.Errorf("document %d does not match avro schema: %w", n, err)
+	}
+	if err := f.enc.Encode(rec); err != nil {
+		return fmt.Errorf("failed to encode avro record %d: %w", n, err)
+	}
+	return nil
+}
+
+// parseCodec maps a user-facing codec name to an OCF codec.
+func parseCodec(name string) (ocf.CodecName, error) {
+	switch name {
+	case "", "null", "none":
+		return ocf.Null, nil
+	case "deflate":
+		return ocf.Deflate, nil
+	case "snappy":
+		return ocf.Snappy, nil
+	case "zstd", "zstandard":
+		return ocf.ZStandard, nil
+	default:
+		return "", fmt.Errorf("unsupported avro codec %q (supported: null, deflate, snappy, zstd)", name)
+	}
+}
@@ -0,0 +1,189 @@
+package avro
+
+import (
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/hamba/avro/v2"
+)
+
+// Layouts of the text that logical types without a time zone are normalized to.
+const (
+	localTimestampLayout = "2006-01-02T15:04:05.999999999"
+	timeOfDayLayout      = "15:04:05.999999999"
+)
+
+// normalize converts a value decoded with schema into the plain, JSON-friendly
+// values the rest of flow works with:
+//   - timestamp-millis/micros -> RFC 3339 string in UTC
+//   - local-timestamp-millis/micros -> "2006-01-02T15:04:05" (no zone)
+//   - date -> "2006-01-02", time-millis/micros -> "15:04:05.000"
+//   - decimal -> decimal string with the schema's scale, e.g. "123.45"
+//   - duration -> {"months", "days", "milliseconds"}
+//   - fixed -> []byte, like bytes
+//
+// Union values of named types (records, enums, fixed) are decoded as a
+// single-key map from the type's name to the value; they are unwrapped unless
+// rawUnions is set. Maps, arrays and records are normalized in place.
+//
+//nolint:cyclop // One case per schema type
+func normalize(schema avro.Schema, v any, rawUnions bool) any {
+	if v == nil {
+		return nil
+	}
+
+	switch s := schema.(type) {
+	case *avro.RefSchema:
+		return normalize(s.Schema(), v, rawUnions)
+	case *avro.RecordSchema:
+		if m, ok := v.(map[string]any); ok {
+			for _, f := range s.Fields() {
+				if val, present := m[f.Name()]; present {
+					m[f.Name()] = normalize(f.Type(), val, rawUnions)
+				}
+			}
+		}
+	case *avro.ArraySchema:
+		if items, ok := v.([]any); ok {
+			for i, item := range items {
+				items[i] = normalize(s.Items(), item, rawUnions)
+			}
+		}
+	case *avro.MapSchema:
+		if m, ok := v.(map[string]any); ok {
+			for k, val := range m {
+				m[k] = normalize(s.Values(), val, rawUnions)
+			}
+		}
+	case *avro.UnionSchema:
+		return normalizeUnion(s, v, rawUnions)
+	case *avro.FixedSchema:
+		return normalizeLogical(s.Logical(), fixedBytes(v))
+	case *avro.PrimitiveSchema:
+		return normalizeLogical(s.Logical(), v)
+	}
+	return v
+}
+
+// normalizeUnion normalizes v with the union branch it was decoded from.
+func normalizeUnion(s *avro.UnionSchema, v any, rawUnions bool) any {
+	if m, ok := v.(map[string]any); ok && len(m) == 1 {
+		for name, val := range m {
+			if branch := namedBranch(s, name); branch != nil {
+				val = normalize(branch, val, rawUnions)
+				if rawUnions {
+					m[name] = val
+					return m
+				}
+				return val
+			}
+		}
+	}
+
+	if branch := valueBranch(s, v); branch != nil {
+		return normalize(branch, v, rawUnions)
+	}
+	return v
+}
+
+// namedBranch returns the branch of s that is the named type called name.
+func namedBranch(s *avro.UnionSchema, name string) avro.Schema {
+	for _, branch := range s.Types() {
+		if n, ok := branch.(avro.NamedSchema); ok && (n.FullName() == name || n.Name() == name) {
+			return branch
+		}
+	}
+	return nil
+}
+
+// valueBranch returns the first branch of s that decodes to values like v, for
+// the unnamed types whose values need normalizing. Primitives are left as is.
+func valueBranch(s *avro.UnionSchema, v any) avro.Schema {
+	var want func(branch avro.Schema) bool
+	switch v.(type) {
+	case time.Time:
+		want = func(branch avro.Schema) bool {
+			switch logicalType(branch) {
+			case avro.TimestampMillis, avro.TimestampMicros, avro.LocalTimestampMillis, avro.LocalTimestampMicros, avro.Date:
+				return true
+			}
+			return false
+		}
+	case time.Duration:
+		want = func(branch avro.Schema) bool {
+			lt := logicalType(branch)
+			return lt == avro.TimeMillis || lt == avro.TimeMicros
+		}
+	case *big.Rat:
+		want = func(branch avro.Schema) bool { return logicalType(branch) == avro.Decimal }
+	case []any:
+		want = func(branch avro.Schema) bool { return branch.Type() == avro.Array }
+	case map[string]any:
+		want = func(branch avro.Schema) bool { return branch.Type() == avro.Map }
+	default:
+		return nil
+	}
+
+	for _, branch := range s.Types() {
+		if want(branch) {
+			return branch
+		}
+	}
+	return nil
+}
+
+// logicalType returns the logical type of schema, or "" if it has none.
+func logicalType(schema avro.Schema) avro.LogicalType {
+	if l, ok := schema.(avro.LogicalTypeSchema); ok && l.Logical() != nil {
+		return l.Logical().Type()
+	}
+	return ""
+}
+
+// normalizeLogical converts a value of a primitive or fixed type with the
+// given logical type (nil for none).
+//
+//nolint:cyclop // One case per logical type
+func normalizeLogical(logical avro.LogicalSchema, v any) any {
+	if logical == nil {
+		return v
+	}
+
+	switch vv := v.(type) {
+	case time.Time:
+		switch logical.Type() {
+		case avro.TimestampMillis, avro.TimestampMicros:
+			return vv.UTC().Format(time.RFC3339Nano)
+		case avro.LocalTimestampMillis, avro.LocalTimestampMicros:
+			return vv.Format(localTimestampLayout)
+		case avro.Date:
+			return vv.Format(time.DateOnly)
+		}
+	case time.Duration:
+		return time.Time{}.Add(vv).Format(timeOfDayLayout)
+	case *big.Rat:
+		if d, ok := logical.(*avro.DecimalLogicalSchema); ok {
+			return vv.FloatString(d.Scale())
+		}
+		return vv.RatString()
+	case avro.LogicalDuration:
+		return map[string]any{
+			"months":       int64(vv.Months),
+			"days":         int64(vv.Days),
+			"milliseconds": int64(vv.Milliseconds),
+		}
+	}
+	return v
+}
+
+// fixedBytes returns a fixed value, decoded as a byte array, as a byte slice.
+func fixedBytes(v any) any {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Array || rv.Type().Elem().Kind() != reflect.Uint8 {
+		return v
+	}
+	b := make([]byte, rv.Len())
+	reflect.Copy(reflect.ValueOf(b), rv)
+	return b
+}
@@ -0,0 +1,124 @@
+package avro
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const logicalSchema = `{"type":"record","name":"Event","namespace":"com.example","fields":[
+	{"name":"ts","type":{"type":"long","logicalType":"timestamp-millis"}},
+	{"name":"ts_micros","type":{"type":"long","logicalType":"timestamp-micros"}},
+	{"name":"local","type":{"type":"long","logicalType":"local-timestamp-millis"}},
+	{"name":"day","type":{"type":"int","logicalType":"date"}},
+	{"name":"at","type":{"type":"int","logicalType":"time-millis"}},
+	{"name":"price","type":{"type":"bytes","logicalType":"decimal","precision":10,"scale":2}},
+	{"name":"rate","type":{"type":"fixed","name":"Rate","size":8,"logicalType":"decimal","precision":10,"scale":3}},
+	{"name":"id","type":{"type":"string","logicalType":"uuid"}},
+	{"name":"wait","type":{"type":"fixed","name":"Wait","size":12,"logicalType":"duration"}},
+	{"name":"hash","type":{"type":"fixed","name":"Hash","size":4}},
+	{"name":"note","type":["null","string"]},
+	{"name":"count","type":["null","string","long"]},
+	{"name":"owner","type":["null",{"type":"record","name":"Owner","fields":[
+		{"name":"name","type":"string"},
+		{"name":"since","type":["null",{"type":"int","logicalType":"date"}]}]}]},
+	{"name":"seen","type":{"type":"array","items":{"type":"long","logicalType":"timestamp-millis"}}},
+	{"name":"labels","type":{"type":"map","values":["null",{"type":"bytes","logicalType":"decimal","precision":4,"scale":1}]}}
+]}`
+
+func logicalRecord() map[string]any {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	return map[string]any{
+		"ts":        ts,
+		"ts_micros": time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC),
+		"local":     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"day":       time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		"at":        13*time.Hour + 5*time.Millisecond,
+		"price":     big.NewRat(12345, 100),
+		"rate":      big.NewRat(-5, 4),
+		"id":        "550e8400-e29b-41d4-a716-446655440000",
+		"wait":      [12]byte{1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0},
+		"hash":      [4]byte{1, 2, 3, 4},
+		"note":      "x",
+		"count":     map[string]any{"long": int64(5)},
+		"owner":     map[string]any{"com.example.Owner": map[string]any{"name": "Ann", "since": time.Date(2020, 5, 6, 0, 0, 0, 0, time.UTC)}},
+		"seen":      []any{ts},
+		"labels":    map[string]any{"a": big.NewRat(15, 10), "b": nil},
+	}
+}
+
+func TestParser_NormalizesLogicalTypesAndUnions(t *testing.T) {
+	p, err := NewParser(ocfFile(t, logicalSchema, logicalRecord()), format.ParserOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []map[string]any{{
+		"ts":        "2024-01-02T03:04:05.006Z",
+		"ts_micros": "2024-01-02T03:04:05.000006Z",
+		"local":     "2024-01-02T03:04:05",
+		"day":       "2024-01-02",
+		"at":        "13:00:00.005",
+		"price":     "123.45",
+		"rate":      "-1.250",
+		"id":        "550e8400-e29b-41d4-a716-446655440000",
+		"wait":      map[string]any{"months": int64(1), "days": int64(2), "milliseconds": int64(3)},
+		"hash":      []byte{1, 2, 3, 4},
+		"note":      "x",
+		"count":     int64(5),
+		"owner":     map[string]any{"name": "Ann", "since": "2020-05-06"},
+		"seen":      []any{"2024-01-02T03:04:05.006Z"},
+		"labels":    map[string]any{"a": "1.5", "b": nil},
+	}}, readAll(t, p))
+}
+
+func TestParser_RawUnions(t *testing.T) {
+	p, err := NewParser(ocfFile(t, logicalSchema, logicalRecord()), format.ParserOptions{RawUnions: true})
+	require.NoError(t, err)
+
+	records := readAll(t, p)
+	require.Len(t, records, 1)
+	// Named types stay wrapped, and their values are still normalized
+	assert.Equal(t, map[string]any{"com.example.Owner": map[string]any{"name": "Ann", "since": "2020-05-06"}}, records[0]["owner"])
+	assert.Equal(t, "x", records[0]["note"])
+}
+
+func TestParser_NormalizesWithReaderSchema(t *testing.T) {
+	writer := `{"type":"record","name":"E","fields":[{"name":"n","type":"int"}]}`
+	reader := `{"type":"record","name":"E","fields":[{"name":"n","type":"long"},
+		{"name":"at","type":{"type":"long","logicalType":"timestamp-millis"},"default":0}]}`
+
+	p, err := NewParser(ocfFile(t, writer, map[string]any{"n": 1}), format.ParserOptions{Schema: reader})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"n": int64(1), "at": "1970-01-01T00:00:00Z"}}, readAll(t, p))
+}
+
+func TestFormatter_AcceptsNormalizedLogicalTypes(t *testing.T) {
+	schema := `{"type":"record","name":"E","fields":[
+		{"name":"ts","type":{"type":"long","logicalType":"timestamp-micros"}},
+		{"name":"local","type":{"type":"long","logicalType":"local-timestamp-millis"}},
+		{"name":"day","type":{"type":"int","logicalType":"date"}},
+		{"name":"at","type":{"type":"long","logicalType":"time-micros"}},
+		{"name":"price","type":{"type":"bytes","logicalType":"decimal","precision":10,"scale":2}}]}`
+	record := map[string]any{
+		"ts":    "2024-01-02T03:04:05.000006Z",
+		"local": "2024-01-02T03:04:05.5",
+		"day":   "2024-01-02",
+		"at":    "13:00:00.000005",
+		"price": "123.45",
+	}
+
+	// What the parser produces can be written back with the same schema
+	records := roundTrip(t, format.FormatterOptions{Schema: schema}, record)
+	assert.Equal(t, []map[string]any{record}, records)
+
+	var buf bytes.Buffer
+	f, err := NewFormatter(&buf, format.FormatterOptions{Schema: schema})
+	require.NoError(t, err)
+	record["ts"] = "yesterday"
+	err = f.Write(record)
+	assert.ErrorContains(t, err, `field "ts": string value does not fit avro type long.timestamp-micros`)
+}
@@ -3,31 +3,128 @@ package avro
 import (
 	"fmt"
 	"io"
+	"slices"
+	"strings"
 
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/ordered"
+	"github.com/hamba/avro/v2"
 	"github.com/hamba/avro/v2/ocf"
 )
 
 // Parser implements the format.Parser interface for Avro OCF (Object Container Files).
 // It streams records from an Avro file without buffering the entire file into memory.
 type Parser struct {
-	decoder *ocf.Decoder
+	decoder   *ocf.Decoder
+	schema    avro.Schema // schema records are decoded with
+	rawUnions bool
 }
 
 // NewParser creates a new Avro parser that reads from the given reader.
 // The reader must contain a valid Avro OCF file with embedded schema.
-func NewParser(r io.Reader) (*Parser, error) {
-	dec, err := ocf.NewDecoder(r)
+//
+// If opts.Schema is set, records are resolved against it as the reader schema
+// using Avro's schema resolution rules: fields missing from the file get the
+// reader's defaults, fields the reader doesn't declare are dropped, and
+// numeric types are promoted. Files written with different versions of a
+// schema then all produce records of the same shape.
+//
+// Logical types and unions are normalized into JSON-friendly values (see
+// normalize); opts.RawUnions keeps union values of named types wrapped.
+func NewParser(r io.Reader, opts format.ParserOptions) (*Parser, error) {
+	if opts.Schema == "" {
+		dec, err := ocf.NewDecoder(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create avro decoder: %w", err)
+		}
+		return &Parser{decoder: dec, schema: dec.Schema(), rawUnions: opts.RawUnions}, nil
+	}
+
+	reader, err := avro.ParseWithCache(opts.Schema, "", &avro.SchemaCache{})
+	if err != nil {
+		return nil, fmt.Errorf("invalid avro reader schema: %w", err)
+	}
+
+	// Each file's schema is parsed into its own cache, so that files with
+	// different versions of the same named record don't clash
+	api := &resolvingAPI{API: avro.DefaultConfig, reader: reader}
+	dec, err := ocf.NewDecoder(r, ocf.WithDecoderConfig(api), ocf.WithDecoderSchemaCache(&avro.SchemaCache{}))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create avro decoder: %w", err)
 	}
+	if api.err != nil {
+		return nil, fmt.Errorf("file schema is not compatible with the reader schema: %w", api.err)
+	}
 
 	return &Parser{
-		decoder: dec,
+		decoder:   dec,
+		schema:    api.resolved,
+		rawUnions: opts.RawUnions,
 	}, nil
 }
 
+// resolvingAPI decodes records written with a file's schema as the reader
+// schema. The OCF decoder asks it for a record decoder once it has read the
+// file header.
+type resolvingAPI struct {
+	avro.API
+
+	reader   avro.Schema
+	resolved avro.Schema // what records are decoded with
+	err      error       // set if the file's schema can't be resolved
+}
+
+// NewDecoder returns a decoder for data written with writer that produces
+// values of the reader schema.
+func (a *resolvingAPI) NewDecoder(writer avro.Schema, r io.Reader) *avro.Decoder {
+	resolved, err := avro.NewSchemaCompatibility().Resolve(a.reader, writer)
+	if err != nil {
+		a.err = err
+		resolved = writer
+	}
+	a.resolved = resolved
+	return a.API.NewDecoder(resolved, r)
+}
+
+// Describe returns the schema embedded in the file's header, as written, along
+// with its compression codec and any metadata the writer added.
+func (p *Parser) Describe() (any, error) {
+	meta := p.decoder.Metadata()
+	schema, err := ordered.DecodeJSON(meta["avro.schema"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid avro schema in file header: %w", err)
+	}
+
+	codec := string(meta["avro.codec"])
+	if codec == "" {
+		codec = "null"
+	}
+
+	desc := ordered.NewMap()
+	desc.Set("format", "avro")
+	desc.Set("codec", codec)
+	desc.Set("schema", schema)
+
+	var keys []string
+	for k := range meta {
+		if !strings.HasPrefix(k, "avro.") {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) > 0 {
+		slices.Sort(keys)
+		metadata := ordered.NewMap()
+		for _, k := range keys {
+			metadata.Set(k, string(meta[k]))
+		}
+		desc.Set("metadata", metadata)
+	}
+	return desc, nil
+}
+
 // ForEach iterates over all records in the Avro file, calling fn for each record.
-// Records are decoded into map[string]any for format-agnostic processing.
+// Records are decoded into map[string]any for format-agnostic processing,
+// with logical types and unions normalized.
 // Iteration stops when:
 // - All records have been processed (returns nil)
 // - The callback fn returns an error (returns that error)
@@ -41,7 +138,7 @@ func (p *Parser) ForEach(fn func(doc any) error) error {
 		}
 
 		// Call the callback with the decoded record
-		if err := fn(record); err != nil {
+		if err := fn(normalize(p.schema, record, p.rawUnions)); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,80 @@
+package avro
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/hamba/avro/v2/ocf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	userV1 = `{"type":"record","name":"User","fields":[
+		{"name":"name","type":"string"},
+		{"name":"age","type":"int"},
+		{"name":"nickname","type":"string"}]}`
+
+	userV2 = `{"type":"record","name":"User","fields":[
+		{"name":"name","type":"string"},
+		{"name":"age","type":"long"},
+		{"name":"email","type":["null","string"],"default":null},
+		{"name":"active","type":"boolean","default":true}]}`
+)
+
+// ocfFile encodes records into an Avro OCF file with the given schema.
+func ocfFile(t *testing.T, schema string, records ...any) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc, err := ocf.NewEncoder(schema, &buf)
+	require.NoError(t, err)
+	for _, record := range records {
+		require.NoError(t, enc.Encode(record))
+	}
+	require.NoError(t, enc.Close())
+	return &buf
+}
+
+func readAll(t *testing.T, p *Parser) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	require.NoError(t, p.ForEach(func(doc any) error {
+		records = append(records, doc.(map[string]any))
+		return nil
+	}))
+	return records
+}
+
+func TestParser_ReaderSchema(t *testing.T) {
+	v1 := ocfFile(t, userV1, map[string]any{"name": "Alice", "age": 30, "nickname": "al"})
+	v2 := ocfFile(t, userV2, map[string]any{"name": "Bob", "age": int64(25), "email": "bob@example.com", "active": false})
+
+	// Dropped fields go, new fields get their defaults, and int is promoted to long
+	p, err := NewParser(v1, format.ParserOptions{Schema: userV2})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{
+		{"name": "Alice", "age": int64(30), "email": nil, "active": true},
+	}, readAll(t, p))
+
+	p, err = NewParser(v2, format.ParserOptions{Schema: userV2})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]any{
+		{"name": "Bob", "age": int64(25), "email": "bob@example.com", "active": false},
+	}, readAll(t, p))
+}
+
+func TestParser_ReaderSchema_Incompatible(t *testing.T) {
+	// A new field without a default can't be filled in for older files
+	reader := `{"type":"record","name":"User","fields":[
+		{"name":"name","type":"string"},
+		{"name":"email","type":"string"}]}`
+
+	_, err := NewParser(ocfFile(t, userV1, map[string]any{"name": "Alice", "age": 30, "nickname": "al"}), format.ParserOptions{Schema: reader})
+	assert.ErrorContains(t, err, "not compatible with the reader schema")
+
+	_, err = NewParser(ocfFile(t, userV1), format.ParserOptions{Schema: `{"type":`})
+	assert.ErrorContains(t, err, "invalid avro reader schema")
+}
@@ -0,0 +1,504 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"math/big"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/ordered"
+	"github.com/hamba/avro/v2"
+)
+
+const (
+	// recordName is the name given to the top-level record of an inferred schema.
+	recordName = "Record"
+
+	// originalNamePrefix marks the field doc of inferred fields whose key had
+	// to be sanitized into a valid Avro name.
+	originalNamePrefix = "original name: "
+)
+
+// inferredType accumulates the shape of values observed at one position in
+// the sampled documents. It is converted to an Avro schema once sampling ends.
+type inferredType struct {
+	kind     avro.Type // avro.Null until a non-null value is seen
+	logical  avro.LogicalType
+	nullable bool
+	items    *inferredType            // element type for avro.Array
+	fields   map[string]*inferredType // field types for avro.Record
+	order    []string                 // field names in the order first seen
+}
+
+// inferSchema builds a record schema that every document in docs fits.
+// Nested maps become nested records, []any becomes an array, and fields that
+// are missing or null in some documents become nullable unions. Record fields
+// are in the order they are first seen: the key order of an *ordered.Map, or
+// sorted for a map[string]any.
+func inferSchema(docs []any) (avro.Schema, error) {
+	root := &inferredType{kind: avro.Null}
+	for i, doc := range docs {
+		if format.TypeOf(doc) != format.TypeObject {
+			return nil, fmt.Errorf("document %d: avro output requires objects, got %s", i+1, typeName(doc))
+		}
+		if err := root.observe(doc, ""); err != nil {
+			return nil, fmt.Errorf("document %d: %w", i+1, err)
+		}
+	}
+
+	if root.kind == avro.Null {
+		// No documents: an empty record still produces a valid container
+		root.kind, root.fields = avro.Record, map[string]*inferredType{}
+	}
+
+	schemaJSON, err := json.Marshal(root.toSchema(recordName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal inferred schema: %w", err)
+	}
+
+	schema, err := avro.Parse(string(schemaJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build inferred schema: %w", err)
+	}
+	return schema, nil
+}
+
+// observe merges the shape of v into t. path is used for error messages.
+//
+//nolint:cyclop // One case per value type
+func (t *inferredType) observe(v any, path string) error {
+	switch format.TypeOf(v) {
+	case format.TypeNull:
+		t.nullable = true
+		return nil
+	case format.TypeBoolean:
+		return t.merge(avro.Boolean, "", path)
+	case format.TypeString:
+		return t.merge(avro.String, "", path)
+	case format.TypeBytes:
+		return t.merge(avro.Bytes, "", path)
+	case format.TypeTimestamp:
+		return t.merge(avro.Long, avro.TimestampMicros, path)
+	case format.TypeInteger:
+		return t.merge(avro.Long, "", path)
+	case format.TypeNumber:
+		return t.merge(avro.Double, "", path)
+	case format.TypeArray:
+		return t.observeArray(v.([]any), path)
+	case format.TypeObject:
+		m, ok := v.(*ordered.Map)
+		if !ok {
+			m = sortedRecord(v.(map[string]any))
+		}
+		return t.observeRecord(m, path)
+	default:
+		return fmt.Errorf("field %q: unsupported value of type %T", path, v)
+	}
+}
+
+// observeArray merges an array and its items into t.
+func (t *inferredType) observeArray(items []any, path string) error {
+	if err := t.merge(avro.Array, "", path); err != nil {
+		return err
+	}
+	if t.items == nil {
+		t.items = &inferredType{kind: avro.Null}
+	}
+	for i, item := range items {
+		if err := t.items.observe(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observeRecord merges an object into t. Fields that were seen before but are
+// missing from m, or are new in m, become nullable.
+func (t *inferredType) observeRecord(m *ordered.Map, path string) error {
+	seenBefore := t.kind == avro.Record
+	if err := t.merge(avro.Record, "", path); err != nil {
+		return err
+	}
+	if t.fields == nil {
+		t.fields = make(map[string]*inferredType, m.Len())
+	}
+
+	for name, field := range t.fields {
+		if _, ok := m.Get(name); !ok {
+			field.nullable = true
+		}
+	}
+
+	for name, val := range m.All() {
+		field, ok := t.fields[name]
+		if !ok {
+			field = &inferredType{kind: avro.Null, nullable: seenBefore}
+			t.fields[name] = field
+			t.order = append(t.order, name)
+		}
+		if err := field.observe(val, format.JoinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedRecord returns m as an *ordered.Map with its keys sorted, so records
+// without a key order still get a stable field order.
+func sortedRecord(m map[string]any) *ordered.Map {
+	out := ordered.NewMap()
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		out.Set(k, m[k])
+	}
+	return out
+}
+
+// merge records that a value of kind was seen. long and double widen to
+// double; any other mix of types is reported as a conflict.
+func (t *inferredType) merge(kind avro.Type, logical avro.LogicalType, path string) error {
+	switch {
+	case t.kind == avro.Null:
+		t.kind, t.logical = kind, logical
+	case t.kind == kind && t.logical == logical:
+	case isNumeric(t.kind) && isNumeric(kind) && logical == "" && t.logical == "":
+		t.kind = avro.Double
+	default:
+		return fmt.Errorf("field %q has conflicting types %s and %s (use --avro-schema to set one explicitly)",
+			path, t.describe(), describeType(kind, logical))
+	}
+	return nil
+}
+
+// toSchema converts t into an Avro schema in its JSON form.
+// name is used for record types and must be unique within the schema.
+func (t *inferredType) toSchema(name string) any {
+	var schema any
+	switch t.kind {
+	case avro.Null:
+		// Only nulls were observed
+		return string(avro.Null)
+	case avro.Array:
+		schema = map[string]any{"type": avro.Array, "items": t.items.toSchema(name + "_item")}
+	case avro.Record:
+		fields := make([]any, 0, len(t.order))
+		for _, n := range t.order {
+			field := map[string]any{"name": avroName(n), "type": t.fields[n].toSchema(name + "_" + avroName(n))}
+			if n != avroName(n) {
+				field["doc"] = originalNamePrefix + n
+			}
+			if t.fields[n].nullable && t.fields[n].kind != avro.Null {
+				field["default"] = nil
+			}
+			fields = append(fields, field)
+		}
+		schema = map[string]any{"type": avro.Record, "name": name, "fields": fields}
+	default:
+		if t.logical != "" {
+			schema = map[string]any{"type": t.kind, "logicalType": t.logical}
+		} else {
+			schema = string(t.kind)
+		}
+	}
+
+	if t.nullable {
+		return []any{string(avro.Null), schema}
+	}
+	return schema
+}
+
+func (t *inferredType) describe() string {
+	return describeType(t.kind, t.logical)
+}
+
+func describeType(kind avro.Type, logical avro.LogicalType) string {
+	if logical != "" {
+		return string(kind) + "." + string(logical)
+	}
+	return string(kind)
+}
+
+func isNumeric(t avro.Type) bool {
+	return t == avro.Long || t == avro.Double
+}
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// avroName turns an arbitrary key into a valid Avro name
+// ([A-Za-z_][A-Za-z0-9_]*).
+func avroName(key string) string {
+	name := invalidNameChars.ReplaceAllString(key, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// ----------------------------- Conformance -----------------------------
+
+// conform converts v into the Go representation the Avro encoder expects for
+// schema, or returns an error describing why v does not fit. Union values are
+// wrapped as single-key maps naming the chosen branch.
+//
+//nolint:cyclop,funlen // One case per Avro type
+func conform(schema avro.Schema, v any, path string) (any, error) {
+	if ref, ok := schema.(*avro.RefSchema); ok {
+		schema = ref.Schema()
+	}
+
+	switch s := schema.(type) {
+	case *avro.UnionSchema:
+		return conformUnion(s, v, path)
+	case *avro.RecordSchema:
+		return conformRecord(s, v, path)
+	case *avro.ArraySchema:
+		arr, ok := v.([]any)
+		if !ok {
+			return nil, mismatch(path, schema, v)
+		}
+		out := make([]any, len(arr))
+		for i, item := range arr {
+			c, err := conform(s.Items(), item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = c
+		}
+		return out, nil
+	case *avro.MapSchema:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, mismatch(path, schema, v)
+		}
+		out := make(map[string]any, len(m))
+		for k, val := range m {
+			c, err := conform(s.Values(), val, format.JoinPath(path, k))
+			if err != nil {
+				return nil, err
+			}
+			out[k] = c
+		}
+		return out, nil
+	case *avro.EnumSchema:
+		str, ok := v.(string)
+		if !ok || !slices.Contains(s.Symbols(), str) {
+			return nil, mismatch(path, schema, v)
+		}
+		return str, nil
+	case *avro.PrimitiveSchema:
+		return conformPrimitive(s, v, path)
+	default:
+		return nil, fmt.Errorf("field %q: avro %s fields are not supported for writing", path, schema.Type())
+	}
+}
+
+func conformUnion(s *avro.UnionSchema, v any, path string) (any, error) {
+	for _, branch := range s.Types() {
+		if branch.Type() == avro.Null {
+			if v == nil {
+				return map[string]any{string(avro.Null): nil}, nil
+			}
+			continue
+		}
+		if c, err := conform(branch, v, path); err == nil {
+			return map[string]any{unionBranchName(branch): c}, nil
+		}
+	}
+	return nil, mismatch(path, s, v)
+}
+
+func conformRecord(s *avro.RecordSchema, v any, path string) (any, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, mismatch(path, s, v)
+	}
+
+	out := make(map[string]any, len(s.Fields()))
+	known := make(map[string]bool, len(s.Fields()))
+	for _, f := range s.Fields() {
+		key := fieldKey(f, m)
+		known[key] = true
+
+		val, present := m[key]
+		if !present {
+			if f.HasDefault() {
+				continue // encoder fills in the default
+			}
+			if !acceptsNull(f.Type()) {
+				return nil, fmt.Errorf("field %q is required by the avro schema but missing", format.JoinPath(path, key))
+			}
+		}
+
+		c, err := conform(f.Type(), val, format.JoinPath(path, key))
+		if err != nil {
+			return nil, err
+		}
+		out[f.Name()] = c
+	}
+
+	for k := range m {
+		if !known[k] {
+			return nil, fmt.Errorf("field %q is not in the avro schema", format.JoinPath(path, k))
+		}
+	}
+	return out, nil
+}
+
+// fieldKey returns the document key holding the value for f. Inferred schemas
+// sanitize keys into Avro names, so fall back to the original key recorded in
+// the field doc when the sanitized name is absent.
+func fieldKey(f *avro.Field, m map[string]any) string {
+	if _, ok := m[f.Name()]; ok {
+		return f.Name()
+	}
+	if orig, ok := strings.CutPrefix(f.Doc(), originalNamePrefix); ok {
+		return orig
+	}
+	return f.Name()
+}
+
+//nolint:cyclop // One case per primitive type
+func conformPrimitive(s *avro.PrimitiveSchema, v any, path string) (any, error) {
+	// Logical types read from Avro are normalized to text (see normalize)
+	if str, isString := v.(string); isString && s.Logical() != nil && s.Type() != avro.String {
+		if out, ok := parseLogical(s.Logical().Type(), str); ok {
+			return out, nil
+		}
+		return nil, mismatch(path, s, v)
+	}
+
+	var (
+		out any
+		ok  bool
+	)
+
+	switch s.Type() {
+	case avro.Null:
+		out, ok = nil, v == nil
+	case avro.Boolean:
+		out, ok = v.(bool)
+	case avro.String:
+		out, ok = v.(string)
+	case avro.Bytes:
+		switch b := v.(type) {
+		case []byte:
+			out, ok = b, true
+		case string:
+			out, ok = []byte(b), true
+		}
+	case avro.Int:
+		var n int64
+		if n, ok = format.ToInt64(v); ok && n >= math.MinInt32 && n <= math.MaxInt32 {
+			out = int32(n)
+		} else {
+			ok = false
+		}
+	case avro.Long:
+		if t, isTime := v.(time.Time); isTime && s.Logical() != nil {
+			return t, nil
+		}
+		out, ok = format.ToInt64(v)
+	case avro.Float:
+		var f float64
+		f, ok = format.ToFloat64(v)
+		out = float32(f)
+	case avro.Double:
+		out, ok = format.ToFloat64(v)
+	}
+
+	if !ok {
+		return nil, mismatch(path, s, v)
+	}
+	return out, nil
+}
+
+// parseLogical parses the text normalize produces for a value of a logical
+// type back into the Go value the Avro encoder takes.
+func parseLogical(logical avro.LogicalType, s string) (any, bool) {
+	var (
+		t   time.Time
+		err error
+	)
+	switch logical {
+	case avro.TimestampMillis, avro.TimestampMicros:
+		t, err = time.Parse(time.RFC3339Nano, s)
+	case avro.LocalTimestampMillis, avro.LocalTimestampMicros:
+		t, err = time.Parse(localTimestampLayout, s)
+	case avro.Date:
+		t, err = time.Parse(time.DateOnly, s)
+	case avro.TimeMillis, avro.TimeMicros:
+		if t, err = time.Parse(timeOfDayLayout, s); err != nil {
+			return nil, false
+		}
+		return t.Sub(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)), true
+	case avro.Decimal:
+		r, ok := new(big.Rat).SetString(s)
+		return r, ok
+	default:
+		return nil, false
+	}
+	return t, err == nil
+}
+
+func acceptsNull(schema avro.Schema) bool {
+	if schema.Type() == avro.Null {
+		return true
+	}
+	if u, ok := schema.(*avro.UnionSchema); ok {
+		return u.Nullable() || slices.ContainsFunc(u.Types(), func(s avro.Schema) bool { return s.Type() == avro.Null })
+	}
+	return false
+}
+
+// unionBranchName mirrors how the Avro library names union branches:
+// the full name for named types, otherwise the type plus any logical type.
+func unionBranchName(schema avro.Schema) string {
+	if n, ok := schema.(avro.NamedSchema); ok {
+		return n.FullName()
+	}
+	name := string(schema.Type())
+	if l, ok := schema.(avro.LogicalTypeSchema); ok && l.Logical() != nil {
+		name += "." + string(l.Logical().Type())
+	}
+	return name
+}
+
+func mismatch(path string, schema avro.Schema, v any) error {
+	if path == "" {
+		path = "(root)"
+	}
+	return fmt.Errorf("field %q: %s value does not fit avro type %s", path, typeName(v), schemaSummary(schema))
+}
+
+// schemaSummary returns a short description of schema for error messages.
+func schemaSummary(schema avro.Schema) string {
+	switch s := schema.(type) {
+	case avro.NamedSchema:
+		return string(s.Type()) + " " + s.FullName()
+	case *avro.UnionSchema:
+		names := make([]string, 0, len(s.Types()))
+		for _, t := range s.Types() {
+			names = append(names, unionBranchName(t))
+		}
+		return "union [" + strings.Join(names, ", ") + "]"
+	default:
+		return unionBranchName(schema)
+	}
+}
+
+// typeName describes the Go value v in JSON terms for error messages.
+func typeName(v any) string {
+	switch t := format.TypeOf(v); t {
+	case "":
+		return fmt.Sprintf("%T", v)
+	case format.TypeInteger:
+		return string(format.TypeNumber)
+	default:
+		return string(t)
+	}
+}
@@ -0,0 +1,51 @@
+// Package csv implements CSV and TSV format support for flow.
+// It provides parsing and formatting of delimited text with:
+//   - Streaming row processing (each row becomes an *ordered.Map in column order)
+//   - Header row handling, or positional column names with no header
+//   - Optional number/boolean inference for cell values
+//   - Flattening of nested documents into dotted columns on output
+package csv
+
+import (
+	"io"
+
+	"github.com/GeoffMall/flow/internal/format"
+)
+
+// Format implements format.Format for delimited text.
+// The same implementation is registered as "csv" (comma) and "tsv" (tab).
+type Format struct {
+	name      string
+	delimiter rune
+}
+
+// Name returns the format identifier.
+func (f *Format) Name() string {
+	return f.name
+}
+
+// NewParser creates a new streaming parser.
+// opts.Delimiter overrides the format's default delimiter.
+func (f *Format) NewParser(r io.Reader, opts format.ParserOptions) (format.Parser, error) {
+	if opts.Delimiter == 0 {
+		opts.Delimiter = f.delimiter
+	}
+	return NewParser(r, opts)
+}
+
+// NewFormatter creates a new formatter.
+// opts.Delimiter overrides the format's default delimiter.
+func (f *Format) NewFormatter(w io.Writer, opts format.FormatterOptions) (format.Formatter, error) {
+	if opts.Delimiter == 0 {
+		opts.Delimiter = f.delimiter
+	}
+	return NewFormatter(w, opts)
+}
+
+// Register the CSV and TSV formats on package initialization
+//
+//nolint:gochecknoinits // Required for automatic format registration
+func init() {
+	format.Register(&Format{name: "csv", delimiter: ','})
+	format.Register(&Format{name: "tsv", delimiter: '\t'})
+}
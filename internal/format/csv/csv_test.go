@@ -0,0 +1,214 @@
+package csv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/ordered"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseAll(t *testing.T, input string, opts format.ParserOptions) ([]any, error) {
+	t.Helper()
+
+	parser, err := NewParser(strings.NewReader(input), opts)
+	assert.NoError(t, err)
+
+	var docs []any
+	err = parser.ForEach(func(doc any) error {
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+func TestParser_Header(t *testing.T) {
+	docs, err := parseAll(t, "name,age\nAlice,30\nBob,25\n", format.ParserOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []any{
+		map[string]any{"name": "Alice", "age": "30"},
+		map[string]any{"name": "Bob", "age": "25"},
+	}, ordered.ToPlain(docs))
+	assert.Equal(t, []string{"name", "age"}, docs[0].(*ordered.Map).Keys())
+}
+
+func TestParser_NoHeader(t *testing.T) {
+	docs, err := parseAll(t, "Alice,30\nBob,25\n", format.ParserOptions{NoHeader: true})
+
+	assert.NoError(t, err)
+	assert.Len(t, docs, 2)
+	assert.Equal(t, map[string]any{"col1": "Alice", "col2": "30"}, ordered.ToPlain(docs[0]))
+}
+
+func TestParser_InferTypes(t *testing.T) {
+	docs, err := parseAll(t, "s,i,f,b,e\nx,42,1.5,true,\n", format.ParserOptions{InferTypes: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"s": "x",
+		"i": int64(42),
+		"f": 1.5,
+		"b": true,
+		"e": nil,
+	}, ordered.ToPlain(docs[0]))
+}
+
+func TestParser_InferTypes_KeepsNonFiniteAsString(t *testing.T) {
+	docs, err := parseAll(t, "v\nInf\nNaN\n", format.ParserOptions{InferTypes: true})
+
+	assert.NoError(t, err)
+	v, _ := docs[0].(*ordered.Map).Get("v")
+	assert.Equal(t, "Inf", v)
+	v, _ = docs[1].(*ordered.Map).Get("v")
+	assert.Equal(t, "NaN", v)
+}
+
+func TestParser_InferTypes_KeepsLeadingZeros(t *testing.T) {
+	docs, err := parseAll(t, "zip,id,n\n02134,007,0\n", format.ParserOptions{InferTypes: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"zip": "02134", "id": "007", "n": int64(0)}, ordered.ToPlain(docs[0]))
+}
+
+func TestParser_Delimiter(t *testing.T) {
+	docs, err := parseAll(t, "name;city\nAlice;\"Paris; France\"\n", format.ParserOptions{Delimiter: ';'})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"name": "Alice", "city": "Paris; France"}, ordered.ToPlain(docs[0]))
+}
+
+func TestParser_DuplicateHeaderIsSuffixed(t *testing.T) {
+	docs, err := parseAll(t, "id,id,id_2,id\n1,2,3,4\n", format.ParserOptions{})
+
+	assert.NoError(t, err)
+	assert.Len(t, docs, 1)
+	row := docs[0].(*ordered.Map)
+	assert.Equal(t, []string{"id", "id_2", "id_2_2", "id_3"}, row.Keys())
+	assert.Equal(t, map[string]any{"id": "1", "id_2": "2", "id_2_2": "3", "id_3": "4"}, ordered.ToPlain(row))
+}
+
+func TestParser_TSVBareQuotes(t *testing.T) {
+	docs, err := parseAll(t, "name\tnote\nAlice\tsays \"hi\"\nBob\t5'10\"\n", format.ParserOptions{Delimiter: '\t'})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []any{
+		map[string]any{"name": "Alice", "note": `says "hi"`},
+		map[string]any{"name": "Bob", "note": `5'10"`},
+	}, ordered.ToPlain(docs))
+}
+
+func TestParser_RaggedRow(t *testing.T) {
+	docs, err := parseAll(t, "a,b\n1,2\n3\n", format.ParserOptions{})
+
+	assert.Error(t, err)
+	assert.Len(t, docs, 1)
+}
+
+func TestParser_Empty(t *testing.T) {
+	docs, err := parseAll(t, "", format.ParserOptions{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, docs)
+}
+
+func TestParser_InvalidDelimiter(t *testing.T) {
+	_, err := NewParser(strings.NewReader(""), format.ParserOptions{Delimiter: '"'})
+	assert.Error(t, err)
+}
+
+func TestFormatter_FlattensNestedDocuments(t *testing.T) {
+	buf := &bytes.Buffer{}
+	f, err := NewFormatter(buf, format.FormatterOptions{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, f.Write(map[string]any{
+		"name": "Alice",
+		"user": map[string]any{"id": float64(7), "admin": true},
+		"tags": []any{"a", "b"},
+	}))
+	assert.NoError(t, f.Write(map[string]any{
+		"name": "Bob, Jr.",
+		"user": map[string]any{"id": 1.5},
+	}))
+	assert.NoError(t, f.Close())
+
+	assert.Equal(t, "name,tags[0],tags[1],user.admin,user.id\n"+
+		"Alice,a,b,true,7\n"+
+		"\"Bob, Jr.\",,,,1.5\n", buf.String())
+}
+
+func TestFormatter_KeepsDocumentKeyOrder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	f, err := NewFormatter(buf, format.FormatterOptions{})
+	assert.NoError(t, err)
+
+	user := ordered.NewMap()
+	user.Set("id", int64(7))
+	user.Set("admin", true)
+	doc := ordered.NewMap()
+	doc.Set("name", "Alice")
+	doc.Set("user", user)
+	doc.Set("age", int64(30))
+
+	assert.NoError(t, f.Write(doc))
+	assert.NoError(t, f.Close())
+
+	assert.Equal(t, "name,user.id,user.admin,age\nAlice,7,true,30\n", buf.String())
+}
+
+func TestFormatter_UnknownColumnIsDropped(t *testing.T) {
+	buf := &bytes.Buffer{}
+	warnings := &bytes.Buffer{}
+	f, err := NewFormatter(buf, format.FormatterOptions{})
+	assert.NoError(t, err)
+	f.warn = warnings
+
+	assert.NoError(t, f.Write(map[string]any{"a": "1"}))
+	assert.NoError(t, f.Write(map[string]any{"a": "2", "b": "3"}))
+	assert.NoError(t, f.Write(map[string]any{"b": "4"}))
+	assert.NoError(t, f.Close())
+
+	assert.Equal(t, "a\n1\n2\n\n", buf.String())
+	assert.Equal(t, 1, strings.Count(warnings.String(), "Warning:"))
+	assert.Contains(t, warnings.String(), `document 2: dropping column "b"`)
+}
+
+func TestFormatter_NoHeaderAndScalars(t *testing.T) {
+	buf := &bytes.Buffer{}
+	f, err := NewFormatter(buf, format.FormatterOptions{NoHeader: true})
+	assert.NoError(t, err)
+
+	assert.NoError(t, f.Write("alice"))
+	assert.NoError(t, f.Write(nil))
+	assert.NoError(t, f.Close())
+
+	assert.Equal(t, "alice\n\n", buf.String())
+}
+
+func TestFormat_TSV_RoundTrip(t *testing.T) {
+	tsv, err := format.Get("tsv")
+	assert.NoError(t, err)
+	assert.Equal(t, "tsv", tsv.Name())
+
+	parser, err := tsv.NewParser(strings.NewReader("name\tage\nAlice\t30\n"), format.ParserOptions{InferTypes: true})
+	assert.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	formatter, err := tsv.NewFormatter(buf, format.FormatterOptions{})
+	assert.NoError(t, err)
+
+	err = parser.ForEach(formatter.Write)
+	assert.NoError(t, err)
+	assert.NoError(t, formatter.Close())
+
+	assert.Equal(t, "name\tage\nAlice\t30\n", buf.String())
+}
+
+func TestFormat_CSVRegistered(t *testing.T) {
+	f, err := format.Get("csv")
+	assert.NoError(t, err)
+	assert.Equal(t, "csv", f.Name())
+}
@@ -0,0 +1,186 @@
+package csv
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/ordered"
+)
+
+// Formatter implements format.Formatter for delimited text output.
+//
+// Nested documents are flattened into dotted columns (user.name, tags[0]).
+// The columns are taken from the first document, in its key order (sorted for
+// a map[string]any), and the header row is written before it. Later documents
+// may omit columns, which are written as empty cells. A column that is not in
+// the header is dropped, with a warning the first time it is seen.
+type Formatter struct {
+	w        *csv.Writer
+	warn     io.Writer
+	noHeader bool
+	columns  []string
+	index    map[string]int
+	dropped  map[string]bool
+	record   []string
+	written  int
+}
+
+// NewFormatter creates a new delimited text formatter.
+// Color and Compact options are ignored.
+func NewFormatter(w io.Writer, opts format.FormatterOptions) (*Formatter, error) {
+	if opts.Delimiter == 0 {
+		opts.Delimiter = ','
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = opts.Delimiter
+	if !validDelimiter(cw.Comma) {
+		return nil, fmt.Errorf("invalid delimiter %q", opts.Delimiter)
+	}
+
+	return &Formatter{
+		w:        cw,
+		warn:     os.Stderr,
+		noHeader: opts.NoHeader,
+	}, nil
+}
+
+// Write outputs a single document as a row.
+func (f *Formatter) Write(doc any) error {
+	f.written++
+
+	var flat []cell
+	flatten(doc, "", &flat)
+
+	if f.columns == nil {
+		if err := f.writeHeader(flat); err != nil {
+			return err
+		}
+	}
+
+	clear(f.record)
+	for _, c := range flat {
+		i, ok := f.index[c.column]
+		if !ok {
+			f.drop(c.column)
+			continue
+		}
+		f.record[i] = c.value
+	}
+
+	if err := f.w.Write(f.record); err != nil {
+		return fmt.Errorf("csv write: %w", err)
+	}
+	return nil
+}
+
+// Close flushes buffered rows to the underlying writer.
+// Must be called when done writing.
+func (f *Formatter) Close() error {
+	f.w.Flush()
+	return f.w.Error()
+}
+
+// drop warns, once per column, that a column missing from the header is not written.
+func (f *Formatter) drop(column string) {
+	if f.dropped[column] {
+		return
+	}
+	if f.dropped == nil {
+		f.dropped = make(map[string]bool)
+	}
+	f.dropped[column] = true
+	_, _ = fmt.Fprintf(f.warn, "Warning: document %d: dropping column %q, which is not in the header (columns are taken from the first document)\n", f.written, column)
+}
+
+// writeHeader fixes the column order from the first document and writes the header row.
+func (f *Formatter) writeHeader(flat []cell) error {
+	f.columns = make([]string, 0, len(flat))
+	f.index = make(map[string]int, len(flat))
+	for _, c := range flat {
+		if _, dup := f.index[c.column]; !dup {
+			f.index[c.column] = len(f.columns)
+			f.columns = append(f.columns, c.column)
+		}
+	}
+	f.record = make([]string, len(f.columns))
+
+	if f.noHeader {
+		return nil
+	}
+	if err := f.w.Write(f.columns); err != nil {
+		return fmt.Errorf("csv write: %w", err)
+	}
+	return nil
+}
+
+// cell is one flattened column of a document and its text.
+type cell struct {
+	column, value string
+}
+
+// flatten appends every leaf value of v to out, named by its dotted path, in
+// key order: an *ordered.Map's own order, or sorted for a map[string]any.
+// Scalars at the root are written to a single "value" column; empty objects
+// and arrays are written as empty cells.
+func flatten(v any, prefix string, out *[]cell) {
+	switch vv := v.(type) {
+	case *ordered.Map:
+		if vv.Len() == 0 && prefix != "" {
+			*out = append(*out, cell{prefix, ""})
+		}
+		for k, child := range vv.All() {
+			flatten(child, format.JoinPath(prefix, k), out)
+		}
+	case map[string]any:
+		if len(vv) == 0 && prefix != "" {
+			*out = append(*out, cell{prefix, ""})
+		}
+		for _, k := range slices.Sorted(maps.Keys(vv)) {
+			flatten(vv[k], format.JoinPath(prefix, k), out)
+		}
+	case []any:
+		if len(vv) == 0 {
+			*out = append(*out, cell{columnName(prefix), ""})
+		}
+		for i, child := range vv {
+			flatten(child, fmt.Sprintf("%s[%d]", prefix, i), out)
+		}
+	default:
+		*out = append(*out, cell{columnName(prefix), cellString(v)})
+	}
+}
+
+func columnName(prefix string) string {
+	if prefix == "" {
+		return "value"
+	}
+	return prefix
+}
+
+// cellString renders a scalar as CSV cell text. null becomes an empty cell.
+func cellString(v any) string {
+	switch vv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return vv
+	case bool:
+		return strconv.FormatBool(vv)
+	case float64:
+		return strconv.FormatFloat(vv, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(vv), 'f', -1, 32)
+	case json.Number:
+		return vv.String()
+	default:
+		return fmt.Sprint(vv)
+	}
+}
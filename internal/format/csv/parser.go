@@ -0,0 +1,121 @@
+package csv
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/ordered"
+)
+
+// Parser implements format.Parser for delimited text.
+// Each row is streamed as an *ordered.Map keyed by the header row, in column
+// order (or col1, col2, ... when there is no header). A repeated header name
+// gets a numeric suffix (id, id_2, ...) so no column is lost.
+type Parser struct {
+	r          *csv.Reader
+	noHeader   bool
+	inferTypes bool
+}
+
+// NewParser creates a new streaming parser for delimited text.
+func NewParser(r io.Reader, opts format.ParserOptions) (*Parser, error) {
+	if opts.Delimiter == 0 {
+		opts.Delimiter = ','
+	}
+
+	cr := csv.NewReader(r)
+	cr.Comma = opts.Delimiter
+	cr.ReuseRecord = true
+	cr.LazyQuotes = cr.Comma == '\t' // TSV has no quoting, so a bare " is text
+	if !validDelimiter(cr.Comma) {
+		return nil, fmt.Errorf("invalid delimiter %q", opts.Delimiter)
+	}
+
+	return &Parser{
+		r:          cr,
+		noHeader:   opts.NoHeader,
+		inferTypes: opts.InferTypes,
+	}, nil
+}
+
+// ForEach streams rows and calls fn for each one.
+// Rows must have the same number of fields as the header.
+func (p *Parser) ForEach(fn func(any) error) error {
+	var header []string
+
+	if !p.noHeader {
+		rec, err := p.r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil // Empty input has no rows
+			}
+			return fmt.Errorf("failed to read header: %w", err)
+		}
+		header = uniqueHeader(rec) // Copies rec, which is reused by the reader
+	}
+
+	for {
+		rec, err := p.r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if header == nil {
+			header = positionalHeader(len(rec))
+		}
+
+		row := ordered.NewMap()
+		for i, name := range header {
+			row.Set(name, p.cellValue(rec[i]))
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// cellValue returns the cell as a string, or as a typed value when inference is enabled.
+func (p *Parser) cellValue(s string) any {
+	if !p.inferTypes {
+		return s
+	}
+	return format.ParseScalar(s)
+}
+
+// positionalHeader names columns col1..colN for input without a header row.
+func positionalHeader(n int) []string {
+	header := make([]string, n)
+	for i := range header {
+		header[i] = "col" + strconv.Itoa(i+1)
+	}
+	return header
+}
+
+// validDelimiter mirrors the restrictions encoding/csv places on delimiters.
+func validDelimiter(r rune) bool {
+	return r != 0 && r != '"' && r != '\r' && r != '\n' && r != 0xFFFD
+}
+
+// uniqueHeader returns a copy of rec in which every repeated name gets the
+// first free suffix _2, _3, ...
+func uniqueHeader(rec []string) []string {
+	header := make([]string, len(rec))
+	seen := make(map[string]bool, len(rec))
+	for i, name := range rec {
+		unique := name
+		for n := 2; seen[unique]; n++ {
+			unique = fmt.Sprintf("%s_%d", name, n)
+		}
+		seen[unique] = true
+		header[i] = unique
+	}
+	return header
+}
@@ -37,20 +37,23 @@ type Format interface {
 	// Name returns the format identifier (e.g., "json", "yaml", "csv")
 	Name() string
 
-	// NewParser creates a streaming parser for this format
-	NewParser(r io.Reader) (Parser, error)
+	// NewParser creates a streaming parser for this format with the given options
+	NewParser(r io.Reader, opts ParserOptions) (Parser, error)
 
-	// NewFormatter creates a formatter for output with the given options
-	NewFormatter(w io.Writer, opts FormatterOptions) Formatter
+	// NewFormatter creates a formatter for output with the given options.
+	// Returns an error if the options are invalid for this format.
+	NewFormatter(w io.Writer, opts FormatterOptions) (Formatter, error)
 }
 
 // Parser streams documents from input data without loading everything into memory.
 // Parsers normalize data into Go's standard types (map[string]any, []any, primitives).
+// Parsers for formats where key order is meaningful to users (JSON, YAML, CSV) produce
+// *ordered.Map instead of map[string]any so the order survives to the output.
 type Parser interface {
 	// ForEach calls fn for each document/row in the input stream.
 	// For JSON arrays, each array element is treated as a separate document.
 	// For YAML, each document separated by --- is processed individually.
-	// For CSV, each row (after headers) is converted to map[string]any.
+	// For CSV, each row (after headers) is converted to an *ordered.Map in column order.
 	// Processing stops when fn returns an error or end of input is reached.
 	ForEach(fn func(doc any) error) error
 }
@@ -58,7 +61,8 @@ type Parser interface {
 // Formatter writes documents to output, with optional formatting and styling.
 type Formatter interface {
 	// Write outputs a single document/row.
-	// The document should be in normalized form (map[string]any, []any, etc.).
+	// The document should be in normalized form (map[string]any or *ordered.Map, []any, etc.).
+	// Formatters that write keys in order (JSON, YAML) keep the order of an *ordered.Map.
 	Write(doc any) error
 
 	// Close flushes any buffered data and releases resources.
@@ -66,6 +70,72 @@ type Formatter interface {
 	Close() error
 }
 
+// Describer is implemented by parsers of formats that store their schema in
+// the file (Avro, Parquet), so flow schema can show it without reading rows.
+type Describer interface {
+	// Describe returns a document describing the input: its schema and
+	// whatever else the file records about its layout.
+	Describe() (any, error)
+}
+
+// ParserOptions holds parsing options. Formats ignore options that do not apply to them.
+type ParserOptions struct {
+	// Delimiter separates fields in delimited text formats (CSV, TSV).
+	// Zero means the format's default.
+	Delimiter rune
+
+	// NoHeader treats the first row of delimited text as data rather than
+	// column names; columns are then named col1, col2, ...
+	NoHeader bool
+
+	// InferTypes converts textual cells that look like numbers or booleans
+	// into numeric and boolean values, and empty cells into null.
+	InferTypes bool
+
+	// YAMLNodes makes the YAML parser pass each document through as a
+	// *yaml.Node so operations can edit it in place, keeping comments, quoting,
+	// flow/block style and anchors for the parts they don't touch.
+	// Only useful when the output is YAML as well.
+	YAMLNodes bool
+
+	// SpoolLimit caps how many bytes of non-seekable input (stdin, pipes,
+	// decompressed streams) are copied aside for formats that need random
+	// access, like Parquet. Zero means no limit.
+	SpoolLimit int64
+
+	// Columns lists the fields that are read from each document, as keys
+	// from the root. Columnar formats (Parquet) may decode only these and
+	// leave the other fields out. Empty means all fields.
+	Columns [][]string
+
+	// Predicates are conditions every document that is kept satisfies.
+	// Formats with statistics for blocks of rows (Parquet row groups) may skip
+	// blocks that cannot match. Documents that are read still go through the
+	// pipeline, so parsers don't need to filter them.
+	Predicates []Predicate
+
+	// Schema is a reader schema that formats with schema resolution (Avro)
+	// decode every file into, whatever schema it was written with.
+	// Empty means each file's own schema.
+	Schema string
+
+	// RawUnions keeps Avro union values of named types (records, enums,
+	// fixed) as decoded: a single-key object from the type's name to the
+	// value. By default the value is unwrapped.
+	RawUnions bool
+}
+
+// Predicate is a condition on a single field, such as status=active or
+// age>=30, that can rule out a block of rows from its statistics.
+type Predicate interface {
+	// Path returns the keys of the field from the document root.
+	Path() []string
+
+	// MayMatch reports whether a value between min and max, inclusive, could
+	// satisfy the condition. A nil bound is unknown.
+	MayMatch(min, max any) bool
+}
+
 // FormatterOptions holds common formatting options applicable across formats.
 type FormatterOptions struct {
 	// Color enables ANSI color codes in output (for terminal display)
@@ -73,4 +143,27 @@ type FormatterOptions struct {
 
 	// Compact removes unnecessary whitespace for minimal output size
 	Compact bool
+
+	// Schema is an explicit schema definition for formats that embed one
+	// (e.g. an Avro .avsc document). If empty, the schema is inferred.
+	Schema string
+
+	// Codec selects the compression codec for binary formats
+	// (e.g. "snappy"). If empty, the format's default is used.
+	Codec string
+
+	// SampleSize is the number of documents buffered to infer a schema
+	// when none is given. Zero means the format's default.
+	SampleSize int
+
+	// RowGroupSize is the number of rows per row group for columnar
+	// formats (e.g. Parquet). Zero means the format's default.
+	RowGroupSize int
+
+	// Delimiter separates fields in delimited text formats (CSV, TSV).
+	// Zero means the format's default.
+	Delimiter rune
+
+	// NoHeader omits the header row from delimited text output.
+	NoHeader bool
 }
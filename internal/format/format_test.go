@@ -52,12 +52,12 @@ func (m *mockFormat) Name() string {
 	return m.name
 }
 
-func (m *mockFormat) NewParser(r io.Reader) (Parser, error) {
+func (m *mockFormat) NewParser(r io.Reader, opts ParserOptions) (Parser, error) {
 	return m.parser, nil
 }
 
-func (m *mockFormat) NewFormatter(w io.Writer, opts FormatterOptions) Formatter {
-	return m.formatter
+func (m *mockFormat) NewFormatter(w io.Writer, opts FormatterOptions) (Formatter, error) {
+	return m.formatter, nil
 }
 
 // Tests
@@ -21,13 +21,13 @@ func (f *Format) Name() string {
 }
 
 // NewParser creates a new JSON streaming parser.
-func (f *Format) NewParser(r io.Reader) (format.Parser, error) {
+func (f *Format) NewParser(r io.Reader, opts format.ParserOptions) (format.Parser, error) {
 	return NewParser(r), nil
 }
 
 // NewFormatter creates a new JSON formatter.
-func (f *Format) NewFormatter(w io.Writer, opts format.FormatterOptions) format.Formatter {
-	return NewFormatter(w, opts)
+func (f *Format) NewFormatter(w io.Writer, opts format.FormatterOptions) (format.Formatter, error) {
+	return NewFormatter(w, opts), nil
 }
 
 // Register the JSON format on package initialization
@@ -2,10 +2,14 @@ package json
 
 import (
 	"bytes"
+	"fmt"
+	"io"
+	"runtime"
 	"strings"
 	"testing"
 
 	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/ordered"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,7 +26,7 @@ func TestParser_SingleObject(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, docs, 1)
 
-	obj, ok := docs[0].(map[string]any)
+	obj, ok := ordered.ToPlain(docs[0]).(map[string]any)
 	assert.True(t, ok)
 	assert.Equal(t, "Alice", obj["name"])
 	assert.Equal(t, float64(30), obj["age"])
@@ -42,7 +46,7 @@ func TestParser_Array(t *testing.T) {
 	assert.Len(t, docs, 3, "array elements should be streamed individually")
 
 	for i, doc := range docs {
-		obj, ok := doc.(map[string]any)
+		obj, ok := ordered.ToPlain(doc).(map[string]any)
 		assert.True(t, ok)
 		assert.Equal(t, float64(i+1), obj["id"])
 	}
@@ -64,7 +68,7 @@ func TestParser_ConcatenatedDocuments(t *testing.T) {
 	assert.Len(t, docs, 3)
 
 	for i, doc := range docs {
-		obj, ok := doc.(map[string]any)
+		obj, ok := ordered.ToPlain(doc).(map[string]any)
 		assert.True(t, ok)
 		assert.Equal(t, float64(i+1), obj["id"])
 	}
@@ -260,7 +264,7 @@ func TestParser_MixedTypes(t *testing.T) {
 	assert.Equal(t, "string", docs[1])
 	assert.Equal(t, true, docs[2])
 	assert.Nil(t, docs[3])
-	assert.IsType(t, map[string]any{}, docs[4])
+	assert.IsType(t, &ordered.Map{}, docs[4])
 }
 
 func TestFormat_Integration(t *testing.T) {
@@ -271,7 +275,7 @@ func TestFormat_Integration(t *testing.T) {
 
 	// Test parser
 	input := strings.NewReader(`{"test": true}`)
-	parser, err := fmt.NewParser(input)
+	parser, err := fmt.NewParser(input, format.ParserOptions{})
 	assert.NoError(t, err)
 
 	var docs []any
@@ -284,7 +288,8 @@ func TestFormat_Integration(t *testing.T) {
 
 	// Test formatter
 	buf := &bytes.Buffer{}
-	formatter := fmt.NewFormatter(buf, format.FormatterOptions{})
+	formatter, err := fmt.NewFormatter(buf, format.FormatterOptions{})
+	assert.NoError(t, err)
 	err = formatter.Write(docs[0])
 	assert.NoError(t, err)
 	err = formatter.Close()
@@ -292,3 +297,154 @@ func TestFormat_Integration(t *testing.T) {
 
 	assert.Contains(t, buf.String(), `"test"`)
 }
+
+func TestParser_ArrayStreamsBeforeEnd(t *testing.T) {
+	// Elements must be emitted before the rest of the array is read,
+	// so a syntax error late in the array still yields the earlier elements.
+	input := `[{"id": 1}, {"id": 2}, {oops}]`
+	parser := NewParser(strings.NewReader(input))
+
+	var docs []any
+	err := parser.ForEach(func(doc any) error {
+		docs = append(docs, doc)
+		return nil
+	})
+
+	assert.Error(t, err)
+	assert.Len(t, docs, 2, "elements before the error should already be streamed")
+}
+
+func TestParser_TruncatedArray(t *testing.T) {
+	input := `[{"id": 1}, {"id": 2}`
+	parser := NewParser(strings.NewReader(input))
+
+	var docs []any
+	err := parser.ForEach(func(doc any) error {
+		docs = append(docs, doc)
+		return nil
+	})
+
+	assert.Error(t, err, "unterminated array should fail")
+	assert.Len(t, docs, 2)
+}
+
+func TestParser_ArrayFollowedByDocuments(t *testing.T) {
+	input := `  [{"id": 1}, {"id": 2}]
+{"id": 3}`
+	parser := NewParser(strings.NewReader(input))
+
+	var docs []any
+	err := parser.ForEach(func(doc any) error {
+		docs = append(docs, doc)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, docs, 3)
+	assert.Equal(t, float64(3), ordered.ToPlain(docs[2]).(map[string]any)["id"])
+}
+
+func TestParser_EmptyInput(t *testing.T) {
+	parser := NewParser(strings.NewReader("  \n"))
+
+	err := parser.ForEach(func(doc any) error {
+		return nil
+	})
+
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+// arrayReader generates a JSON array of n objects on the fly, so the
+// benchmark input itself never lives in memory.
+type arrayReader struct {
+	n    int
+	i    int
+	buf  []byte
+	done bool
+}
+
+func newArrayReader(n int) *arrayReader {
+	return &arrayReader{n: n, buf: []byte("[")}
+}
+
+func (r *arrayReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		switch {
+		case r.i == r.n:
+			r.buf = []byte("]")
+			r.done = true
+		case r.i == 0:
+			r.buf = fmt.Appendf(nil, `{"id":%d,"name":"user-%d","tags":["a","b","c"]}`, r.i, r.i)
+			r.i++
+		default:
+			r.buf = fmt.Appendf(nil, `,{"id":%d,"name":"user-%d","tags":["a","b","c"]}`, r.i, r.i)
+			r.i++
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// BenchmarkParser_LargeArray streams arrays of increasing size and reports the
+// peak live heap observed while streaming. The peak should stay flat as the
+// element count grows, since only one element is held at a time.
+func BenchmarkParser_LargeArray(b *testing.B) {
+	for _, n := range []int{1_000, 100_000, 1_000_000} {
+		b.Run(fmt.Sprintf("elements=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+
+			var peak uint64
+			for b.Loop() {
+				runtime.GC()
+				var ms runtime.MemStats
+
+				count := 0
+				parser := NewParser(newArrayReader(n))
+				err := parser.ForEach(func(doc any) error {
+					count++
+					if count%1_000 == 0 {
+						runtime.ReadMemStats(&ms)
+						peak = max(peak, ms.HeapAlloc)
+					}
+					return nil
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if count != n {
+					b.Fatalf("got %d elements, want %d", count, n)
+				}
+			}
+
+			b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+		})
+	}
+}
+
+func TestParser_PreservesKeyOrder(t *testing.T) {
+	input := `{"zeta": 1, "alpha": {"y": 2, "x": [{"b": 1, "a": 2}]}, "mid": null}`
+	parser := NewParser(strings.NewReader(input))
+
+	buf := &bytes.Buffer{}
+	formatter := NewFormatter(buf, format.FormatterOptions{Compact: true})
+	err := parser.ForEach(formatter.Write)
+	assert.NoError(t, err)
+
+	assert.Equal(t, `{"zeta":1,"alpha":{"y":2,"x":[{"b":1,"a":2}]},"mid":null}`+"\n", buf.String())
+}
+
+func TestFormatter_OrderedPretty(t *testing.T) {
+	doc := ordered.NewMap()
+	doc.Set("z", "<tag>")
+	doc.Set("a", []any{float64(1)})
+
+	buf := &bytes.Buffer{}
+	formatter := NewFormatter(buf, format.FormatterOptions{})
+	assert.NoError(t, formatter.Write(doc))
+	assert.Equal(t, "{\n  \"z\": \"<tag>\",\n  \"a\": [\n    1\n  ]\n}\n", buf.String())
+}
@@ -1,9 +1,12 @@
 package json
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
 	"io"
+
+	"github.com/GeoffMall/flow/internal/ordered"
 )
 
 // Parser implements format.Parser for JSON format.
@@ -12,81 +15,94 @@ import (
 //   - Concatenated JSON documents
 //   - Standard JSON objects and primitives
 type Parser struct {
+	br  *bufio.Reader
 	dec *json.Decoder
 }
 
 // NewParser creates a new JSON streaming parser.
 func NewParser(r io.Reader) *Parser {
-	dec := json.NewDecoder(r)
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
 	dec.UseNumber() // Preserve number precision
-	return &Parser{dec: dec}
+	return &Parser{br: br, dec: dec}
 }
 
 // ForEach streams JSON values and calls fn for each document.
-// If the first value is an array, each array element is streamed separately.
+// If the first value is an array, each array element is decoded and passed to fn
+// as soon as it is read, so memory use is bounded by the largest element rather
+// than by the size of the array.
 // Supports concatenated JSON documents.
 func (p *Parser) ForEach(fn func(any) error) error {
-	// Read first document
-	rm, err := p.decodeFirstMessage()
+	// Peek at the first value to decide whether to stream an array
+	isArray, err := p.firstValueIsArray()
 	if err != nil {
 		return err
 	}
 
-	// If first document is an array, stream its elements
-	if p.isArray(rm) {
-		if err := p.processArrayElements(rm, fn); err != nil {
+	if isArray {
+		if err := p.processArrayElements(fn); err != nil {
 			return err
 		}
-		// Continue with any concatenated documents
-		return p.processConcatenatedDocuments(fn)
-	}
-
-	// Process single value
-	if err := p.processRawMessage(rm, fn); err != nil {
-		return err
 	}
 
-	// Continue with any concatenated documents
+	// Continue with any (remaining) concatenated documents
 	return p.processConcatenatedDocuments(fn)
 }
 
-// decodeFirstMessage reads the first JSON message from the decoder
-func (p *Parser) decodeFirstMessage() (json.RawMessage, error) {
-	var rm json.RawMessage
-	if err := p.dec.Decode(&rm); err != nil {
-		if errors.Is(err, io.EOF) {
-			return nil, io.EOF
+// firstValueIsArray skips leading whitespace and reports whether the next
+// value in the input starts with '['. It does not consume the value itself.
+// Returns io.EOF if the input is empty.
+func (p *Parser) firstValueIsArray() (bool, error) {
+	for {
+		b, err := p.br.Peek(1)
+		if err != nil {
+			return false, err
 		}
-		return nil, err
-	}
-	return rm, nil
-}
 
-// isArray checks if the raw message represents a JSON array
-func (p *Parser) isArray(rm json.RawMessage) bool {
-	return len(rm) > 0 && rm[0] == '['
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = p.br.ReadByte()
+		default:
+			return b[0] == '[', nil
+		}
+	}
 }
 
-// processArrayElements streams individual elements from a JSON array
-func (p *Parser) processArrayElements(rm json.RawMessage, fn func(any) error) error {
-	var arr []any
-	if err := json.Unmarshal(rm, &arr); err != nil {
+// processArrayElements streams individual elements from a top-level JSON array
+// using the decoder's token API, without buffering the array.
+func (p *Parser) processArrayElements(fn func(any) error) error {
+	// Consume the opening '['
+	if _, err := p.dec.Token(); err != nil {
 		return err
 	}
 
-	for i := range arr {
-		if err := fn(arr[i]); err != nil {
+	for p.dec.More() {
+		var rm json.RawMessage
+		if err := p.dec.Decode(&rm); err != nil {
+			return err
+		}
+
+		if err := p.processRawMessage(rm, fn); err != nil {
 			return err
 		}
 	}
 
+	// Consume the closing ']'
+	if _, err := p.dec.Token(); err != nil {
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
 	return nil
 }
 
-// processRawMessage converts and processes a single raw JSON message
+// processRawMessage converts and processes a single raw JSON message.
+// Objects are decoded as *ordered.Map so key order survives to the output.
 func (p *Parser) processRawMessage(rm json.RawMessage, fn func(any) error) error {
-	var v any
-	if err := json.Unmarshal(rm, &v); err != nil {
+	v, err := ordered.DecodeJSON(rm)
+	if err != nil {
 		return err
 	}
 
@@ -0,0 +1,110 @@
+package parquet
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/GeoffMall/flow/internal/ordered"
+	"github.com/parquet-go/parquet-go"
+)
+
+// Describe returns the file's schema as a list of leaf columns, and for each
+// row group its row count and the compression, sizes and statistics of each
+// column chunk. It only reads the footer.
+func (p *Parser) Describe() (any, error) {
+	schema := p.file.Schema()
+	meta := p.file.Metadata()
+
+	desc := ordered.NewMap()
+	desc.Set("format", "parquet")
+	desc.Set("rows", p.file.NumRows())
+	if meta.CreatedBy != "" {
+		desc.Set("created_by", meta.CreatedBy)
+	}
+
+	columns := make([]any, 0, len(schema.Columns()))
+	for _, path := range schema.Columns() {
+		leaf, _ := schema.Lookup(path...)
+		col := ordered.NewMap()
+		col.Set("path", strings.Join(path, "."))
+		col.Set("type", leaf.Node.Type().Kind().String())
+		if lt := leaf.Node.Type().LogicalType(); lt != nil {
+			col.Set("logical_type", lt.String())
+		}
+		col.Set("repetition", repetition(leaf.Node))
+		columns = append(columns, col)
+	}
+	desc.Set("schema", columns)
+
+	rowGroups := make([]any, 0, len(meta.RowGroups))
+	for i, rg := range p.file.RowGroups() {
+		group := ordered.NewMap()
+		group.Set("rows", rg.NumRows())
+		group.Set("size", meta.RowGroups[i].TotalByteSize)
+
+		chunks := make([]any, 0, len(rg.ColumnChunks()))
+		for j, chunk := range rg.ColumnChunks() {
+			chunkMeta := meta.RowGroups[i].Columns[j].MetaData
+			c := ordered.NewMap()
+			c.Set("path", strings.Join(chunkMeta.PathInSchema, "."))
+			c.Set("compression", chunkMeta.Codec.String())
+			c.Set("values", chunkMeta.NumValues)
+			c.Set("compressed_size", chunkMeta.TotalCompressedSize)
+			c.Set("uncompressed_size", chunkMeta.TotalUncompressedSize)
+			if fc, ok := chunk.(*parquet.FileColumnChunk); ok {
+				c.Set("nulls", fc.NullCount())
+				if minValue, maxValue, ok := fc.Bounds(); ok {
+					c.Set("min", statValue(fc.Type(), minValue))
+					c.Set("max", statValue(fc.Type(), maxValue))
+				}
+			}
+			chunks = append(chunks, c)
+		}
+		group.Set("columns", chunks)
+		rowGroups = append(rowGroups, group)
+	}
+	desc.Set("row_groups", rowGroups)
+	return desc, nil
+}
+
+func repetition(node parquet.Node) string {
+	switch {
+	case node.Repeated():
+		return "repeated"
+	case node.Optional():
+		return "optional"
+	}
+	return "required"
+}
+
+// statValue converts a min/max statistic to a value for display: numbers and
+// booleans as they are stored, strings as text and other bytes as hex.
+func statValue(typ parquet.Type, v parquet.Value) any {
+	lt := typ.LogicalType()
+	unsigned := lt != nil && lt.Integer != nil && !lt.Integer.IsSigned
+
+	switch typ.Kind() {
+	case parquet.Boolean:
+		return v.Boolean()
+	case parquet.Int32:
+		if unsigned {
+			return uint32(v.Int32()) // #nosec G115 - reinterprets the stored bits
+		}
+		return v.Int32()
+	case parquet.Int64:
+		if unsigned {
+			return uint64(v.Int64()) // #nosec G115 - reinterprets the stored bits
+		}
+		return v.Int64()
+	case parquet.Int96:
+		return v.Int96().String()
+	case parquet.Float:
+		return v.Float()
+	case parquet.Double:
+		return v.Double()
+	}
+	if lt != nil && (lt.UTF8 != nil || lt.Enum != nil || lt.Json != nil) {
+		return string(v.ByteArray())
+	}
+	return "0x" + hex.EncodeToString(v.ByteArray())
+}
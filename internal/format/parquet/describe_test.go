@@ -0,0 +1,60 @@
+package parquet
+
+import (
+	"os"
+	"testing"
+
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/ordered"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_Describe(t *testing.T) {
+	docs := make([]any, 5)
+	for i := range docs {
+		doc := map[string]any{"id": float64(i), "name": string(rune('a' + i))}
+		if i == 1 {
+			doc["name"] = nil
+		}
+		docs[i] = doc
+	}
+	path := writeFile(t, format.FormatterOptions{RowGroupSize: 3, Codec: "zstd"}, docs...)
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	parser, err := NewParser(file, format.ParserOptions{})
+	require.NoError(t, err)
+
+	desc, err := parser.Describe()
+	require.NoError(t, err)
+	report := ordered.ToPlain(desc).(map[string]any)
+
+	assert.Equal(t, "parquet", report["format"])
+	assert.Equal(t, int64(5), report["rows"])
+	assert.Equal(t, []any{
+		map[string]any{"path": "id", "type": "INT64", "logical_type": "INT(64,true)", "repetition": "required"},
+		map[string]any{"path": "name", "type": "BYTE_ARRAY", "logical_type": "STRING", "repetition": "optional"},
+	}, report["schema"])
+
+	groups := report["row_groups"].([]any)
+	require.Len(t, groups, 2)
+	first := groups[0].(map[string]any)
+	assert.Equal(t, int64(3), first["rows"])
+	id := first["columns"].([]any)[0].(map[string]any)
+	assert.Equal(t, "id", id["path"])
+	assert.Equal(t, "ZSTD", id["compression"])
+	assert.Equal(t, int64(0), id["min"])
+	assert.Equal(t, int64(2), id["max"])
+
+	name := first["columns"].([]any)[1].(map[string]any)
+	assert.Equal(t, int64(1), name["nulls"])
+	assert.Equal(t, "a", name["min"])
+	assert.Equal(t, "c", name["max"])
+
+	name = groups[1].(map[string]any)["columns"].([]any)[1].(map[string]any)
+	assert.Equal(t, int64(0), name["nulls"])
+	assert.Equal(t, "d", name["min"])
+	assert.Equal(t, "e", name["max"])
+}
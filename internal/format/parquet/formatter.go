@@ -0,0 +1,142 @@
+package parquet
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/ordered"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+)
+
+// DefaultRowGroupSize is the number of rows per row group when none is given.
+const DefaultRowGroupSize = 10_000
+
+// Formatter implements format.Formatter for Parquet output.
+//
+// The schema is inferred from the documents of the first row group, which are
+// buffered until the group is full (or Close is called). Documents written
+// after that must fit the inferred schema. The footer is written on Close.
+type Formatter struct {
+	w            io.Writer
+	codec        compress.Codec
+	rowGroupSize int
+
+	root    *column
+	writer  *parquet.Writer
+	pending []any // first row group, buffered for schema inference
+	written int   // number of documents handed to Write
+}
+
+// NewFormatter creates a new Parquet formatter.
+// opts.Codec selects the page compression codec (snappy, zstd, gzip or none)
+// and opts.RowGroupSize the number of rows per row group.
+// Color and Compact options are ignored for this binary format.
+func NewFormatter(w io.Writer, opts format.FormatterOptions) (*Formatter, error) {
+	codec, err := parseCodec(opts.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	rowGroupSize := opts.RowGroupSize
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+
+	return &Formatter{
+		w:            w,
+		codec:        codec,
+		rowGroupSize: rowGroupSize,
+	}, nil
+}
+
+// Write adds a single document as a row.
+// Returns an error if the document does not fit the schema.
+func (f *Formatter) Write(doc any) error {
+	f.written++
+
+	if f.writer == nil {
+		f.pending = append(f.pending, doc)
+		if len(f.pending) < f.rowGroupSize {
+			return nil
+		}
+		return f.flushPending()
+	}
+
+	return f.writeRow(doc, f.written)
+}
+
+// Close flushes buffered rows and writes the Parquet footer.
+// Must be called when done writing.
+func (f *Formatter) Close() error {
+	if f.writer == nil {
+		if err := f.flushPending(); err != nil {
+			return err
+		}
+	}
+	if err := f.writer.Close(); err != nil {
+		return fmt.Errorf("failed to write parquet footer: %w", err)
+	}
+	return nil
+}
+
+// flushPending infers the schema from the buffered documents, creates the
+// writer and writes them out.
+func (f *Formatter) flushPending() error {
+	root, schema, err := inferSchema(f.pending)
+	if err != nil {
+		return fmt.Errorf("failed to infer parquet schema: %w", err)
+	}
+
+	config, err := parquet.NewWriterConfig(
+		schema,
+		parquet.Compression(f.codec),
+		parquet.MaxRowsPerRowGroup(int64(f.rowGroupSize)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to configure parquet writer: %w", err)
+	}
+
+	f.root = root
+	f.writer = parquet.NewWriter(f.w, config)
+
+	first := f.written - len(f.pending) + 1
+	for i, doc := range f.pending {
+		if err := f.writeRow(doc, first+i); err != nil {
+			return err
+		}
+	}
+	f.pending = nil
+	return nil
+}
+
+// writeRow conforms doc to the schema and hands it to the writer.
+// n is the 1-based document number, used in error messages.
+func (f *Formatter) writeRow(doc any, n int) error {
+	doc = ordered.ToPlain(doc) // Columns are matched by name; key order only matters for inference
+	row, err := f.root.conform(doc, "")
+	if err != nil {
+		return fmt.Errorf("document %d does not match parquet schema: %w", n, err)
+	}
+	if err := f.writer.Write(row); err != nil {
+		return fmt.Errorf("failed to write parquet row %d: %w", n, err)
+	}
+	return nil
+}
+
+// parseCodec maps a user-facing codec name to a parquet compression codec.
+func parseCodec(name string) (compress.Codec, error) {
+	switch name {
+	case "", "snappy":
+		return &parquet.Snappy, nil
+	case "zstd":
+		return &parquet.Zstd, nil
+	case "gzip":
+		return &parquet.Gzip, nil
+	case "none", "uncompressed":
+		return &parquet.Uncompressed, nil
+	default:
+		return nil, fmt.Errorf("unsupported parquet compression %q (supported: snappy, zstd, gzip, none)", name)
+	}
+}
@@ -2,11 +2,13 @@ package parquet
 
 import (
 	"bytes"
+	"io"
 	"os"
 	"strings"
 	"testing"
 
 	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/ordered"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,7 +18,7 @@ func TestParser_MultipleRecords(t *testing.T) {
 	assert.NoError(t, err)
 	defer f.Close()
 
-	parser, err := NewParser(f)
+	parser, err := NewParser(f, format.ParserOptions{})
 	assert.NoError(t, err)
 
 	// Collect all records
@@ -54,7 +56,7 @@ func TestParser_SingleRecord(t *testing.T) {
 	assert.NoError(t, err)
 	defer f.Close()
 
-	parser, err := NewParser(f)
+	parser, err := NewParser(f, format.ParserOptions{})
 	assert.NoError(t, err)
 
 	// Collect all records
@@ -82,16 +84,15 @@ func TestParser_InvalidFile(t *testing.T) {
 	assert.NoError(t, err)
 	defer f.Close()
 
-	_, err = NewParser(f)
+	_, err = NewParser(f, format.ParserOptions{})
 	assert.Error(t, err, "should fail to parse non-Parquet file")
 }
 
-func TestParser_StdinError(t *testing.T) {
-	// Test that NewParser returns error for stdin (non-seekable input)
-	r := strings.NewReader("fake parquet data")
-	_, err := NewParser(r)
-	assert.Error(t, err, "should fail for non-seekable input")
-	assert.Contains(t, err.Error(), "seekable file input", "error should mention seekable requirement")
+func TestParser_InvalidStream(t *testing.T) {
+	// Non-seekable input is spooled, then rejected like an invalid file
+	r := io.MultiReader(strings.NewReader("fake parquet data"))
+	_, err := NewParser(r, format.ParserOptions{})
+	assert.ErrorContains(t, err, "failed to open parquet file")
 }
 
 func TestParser_EarlyReturn(t *testing.T) {
@@ -100,7 +101,7 @@ func TestParser_EarlyReturn(t *testing.T) {
 	assert.NoError(t, err)
 	defer f.Close()
 
-	parser, err := NewParser(f)
+	parser, err := NewParser(f, format.ParserOptions{})
 	assert.NoError(t, err)
 
 	// Process only first record
@@ -126,7 +127,7 @@ func TestFormat_NewParser_Success(t *testing.T) {
 	assert.NoError(t, err)
 	defer file.Close()
 
-	parser, err := f.NewParser(file)
+	parser, err := f.NewParser(file, format.ParserOptions{})
 	assert.NoError(t, err)
 	assert.NotNil(t, parser)
 }
@@ -135,16 +136,190 @@ func TestFormat_NewParser_Error(t *testing.T) {
 	f := &Format{}
 	r := strings.NewReader("not parquet data")
 
-	_, err := f.NewParser(r)
+	_, err := f.NewParser(r, format.ParserOptions{})
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "seekable")
+	assert.Contains(t, err.Error(), "failed to open parquet file")
 }
 
-func TestFormat_NewFormatter_Panics(t *testing.T) {
-	f := &Format{}
+// writeFile writes docs to a temporary parquet file and returns its path.
+func writeFile(t *testing.T, opts format.FormatterOptions, docs ...any) string {
+	t.Helper()
+
+	path := t.TempDir() + "/out.parquet"
+	file, err := os.Create(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	f, err := (&Format{}).NewFormatter(file, opts)
+	assert.NoError(t, err)
+	for _, doc := range docs {
+		assert.NoError(t, f.Write(doc))
+	}
+	assert.NoError(t, f.Close())
+	return path
+}
+
+// readFile reads every row of the parquet file at path.
+func readFile(t *testing.T, path string) []map[string]any {
+	t.Helper()
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	parser, err := NewParser(file, format.ParserOptions{})
+	assert.NoError(t, err)
+
+	var rows []map[string]any
+	err = parser.ForEach(func(doc any) error {
+		rows = append(rows, doc.(map[string]any))
+		return nil
+	})
+	assert.NoError(t, err)
+	return rows
+}
+
+func TestFormatter_RoundTrip(t *testing.T) {
+	path := writeFile(t, format.FormatterOptions{},
+		map[string]any{
+			"name":    "Alice",
+			"age":     float64(30),
+			"score":   9.5,
+			"active":  true,
+			"tags":    []any{"a", "b"},
+			"address": map[string]any{"city": "Paris"},
+			"orders":  []any{map[string]any{"id": float64(1)}},
+		},
+		map[string]any{
+			"name":    "Bob",
+			"age":     float64(25),
+			"score":   float64(7),
+			"active":  false,
+			"tags":    []any{},
+			"address": map[string]any{"city": "Rome"},
+			"orders":  []any{},
+		},
+	)
+
+	rows := readFile(t, path)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, "Alice", rows[0]["name"])
+	assert.Equal(t, int64(30), rows[0]["age"])
+	assert.Equal(t, 9.5, rows[0]["score"])
+	assert.Equal(t, 7.0, rows[1]["score"], "integral doubles widen with the column")
+	assert.Equal(t, []any{"a", "b"}, rows[0]["tags"])
+	assert.Equal(t, map[string]any{"city": "Rome"}, rows[1]["address"])
+	assert.Equal(t, []any{map[string]any{"id": int64(1)}}, rows[0]["orders"])
+}
+
+func TestFormatter_OptionalColumns(t *testing.T) {
+	path := writeFile(t, format.FormatterOptions{},
+		map[string]any{"id": float64(1), "note": nil},
+		map[string]any{"id": float64(2), "note": "hi", "extra": true},
+	)
+
+	rows := readFile(t, path)
+	assert.Len(t, rows, 2)
+	assert.Nil(t, rows[0]["note"])
+	assert.Nil(t, rows[0]["extra"])
+	assert.Equal(t, "hi", rows[1]["note"])
+	assert.Equal(t, true, rows[1]["extra"])
+}
+
+func TestFormatter_KeepsDocumentKeyOrder(t *testing.T) {
+	user := ordered.NewMap()
+	user.Set("id", int64(7))
+	user.Set("admin", true)
+	doc := ordered.NewMap()
+	doc.Set("name", "Alice")
+	doc.Set("user", user)
+	doc.Set("age", int64(30))
+
+	path := writeFile(t, format.FormatterOptions{}, doc, map[string]any{"name": "Bob", "zip": "02134", "city": "Rome"})
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	parser, err := NewParser(file, format.ParserOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"name"}, {"user", "id"}, {"user", "admin"}, {"age"}, {"city"}, {"zip"},
+	}, parser.file.Schema().Columns(), "first-seen order, then sorted keys of plain maps")
+
+	rows := readFile(t, path)
+	assert.Equal(t, map[string]any{
+		"name": "Alice", "user": map[string]any{"id": int64(7), "admin": true}, "age": int64(30), "city": nil, "zip": nil,
+	}, rows[0])
+	assert.Equal(t, map[string]any{"name": "Bob", "user": nil, "age": nil, "city": "Rome", "zip": "02134"}, rows[1])
+}
+
+func TestFormatter_RowGroupsAndCodecs(t *testing.T) {
+	for _, codec := range []string{"snappy", "zstd", "gzip", "none"} {
+		t.Run(codec, func(t *testing.T) {
+			docs := make([]any, 5)
+			for i := range docs {
+				docs[i] = map[string]any{"id": float64(i)}
+			}
+			path := writeFile(t, format.FormatterOptions{Codec: codec, RowGroupSize: 2}, docs...)
+
+			file, err := os.Open(path)
+			assert.NoError(t, err)
+			defer file.Close()
+			parser, err := NewParser(file, format.ParserOptions{})
+			assert.NoError(t, err)
+			assert.Len(t, parser.file.RowGroups(), 3)
+
+			assert.Len(t, readFile(t, path), 5)
+		})
+	}
+}
+
+func TestFormatter_NoDocuments(t *testing.T) {
+	var buf bytes.Buffer
+	f, err := NewFormatter(&buf, format.FormatterOptions{})
+	assert.NoError(t, err)
+
+	err = f.Close()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "at least one")
+}
+
+func TestFormatter_UnknownCodec(t *testing.T) {
+	_, err := (&Format{}).NewFormatter(&bytes.Buffer{}, format.FormatterOptions{Codec: "lzma"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported parquet compression")
+}
+
+func TestFormatter_DocumentDoesNotFitSchema(t *testing.T) {
 	var buf bytes.Buffer
+	f, err := NewFormatter(&buf, format.FormatterOptions{RowGroupSize: 1})
+	assert.NoError(t, err)
+
+	assert.NoError(t, f.Write(map[string]any{"age": float64(30)}))
+
+	err = f.Write(map[string]any{"age": "thirty"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "document 2")
+	assert.Contains(t, err.Error(), `field "age"`)
+
+	err = f.Write(map[string]any{"age": nil})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "required")
 
-	assert.Panics(t, func() {
-		_ = f.NewFormatter(&buf, format.FormatterOptions{})
-	}, "NewFormatter should panic as Parquet write is not supported")
+	err = f.Write(map[string]any{"age": float64(1), "extra": true})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `field "extra" is not in the parquet schema`)
+}
+
+func TestFormatter_ConflictingTypes(t *testing.T) {
+	var buf bytes.Buffer
+	f, err := NewFormatter(&buf, format.FormatterOptions{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, f.Write(map[string]any{"id": "a"}))
+	assert.NoError(t, f.Write(map[string]any{"id": float64(1)}))
+
+	err = f.Close()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "conflicting types")
 }
@@ -1,54 +1,88 @@
 package parquet
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"os"
 
+	"github.com/GeoffMall/flow/internal/format"
 	"github.com/parquet-go/parquet-go"
 )
 
 // Parser implements the format.Parser interface for Apache Parquet files.
 // It streams rows from a Parquet file without buffering the entire file into memory.
 //
-// Note: Parquet requires seekable input (actual files), so io.Reader is not sufficient.
-// The NewParser function will attempt to get the underlying *os.File if possible.
+// Note: Parquet needs random access to its input (the schema is in the footer).
+// Seekable input such as a regular *os.File is read in place; anything else
+// (stdin, pipes, decompressed streams) is spooled first, see newSpool.
+//
+// Only the columns named in ParserOptions.Columns are decoded, and row groups
+// whose min/max statistics rule out one of ParserOptions.Predicates are skipped.
 type Parser struct {
-	file   *parquet.File
-	reader *parquet.Reader
+	file       *parquet.File
+	spool      *spool      // copy of non-seekable input, nil if read in place
+	projection *projection // columns to decode, nil for all of them
+	pushdowns  []pushdown  // predicates checked against row group statistics
 }
 
 // NewParser creates a new Parquet parser that reads from the given reader.
-// The reader must be an *os.File or provide seekable access to the Parquet file.
-// Returns an error if the reader is not seekable (e.g., stdin).
-func NewParser(r io.Reader) (*Parser, error) {
-	// Parquet requires seekable input - try to get underlying file
-	osFile, ok := r.(*os.File)
+// If the reader is not an io.ReaderAt that can seek (e.g. stdin or a pipe), its
+// content is spooled to memory or a temporary file, up to opts.SpoolLimit bytes.
+// Close releases the spooled copy.
+func NewParser(r io.Reader, opts format.ParserOptions) (*Parser, error) {
+	p := &Parser{}
+	ra, size, ok := seekable(r)
 	if !ok {
-		return nil, fmt.Errorf("parquet format requires seekable file input (not stdin or pipe)")
-	}
-
-	// Get file info for size
-	stat, err := osFile.Stat()
-	if err != nil {
-		return nil, fmt.Errorf("failed to stat parquet file: %w", err)
+		s, err := newSpool(r, opts.SpoolLimit)
+		if err != nil {
+			return nil, err
+		}
+		p.spool = s
+		ra, size = s.data, s.size
 	}
 
 	// Open parquet file
-	pf, err := parquet.OpenFile(osFile, stat.Size())
+	pf, err := parquet.OpenFile(ra, size)
 	if err != nil {
+		_ = p.Close()
 		return nil, fmt.Errorf("failed to open parquet file: %w", err)
 	}
 
-	// Create a generic reader
-	reader := parquet.NewReader(pf)
+	p.file = pf
+	p.projection = newProjection(pf.Schema(), opts.Columns)
+	p.pushdowns = newPushdowns(pf.Schema(), opts.Predicates)
+	return p, nil
+}
 
-	return &Parser{
-		file:   pf,
-		reader: reader,
-	}, nil
+// seekable returns r as an io.ReaderAt with its size, if r supports random access.
+func seekable(r io.Reader) (io.ReaderAt, int64, bool) {
+	ra, ok := r.(io.ReaderAt)
+	if !ok {
+		return nil, 0, false
+	}
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		return nil, 0, false
+	}
+	// Fails for an *os.File that is a pipe or terminal
+	size, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, false
+	}
+	return ra, size, true
 }
 
+// Close removes the spooled copy of non-seekable input, if any.
+func (p *Parser) Close() error {
+	if p.spool == nil {
+		return nil
+	}
+	return p.spool.Close()
+}
+
+// rowBatchSize is the number of rows read from a row group at a time.
+const rowBatchSize = 64
+
 // ForEach iterates over all rows in the Parquet file, calling fn for each row.
 // Rows are returned as map[string]any for format-agnostic processing.
 // Iteration stops when:
@@ -56,24 +90,50 @@ func NewParser(r io.Reader) (*Parser, error) {
 // - The callback fn returns an error (returns that error)
 // - The reader encounters an error (returns that error)
 func (p *Parser) ForEach(fn func(doc any) error) error {
-	// Read rows one at a time
+	for _, rg := range p.file.RowGroups() {
+		if !mayMatch(rg, p.pushdowns) {
+			continue
+		}
+		if err := p.readRowGroup(rg, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readRowGroup calls fn for each row of rg, reading only the projected columns.
+func (p *Parser) readRowGroup(rg parquet.RowGroup, fn func(doc any) error) error {
+	schema := p.file.Schema()
+	if p.projection != nil {
+		schema = p.projection.schema
+		rg = p.projection.rowGroup(rg)
+	}
+
+	rows := rg.Rows()
+	defer rows.Close()
+
+	batch := make([]parquet.Row, rowBatchSize)
 	for {
-		// Read next row as a generic map
-		row := make(map[string]any)
-		err := p.reader.Read(&row)
+		n, err := rows.ReadRows(batch)
+		for _, row := range batch[:n] {
+			if p.projection != nil {
+				p.projection.fix(row)
+			}
+
+			// Read the row as a generic map
+			doc := make(map[string]any)
+			if err := schema.Reconstruct(&doc, row); err != nil {
+				return fmt.Errorf("failed to read parquet row: %w", err)
+			}
+			if err := fn(doc); err != nil {
+				return err
+			}
+		}
 		if err != nil {
-			if err == io.EOF {
-				// End of file reached
-				break
+			if errors.Is(err, io.EOF) {
+				return nil
 			}
 			return fmt.Errorf("failed to read parquet row: %w", err)
 		}
-
-		// Call the callback with the row
-		if err := fn(row); err != nil {
-			return err
-		}
 	}
-
-	return nil
 }
@@ -0,0 +1,215 @@
+package parquet
+
+import (
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/parquet-go/parquet-go"
+)
+
+// projection is the part of a file's schema that the pipeline reads. Only the
+// column chunks of its leaf columns are decoded.
+type projection struct {
+	schema  *parquet.Schema
+	columns []int // file column index of each leaf column of schema
+	remap   []int // projected index of each file column (-1 if not read)
+}
+
+// fieldSet is a tree of requested fields. A nil entry means the whole field.
+type fieldSet map[string]fieldSet
+
+func (s fieldSet) add(keys []string) {
+	for i, key := range keys {
+		child, seen := s[key]
+		if seen && child == nil {
+			return // the whole field is already requested
+		}
+		if i == len(keys)-1 {
+			s[key] = nil
+			return
+		}
+		if child == nil {
+			child = fieldSet{}
+			s[key] = child
+		}
+		s = child
+	}
+}
+
+// newProjection returns the projection of schema onto the fields in columns,
+// or nil if every column would be read anyway (or none at all, which would
+// leave no rows to count).
+func newProjection(schema *parquet.Schema, columns [][]string) *projection {
+	if len(columns) == 0 {
+		return nil
+	}
+	fields := fieldSet{}
+	for _, keys := range columns {
+		if len(keys) > 0 {
+			fields.add(keys)
+		}
+	}
+
+	root, ok := projectGroup(schema, fields)
+	if !ok {
+		return nil
+	}
+	projected := parquet.NewSchema(schema.Name(), root)
+	paths := projected.Columns()
+	if len(paths) == len(schema.Columns()) {
+		return nil
+	}
+
+	p := &projection{
+		schema:  projected,
+		columns: make([]int, len(paths)),
+		remap:   make([]int, len(schema.Columns())),
+	}
+	for i := range p.remap {
+		p.remap[i] = -1
+	}
+	for i, path := range paths {
+		leaf, _ := schema.Lookup(path...)
+		p.columns[i] = leaf.ColumnIndex
+		p.remap[leaf.ColumnIndex] = i
+	}
+	return p
+}
+
+// projectGroup keeps the fields of a group node that are in fields. It
+// returns false if none of them exist.
+func projectGroup(node parquet.Node, fields fieldSet) (parquet.Node, bool) {
+	group := parquet.Group{}
+	for _, field := range node.Fields() {
+		sub, ok := fields[field.Name()]
+		if !ok {
+			continue
+		}
+		if sub == nil || !isPlainGroup(field) {
+			group[field.Name()] = field
+			continue
+		}
+		if projected, ok := projectGroup(field, sub); ok {
+			group[field.Name()] = projected
+		}
+	}
+	if len(group) == 0 {
+		return nil, false
+	}
+	if node.Optional() {
+		return parquet.Optional(group), true
+	}
+	return group, true
+}
+
+// isPlainGroup reports whether node is a struct-like group whose fields can be
+// read separately. Lists, maps and repeated groups are read whole.
+func isPlainGroup(node parquet.Node) bool {
+	return !node.Leaf() && !node.Repeated() && node.Type().LogicalType() == nil
+}
+
+// rowGroup returns the projected view of a row group of the file.
+func (p *projection) rowGroup(rg parquet.RowGroup) parquet.RowGroup {
+	chunks := rg.ColumnChunks()
+	columns := make([]parquet.ColumnChunk, len(p.columns))
+	for i, c := range p.columns {
+		columns[i] = chunks[c]
+	}
+	return &projectedRowGroup{RowGroup: rg, schema: p.schema, columns: columns}
+}
+
+// fix renumbers the values of a row read from the file's columns to the
+// projected schema's columns, which Reconstruct goes by.
+func (p *projection) fix(row parquet.Row) {
+	for i, v := range row {
+		row[i] = v.Level(v.RepetitionLevel(), v.DefinitionLevel(), p.remap[v.Column()])
+	}
+}
+
+// projectedRowGroup is a row group with only the projected column chunks.
+type projectedRowGroup struct {
+	parquet.RowGroup
+	schema  *parquet.Schema
+	columns []parquet.ColumnChunk
+}
+
+func (g *projectedRowGroup) ColumnChunks() []parquet.ColumnChunk { return g.columns }
+func (g *projectedRowGroup) Schema() *parquet.Schema             { return g.schema }
+func (g *projectedRowGroup) SortingColumns() []parquet.SortingColumn {
+	return nil
+}
+func (g *projectedRowGroup) Rows() parquet.Rows { return parquet.NewRowGroupRowReader(g) }
+
+// ----------------------------- Statistics -----------------------------
+
+// pushdown is a predicate on a leaf column with usable min/max statistics.
+type pushdown struct {
+	pred   format.Predicate
+	column int
+}
+
+// newPushdowns returns the predicates that row group statistics can decide:
+// those on a leaf column outside any list or map.
+func newPushdowns(schema *parquet.Schema, preds []format.Predicate) []pushdown {
+	var out []pushdown
+	for _, pred := range preds {
+		leaf, ok := schema.Lookup(pred.Path()...)
+		if !ok || !leaf.Node.Leaf() || leaf.MaxRepetitionLevel > 0 {
+			continue
+		}
+		out = append(out, pushdown{pred: pred, column: leaf.ColumnIndex})
+	}
+	return out
+}
+
+// mayMatch reports whether any row of rg could satisfy all the predicates.
+func mayMatch(rg parquet.RowGroup, pushdowns []pushdown) bool {
+	chunks := rg.ColumnChunks()
+	for _, pd := range pushdowns {
+		chunk, ok := chunks[pd.column].(*parquet.FileColumnChunk)
+		if !ok {
+			continue
+		}
+		minValue, maxValue, ok := chunk.Bounds()
+		if !ok {
+			continue
+		}
+		lo, hi, ok := bounds(chunk.Type(), minValue, maxValue)
+		if ok && !pd.pred.MayMatch(lo, hi) {
+			return false
+		}
+	}
+	return true
+}
+
+// bounds converts column statistics to the Go values rows are read as. It
+// returns false for columns whose statistics are ordered differently from
+// those values, or which aren't compared by value.
+func bounds(typ parquet.Type, minValue, maxValue parquet.Value) (lo, hi any, ok bool) {
+	lt := typ.LogicalType()
+	unsigned := lt != nil && lt.Integer != nil && !lt.Integer.IsSigned
+
+	switch typ.Kind() {
+	case parquet.Int32:
+		// Unsigned columns are ordered as unsigned but read as signed values
+		if unsigned {
+			return nil, nil, false
+		}
+		return minValue.Int32(), maxValue.Int32(), true
+	case parquet.Int64:
+		if unsigned {
+			return nil, nil, false
+		}
+		return minValue.Int64(), maxValue.Int64(), true
+	case parquet.Float:
+		// NaN sorts before every number but is left out of statistics, so
+		// the minimum can't rule anything out
+		return nil, maxValue.Float(), true
+	case parquet.Double:
+		return nil, maxValue.Double(), true
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		if lt == nil || lt.UTF8 == nil {
+			return nil, nil, false
+		}
+		return string(minValue.ByteArray()), string(maxValue.ByteArray()), true
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,124 @@
+package parquet
+
+import (
+	"os"
+	"testing"
+
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readWith reads every row of the parquet file at path with opts.
+func readWith(t *testing.T, path string, opts format.ParserOptions) []map[string]any {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	parser, err := NewParser(file, opts)
+	require.NoError(t, err)
+
+	var rows []map[string]any
+	require.NoError(t, parser.ForEach(func(doc any) error {
+		rows = append(rows, doc.(map[string]any))
+		return nil
+	}))
+	return rows
+}
+
+func nestedFile(t *testing.T) string {
+	t.Helper()
+	return writeFile(t, format.FormatterOptions{},
+		map[string]any{
+			"id":     float64(1),
+			"status": "active",
+			"user":   map[string]any{"name": "Alice", "email": "a@example.com", "geo": map[string]any{"lat": 1.5, "lon": 2.5}},
+			"tags":   []any{"x", "y"},
+		},
+		map[string]any{
+			"id":     float64(2),
+			"status": "inactive",
+			"user":   map[string]any{"name": "Bob", "email": "b@example.com", "geo": map[string]any{"lat": 3.5, "lon": 4.5}},
+			"tags":   []any{},
+		},
+	)
+}
+
+func TestParser_Columns(t *testing.T) {
+	path := nestedFile(t)
+
+	rows := readWith(t, path, format.ParserOptions{Columns: [][]string{{"status"}, {"user", "geo", "lat"}, {"tags"}}})
+	assert.Equal(t, []map[string]any{
+		{"status": "active", "user": map[string]any{"geo": map[string]any{"lat": 1.5}}, "tags": []any{"x", "y"}},
+		{"status": "inactive", "user": map[string]any{"geo": map[string]any{"lat": 3.5}}, "tags": []any{}},
+	}, rows)
+
+	// A whole group, and a field of it requested again
+	rows = readWith(t, path, format.ParserOptions{Columns: [][]string{{"user"}, {"user", "name"}}})
+	assert.Equal(t, map[string]any{"user": map[string]any{
+		"name": "Alice", "email": "a@example.com", "geo": map[string]any{"lat": 1.5, "lon": 2.5},
+	}}, rows[0])
+}
+
+func TestParser_Columns_ReadAll(t *testing.T) {
+	path := nestedFile(t)
+	all := readWith(t, path, format.ParserOptions{})
+
+	// Unknown fields alone would leave nothing to read, so every column is read
+	assert.Equal(t, all, readWith(t, path, format.ParserOptions{Columns: [][]string{{"missing"}}}))
+	assert.Nil(t, newProjection(mustSchema(t, path), [][]string{{"id"}, {"status"}, {"user"}, {"tags"}}))
+}
+
+func mustSchema(t *testing.T, path string) *parquet.Schema {
+	t.Helper()
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	parser, err := NewParser(file, format.ParserOptions{})
+	require.NoError(t, err)
+	return parser.file.Schema()
+}
+
+// rangePredicate matches integer fields between lo and hi.
+type rangePredicate struct {
+	path   []string
+	lo, hi int64
+}
+
+func (p rangePredicate) Path() []string { return p.path }
+
+func (p rangePredicate) MayMatch(minValue, maxValue any) bool {
+	lo, ok1 := minValue.(int64)
+	hi, ok2 := maxValue.(int64)
+	return !ok1 || !ok2 || (lo <= p.hi && hi >= p.lo)
+}
+
+func TestParser_PredicatesSkipRowGroups(t *testing.T) {
+	docs := make([]any, 10)
+	for i := range docs {
+		docs[i] = map[string]any{"id": float64(i), "name": "n"}
+	}
+	path := writeFile(t, format.FormatterOptions{RowGroupSize: 3}, docs...)
+
+	ids := func(rows []map[string]any) []int64 {
+		var out []int64
+		for _, row := range rows {
+			out = append(out, row["id"].(int64))
+		}
+		return out
+	}
+
+	// Row groups hold ids 0-2, 3-5, 6-8 and 9; only the second can hold 4 or 5
+	rows := readWith(t, path, format.ParserOptions{Predicates: []format.Predicate{rangePredicate{path: []string{"id"}, lo: 4, hi: 5}}})
+	assert.Equal(t, []int64{3, 4, 5}, ids(rows))
+
+	// Predicates on missing columns, or that the statistics can't decide, skip nothing
+	rows = readWith(t, path, format.ParserOptions{Predicates: []format.Predicate{
+		rangePredicate{path: []string{"missing"}, lo: 100, hi: 100},
+		rangePredicate{path: []string{"name"}, lo: 100, hi: 100},
+	}})
+	assert.Len(t, rows, 10)
+}
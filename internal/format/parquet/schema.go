@@ -0,0 +1,347 @@
+package parquet
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/ordered"
+	"github.com/parquet-go/parquet-go"
+)
+
+// columnKind is the physical shape inferred for a column.
+type columnKind int
+
+const (
+	kindUnknown   columnKind = iota // only nulls (or empty lists) observed so far
+	kindBoolean                     // BOOLEAN
+	kindInt32                       // INT32
+	kindInt64                       // INT64
+	kindDouble                      // DOUBLE
+	kindString                      // BYTE_ARRAY (UTF8)
+	kindBytes                       // BYTE_ARRAY
+	kindTimestamp                   // INT64 (TIMESTAMP_MICROS)
+	kindList                        // LIST group
+	kindGroup                       // nested group
+)
+
+var kindNames = map[columnKind]string{
+	kindUnknown:   "null",
+	kindBoolean:   "boolean",
+	kindInt32:     "int32",
+	kindInt64:     "int64",
+	kindDouble:    "double",
+	kindString:    "string",
+	kindBytes:     "bytes",
+	kindTimestamp: "timestamp",
+	kindList:      "list",
+	kindGroup:     "group",
+}
+
+func (k columnKind) String() string { return kindNames[k] }
+
+// column accumulates the shape of values observed at one position in the
+// buffered documents. It is converted to a parquet node once the first row
+// group is complete, and then used to check and convert every row.
+type column struct {
+	kind     columnKind
+	optional bool
+	element  *column            // element type for kindList
+	fields   map[string]*column // child columns for kindGroup
+	order    []string           // names of fields, in the order first seen
+}
+
+// inferSchema builds a parquet schema that every document in docs fits.
+// Nested maps become groups, []any becomes a LIST, and numbers are typed by
+// inspection: integral values become INT64 (or INT32 for 32-bit Go ints) and
+// anything else DOUBLE. Columns missing or null in some documents are OPTIONAL.
+// Group fields are in the order they are first seen: the key order of an
+// *ordered.Map, or sorted for a map[string]any.
+func inferSchema(docs []any) (*column, *parquet.Schema, error) {
+	root := &column{kind: kindUnknown}
+	for i, doc := range docs {
+		if format.TypeOf(doc) != format.TypeObject {
+			return nil, nil, fmt.Errorf("document %d: parquet output requires objects, got %T", i+1, doc)
+		}
+		if err := root.observe(doc, ""); err != nil {
+			return nil, nil, fmt.Errorf("document %d: %w", i+1, err)
+		}
+	}
+
+	if len(root.fields) == 0 {
+		// Parquet files must have at least one column
+		return nil, nil, errors.New("no fields to write (parquet output needs at least one non-empty document)")
+	}
+
+	return root, parquet.NewSchema("Record", root.node()), nil
+}
+
+// observe merges the shape of v into c. path is used for error messages.
+//
+//nolint:cyclop // One case per value type
+func (c *column) observe(v any, path string) error {
+	switch v.(type) {
+	case int8, int16, int32, uint8, uint16:
+		return c.merge(kindInt32, path)
+	}
+
+	switch format.TypeOf(v) {
+	case format.TypeNull:
+		c.optional = true
+		return nil
+	case format.TypeBoolean:
+		return c.merge(kindBoolean, path)
+	case format.TypeString:
+		return c.merge(kindString, path)
+	case format.TypeBytes:
+		return c.merge(kindBytes, path)
+	case format.TypeTimestamp:
+		return c.merge(kindTimestamp, path)
+	case format.TypeInteger:
+		return c.merge(kindInt64, path)
+	case format.TypeNumber:
+		return c.merge(kindDouble, path)
+	case format.TypeArray:
+		return c.observeList(v.([]any), path)
+	case format.TypeObject:
+		m, ok := v.(*ordered.Map)
+		if !ok {
+			m = sortedGroup(v.(map[string]any))
+		}
+		return c.observeGroup(m, path)
+	default:
+		return fmt.Errorf("field %q: unsupported value of type %T", path, v)
+	}
+}
+
+// observeList merges a list and its elements into c.
+func (c *column) observeList(items []any, path string) error {
+	if err := c.merge(kindList, path); err != nil {
+		return err
+	}
+	if c.element == nil {
+		c.element = &column{kind: kindUnknown}
+	}
+	for i, item := range items {
+		if err := c.element.observe(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observeGroup merges an object into c. Fields that were seen before but are
+// missing from m, or are new in m, become optional.
+func (c *column) observeGroup(m *ordered.Map, path string) error {
+	seenBefore := c.kind == kindGroup
+	if err := c.merge(kindGroup, path); err != nil {
+		return err
+	}
+	if c.fields == nil {
+		c.fields = make(map[string]*column, m.Len())
+	}
+
+	for name, field := range c.fields {
+		if _, ok := m.Get(name); !ok {
+			field.optional = true
+		}
+	}
+
+	for name, val := range m.All() {
+		field, ok := c.fields[name]
+		if !ok {
+			field = &column{kind: kindUnknown, optional: seenBefore}
+			c.fields[name] = field
+			c.order = append(c.order, name)
+		}
+		if err := field.observe(val, format.JoinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedGroup returns m as an *ordered.Map with its keys sorted, so groups
+// without a key order still get a stable field order.
+func sortedGroup(m map[string]any) *ordered.Map {
+	out := ordered.NewMap()
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		out.Set(k, m[k])
+	}
+	return out
+}
+
+// merge records that a value of kind was seen. Integer widths widen to INT64
+// and integers mixed with doubles widen to DOUBLE; any other mix of types is
+// reported as a conflict.
+func (c *column) merge(kind columnKind, path string) error {
+	switch {
+	case c.kind == kindUnknown || c.kind == kind:
+		c.kind = kind
+	case isNumeric(c.kind) && isNumeric(kind):
+		c.kind = max(c.kind, kind) // kinds are ordered int32 < int64 < double
+	default:
+		return fmt.Errorf("field %q has conflicting types %s and %s", path, c.kind, kind)
+	}
+	return nil
+}
+
+func isNumeric(k columnKind) bool {
+	return k == kindInt32 || k == kindInt64 || k == kindDouble
+}
+
+// node converts c into a parquet schema node.
+func (c *column) node() parquet.Node {
+	var n parquet.Node
+	switch c.kind {
+	case kindBoolean:
+		n = parquet.Leaf(parquet.BooleanType)
+	case kindInt32:
+		n = parquet.Int(32)
+	case kindInt64:
+		n = parquet.Int(64)
+	case kindDouble:
+		n = parquet.Leaf(parquet.DoubleType)
+	case kindBytes:
+		n = parquet.Leaf(parquet.ByteArrayType)
+	case kindTimestamp:
+		n = parquet.Timestamp(parquet.Microsecond)
+	case kindList:
+		n = parquet.List(c.element.node())
+	case kindGroup:
+		n = c.group()
+	default:
+		// Strings, and columns where only nulls were observed
+		n = parquet.String()
+	}
+
+	if c.optional || c.kind == kindUnknown {
+		return parquet.Optional(n)
+	}
+	return n
+}
+
+// group converts a kindGroup column into a group node with its fields in
+// c.order. parquet.Group sorts its fields by name, so each field is taken from
+// a single-entry parquet.Group and only the order is kept here.
+func (c *column) group() *orderedGroup {
+	g := &orderedGroup{Group: make(parquet.Group, len(c.order))}
+	for _, name := range c.order {
+		node := c.fields[name].node()
+		g.Group[name] = node
+		g.fields = append(g.fields, parquet.Group{name: node}.Fields()[0])
+	}
+	return g
+}
+
+// orderedGroup is a parquet group node that lists its fields in a fixed order
+// instead of sorted by name. The writer walks map rows through Fields, so that
+// is the only method that needs to differ from parquet.Group.
+type orderedGroup struct {
+	parquet.Group
+	fields []parquet.Field
+}
+
+func (g *orderedGroup) Fields() []parquet.Field { return g.fields }
+
+// ----------------------------- Conformance -----------------------------
+
+// conform converts v into the Go representation the parquet writer expects
+// for c, or returns an error describing why v does not fit the schema.
+//
+//nolint:cyclop,funlen // One case per column kind
+func (c *column) conform(v any, path string) (any, error) {
+	if v == nil {
+		if c.optional || c.kind == kindUnknown {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("field %q is required by the parquet schema but is null or missing", displayPath(path))
+	}
+
+	var (
+		out any
+		ok  bool
+	)
+
+	switch c.kind {
+	case kindUnknown, kindString:
+		out, ok = v.(string)
+	case kindBoolean:
+		out, ok = v.(bool)
+	case kindBytes:
+		out, ok = v.([]byte)
+	case kindTimestamp:
+		out, ok = v.(time.Time)
+	case kindInt32:
+		var n int64
+		if n, ok = format.ToInt64(v); ok && n >= math.MinInt32 && n <= math.MaxInt32 {
+			out = int32(n)
+		} else {
+			ok = false
+		}
+	case kindInt64:
+		out, ok = format.ToInt64(v)
+	case kindDouble:
+		out, ok = format.ToFloat64(v)
+	case kindList:
+		return c.conformList(v, path)
+	case kindGroup:
+		return c.conformGroup(v, path)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("field %q: %T value does not fit parquet column type %s", displayPath(path), v, c.kind)
+	}
+	return out, nil
+}
+
+func (c *column) conformList(v any, path string) (any, error) {
+	arr, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("field %q: %T value does not fit parquet column type list", displayPath(path), v)
+	}
+
+	out := make([]any, len(arr))
+	for i, item := range arr {
+		conv, err := c.element.conform(item, fmt.Sprintf("%s[%d]", path, i))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = conv
+	}
+	return out, nil
+}
+
+func (c *column) conformGroup(v any, path string) (any, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("field %q: %T value does not fit parquet column type group", displayPath(path), v)
+	}
+
+	for k := range m {
+		if _, known := c.fields[k]; !known {
+			return nil, fmt.Errorf("field %q is not in the parquet schema", format.JoinPath(path, k))
+		}
+	}
+
+	out := make(map[string]any, len(c.fields))
+	for name, field := range c.fields {
+		conv, err := field.conform(m[name], format.JoinPath(path, name))
+		if err != nil {
+			return nil, err
+		}
+		out[name] = conv
+	}
+	return out, nil
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
@@ -0,0 +1,91 @@
+package parquet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// memorySpoolSize is how much non-seekable input is kept in memory before
+// spooling moves on to a temporary file.
+var memorySpoolSize int64 = 32 << 20
+
+// spool holds a copy of a non-seekable input so Parquet, which reads the file
+// footer first, can access it at random.
+type spool struct {
+	data io.ReaderAt
+	size int64
+	file *os.File // temporary file, nil if the input fit in memory
+	path string   // temporary file to remove on close, if it couldn't be removed up front
+}
+
+// newSpool copies all of r into memory, or into a temporary file once it
+// outgrows memorySpoolSize. It fails if r holds more than limit bytes
+// (0 = no limit). The caller must close the spool.
+func newSpool(r io.Reader, limit int64) (*spool, error) {
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(r, memorySpoolSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parquet input: %w", err)
+	}
+	if limit > 0 && n > limit {
+		return nil, spoolLimitError(limit)
+	}
+	if n <= memorySpoolSize {
+		return &spool{data: bytes.NewReader(buf.Bytes()), size: n}, nil
+	}
+
+	f, err := os.CreateTemp("", "flow-*.parquet")
+	if err != nil {
+		return nil, fmt.Errorf("failed to spool parquet input: %w", err)
+	}
+	s := &spool{data: f, file: f}
+	// Unlinking the open file means it goes away however flow exits.
+	// Windows can't remove open files, so there it is removed on close.
+	if err := os.Remove(f.Name()); err != nil {
+		s.path = f.Name()
+	}
+
+	src := r
+	if limit > 0 {
+		// One byte over the limit tells a too large input from one that fits exactly
+		src = io.LimitReader(r, limit-n+1)
+	}
+	copied, err := io.Copy(f, io.MultiReader(&buf, src))
+	if err == nil && limit > 0 && copied > limit {
+		err = spoolLimitError(limit)
+	}
+	if err != nil {
+		_ = s.Close()
+		if errors.Is(err, errSpoolLimit) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to spool parquet input: %w", err)
+	}
+	s.size = copied
+	return s, nil
+}
+
+// errSpoolLimit is returned when the input is larger than the spool limit.
+var errSpoolLimit = errors.New("parquet input exceeds the spool limit")
+
+func spoolLimitError(limit int64) error {
+	return fmt.Errorf("%w of %d bytes (raise -parquet-spool-limit, or read from a file)", errSpoolLimit, limit)
+}
+
+// Close releases the spooled copy.
+func (s *spool) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	if s.path != "" {
+		if rerr := os.Remove(s.path); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	s.file = nil
+	return err
+}
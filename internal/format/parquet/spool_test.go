@@ -0,0 +1,97 @@
+package parquet
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stream returns the content of the users.parquet fixture as a non-seekable reader.
+func stream(t *testing.T) (io.Reader, int64) {
+	t.Helper()
+	data, err := os.ReadFile("testdata/users.parquet")
+	require.NoError(t, err)
+	return io.MultiReader(bytes.NewReader(data)), int64(len(data))
+}
+
+func names(t *testing.T, p *Parser) []any {
+	t.Helper()
+	var got []any
+	require.NoError(t, p.ForEach(func(doc any) error {
+		got = append(got, doc.(map[string]any)["name"])
+		return nil
+	}))
+	return got
+}
+
+func TestParser_SpoolsInMemory(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	r, _ := stream(t)
+	p, err := NewParser(r, format.ParserOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, p.spool.file)
+	assert.Equal(t, []any{"Alice", "Bob", "Charlie"}, names(t, p))
+	require.NoError(t, p.Close())
+
+	entries, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestParser_SpoolsToTempFile(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	defer func(size int64) { memorySpoolSize = size }(memorySpoolSize)
+	memorySpoolSize = 16
+
+	r, size := stream(t)
+	p, err := NewParser(r, format.ParserOptions{SpoolLimit: size})
+	require.NoError(t, err)
+	require.NotNil(t, p.spool.file)
+	assert.Equal(t, size, p.spool.size)
+	assert.Equal(t, []any{"Alice", "Bob", "Charlie"}, names(t, p))
+	require.NoError(t, p.Close())
+
+	entries, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the temporary file is removed")
+}
+
+func TestParser_SpoolLimit(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	r, size := stream(t)
+	_, err := NewParser(r, format.ParserOptions{SpoolLimit: size - 1})
+	assert.ErrorIs(t, err, errSpoolLimit)
+
+	// Past the in-memory part as well
+	defer func(size int64) { memorySpoolSize = size }(memorySpoolSize)
+	memorySpoolSize = 16
+	r, _ = stream(t)
+	_, err = NewParser(r, format.ParserOptions{SpoolLimit: size - 1})
+	assert.ErrorIs(t, err, errSpoolLimit)
+
+	entries, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestParser_SeekableNotSpooled(t *testing.T) {
+	f, err := os.Open("testdata/users.parquet")
+	require.NoError(t, err)
+	defer f.Close()
+
+	p, err := NewParser(f, format.ParserOptions{SpoolLimit: 1})
+	require.NoError(t, err)
+	assert.Nil(t, p.spool)
+	assert.Len(t, names(t, p), 3)
+	assert.NoError(t, p.Close())
+}
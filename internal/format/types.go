@@ -0,0 +1,183 @@
+package format
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GeoffMall/flow/internal/ordered"
+)
+
+// Type is the kind of a parsed value. flow schema reports it for each field,
+// and the formatters that infer a schema (Avro, Parquet) map it to their own
+// types, so the report always matches what they would write.
+type Type string
+
+const (
+	TypeNull      Type = "null"
+	TypeBoolean   Type = "boolean"
+	TypeString    Type = "string"
+	TypeBytes     Type = "bytes"
+	TypeInteger   Type = "integer"
+	TypeNumber    Type = "number"
+	TypeTimestamp Type = "timestamp"
+	TypeObject    Type = "object"
+	TypeArray     Type = "array"
+)
+
+// maxExactFloat bounds the whole numbers a float64 holds exactly.
+const maxExactFloat = 1 << 53
+
+// TypeOf returns the Type of v, or "" if v is not a value parsers produce.
+// Whole numbers are TypeInteger whatever their Go type (JSON decodes them as
+// float64), as long as they fit an int64 and, for floats, are exact.
+//
+//nolint:cyclop // One case per Go type parsers produce
+func TypeOf(v any) Type {
+	switch vv := v.(type) {
+	case nil:
+		return TypeNull
+	case bool:
+		return TypeBoolean
+	case string:
+		return TypeString
+	case []byte:
+		return TypeBytes
+	case time.Time:
+		return TypeTimestamp
+	case *ordered.Map, map[string]any:
+		return TypeObject
+	case []any:
+		return TypeArray
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		return TypeInteger
+	case uint:
+		return TypeOf(uint64(vv))
+	case uint64:
+		if vv > math.MaxInt64 {
+			return TypeNumber
+		}
+		return TypeInteger
+	case float32:
+		return floatType(float64(vv))
+	case float64:
+		return floatType(vv)
+	case json.Number:
+		if _, err := vv.Int64(); err == nil {
+			return TypeInteger
+		}
+		return TypeNumber
+	}
+	return ""
+}
+
+func floatType(f float64) Type {
+	if f == math.Trunc(f) && math.Abs(f) < maxExactFloat {
+		return TypeInteger
+	}
+	return TypeNumber
+}
+
+// ParseScalar converts text from a format without types (such as a CSV cell)
+// to the value it spells: nil for "", a bool for true/false (also TRUE, True,
+// ...), an int64 for an integer, a float64 for a finite decimal, and the text
+// itself otherwise. Numbers with a leading zero, such as zip codes and IDs
+// like "007", stay text, so they aren't changed.
+func ParseScalar(s string) any {
+	switch s {
+	case "":
+		return nil
+	case "true", "TRUE", "True":
+		return true
+	case "false", "FALSE", "False":
+		return false
+	}
+
+	if hasLeadingZero(s) {
+		return s
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f
+	}
+	return s
+}
+
+// hasLeadingZero reports whether s, after any sign, starts with a 0 followed
+// by another digit ("0123", "-007", but not "0" or "0.5").
+func hasLeadingZero(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	return len(s) > 1 && s[0] == '0' && s[1] >= '0' && s[1] <= '9'
+}
+
+// ToInt64 converts a parsed number to an int64. Floats convert only when they
+// are whole and in range, so no value is silently changed.
+//
+//nolint:cyclop // One case per Go numeric type
+func ToInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint:
+		return ToInt64(uint64(n))
+	case uint64:
+		return int64(n), n <= math.MaxInt64
+	case float32:
+		return ToInt64(float64(n))
+	case float64:
+		if n != math.Trunc(n) || math.Abs(n) >= 1<<63 {
+			return 0, false
+		}
+		return int64(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// ToFloat64 converts a parsed number of any Go numeric type to a float64.
+func ToFloat64(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case uint:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	i, ok := ToInt64(v)
+	return float64(i), ok
+}
+
+// JoinPath returns the dotted path of key inside the object at path, as
+// schema inference and error messages name fields ("user.address.city").
+func JoinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,91 @@
+package format
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/GeoffMall/flow/internal/ordered"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypeOf(t *testing.T) {
+	tests := []struct {
+		value any
+		want  Type
+	}{
+		{nil, TypeNull},
+		{true, TypeBoolean},
+		{"x", TypeString},
+		{[]byte("x"), TypeBytes},
+		{time.Now(), TypeTimestamp},
+		{ordered.NewMap(), TypeObject},
+		{map[string]any{}, TypeObject},
+		{[]any{}, TypeArray},
+		{int8(1), TypeInteger},
+		{uint64(math.MaxInt64), TypeInteger},
+		{uint64(math.MaxUint64), TypeNumber},
+		{float64(3), TypeInteger},
+		{float32(2.5), TypeNumber},
+		{1e20, TypeNumber}, // too large to be an exact integer
+		{math.Inf(1), TypeNumber},
+		{json.Number("42"), TypeInteger},
+		{json.Number("4.2"), TypeNumber},
+		{struct{}{}, ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, TypeOf(tt.value), "%#v", tt.value)
+	}
+}
+
+func TestParseScalar(t *testing.T) {
+	tests := []struct {
+		text string
+		want any
+	}{
+		{"", nil},
+		{"true", true},
+		{"FALSE", false},
+		{"42", int64(42)},
+		{"-1.5", -1.5},
+		{"Inf", "Inf"},
+		{"NaN", "NaN"},
+		{"yes", "yes"},
+		{"0", int64(0)},
+		{"0.5", 0.5},
+		{"-0.25", -0.25},
+		{"0123", "0123"}, // zip codes and IDs keep their leading zeros
+		{"007", "007"},
+		{"-012", "-012"},
+		{"00.5", "00.5"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ParseScalar(tt.text), tt.text)
+	}
+}
+
+func TestToInt64AndToFloat64(t *testing.T) {
+	i, ok := ToInt64(float64(3))
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), i)
+
+	for _, v := range []any{2.5, 1e19, uint64(math.MaxUint64), json.Number("1.5"), "3"} {
+		_, ok := ToInt64(v)
+		assert.False(t, ok, "%#v", v)
+	}
+
+	f, ok := ToFloat64(uint64(math.MaxUint64))
+	assert.True(t, ok)
+	assert.InDelta(t, 1.8446744073709552e19, f, 1)
+	f, ok = ToFloat64(int32(-2))
+	assert.True(t, ok)
+	assert.Equal(t, -2.0, f)
+	_, ok = ToFloat64(true)
+	assert.False(t, ok)
+}
+
+func TestJoinPath(t *testing.T) {
+	assert.Equal(t, "a", JoinPath("", "a"))
+	assert.Equal(t, "a.b", JoinPath("a", "b"))
+}
@@ -10,7 +10,8 @@ import (
 
 // Formatter implements format.Formatter for YAML output.
 type Formatter struct {
-	enc *yaml.Encoder
+	enc     *yaml.Encoder
+	written bool // whether any document was encoded
 }
 
 // NewFormatter creates a new YAML formatter.
@@ -25,17 +26,40 @@ func NewFormatter(w io.Writer, opts format.FormatterOptions) *Formatter {
 
 // Write outputs a single YAML document.
 // Each call writes a document with trailing newline.
+// A *yaml.Node document (edit mode) is re-emitted with its comments and styles.
 func (f *Formatter) Write(doc any) error {
+	if n, ok := doc.(*yaml.Node); ok {
+		untagMergeKeys(n)
+	}
 	if err := f.enc.Encode(doc); err != nil {
 		return fmt.Errorf("yaml encode: %w", err)
 	}
+	f.written = true
 	return nil
 }
 
+// untagMergeKeys clears the explicit !!merge tag the decoder puts on << keys.
+// The encoder would otherwise write them as "!!merge <<"; untagged, they come
+// out as a plain "<<" that still resolves to a merge key.
+func untagMergeKeys(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i < len(n.Content); i += 2 {
+			if key := n.Content[i]; key.Tag == "!!merge" {
+				key.Tag = ""
+			}
+		}
+	}
+	// Aliases point back into the tree, so they are not followed
+	for _, c := range n.Content {
+		untagMergeKeys(c)
+	}
+}
+
 // Close flushes the encoder and releases resources.
-// Must be called when done writing.
+// Must be called when done writing. It is a no-op if no document was written,
+// since the encoder refuses to close an empty stream.
 func (f *Formatter) Close() error {
-	if f.enc != nil {
+	if f.enc != nil && f.written {
 		return f.enc.Close()
 	}
 	return nil
@@ -1,19 +1,18 @@
 package yaml
 
 import (
-	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
-	"strings"
 
+	"github.com/GeoffMall/flow/internal/ordered"
 	"gopkg.in/yaml.v3"
 )
 
 // Parser implements format.Parser for YAML format.
 // It streams YAML documents separated by --- markers.
 type Parser struct {
-	dec *yaml.Decoder
+	dec       *yaml.Decoder
+	keepNodes bool
 }
 
 // NewParser creates a new YAML streaming parser.
@@ -23,12 +22,24 @@ func NewParser(r io.Reader) *Parser {
 	}
 }
 
+// NewNodeParser creates a YAML parser that passes each document to fn as the
+// *yaml.Node it was decoded to, with comments, styles and anchors intact,
+// instead of normalizing it. Operations edit such documents in place and the
+// YAML formatter writes them back out.
+func NewNodeParser(r io.Reader) *Parser {
+	p := NewParser(r)
+	p.keepNodes = true
+	return p
+}
+
 // ForEach streams YAML documents and calls fn for each.
 // Documents are separated by --- markers in YAML.
-// All values are normalized to JSON-compatible Go types.
+// All values are normalized to JSON-compatible Go types, with mappings
+// decoded as *ordered.Map so key order survives to the output
+// (unless the parser was created with NewNodeParser).
 func (p *Parser) ForEach(fn func(any) error) error {
 	for {
-		var node any
+		var node yaml.Node
 		if err := p.dec.Decode(&node); err != nil {
 			if errors.Is(err, io.EOF) {
 				return nil
@@ -36,65 +47,20 @@ func (p *Parser) ForEach(fn func(any) error) error {
 			return err
 		}
 
-		// Normalize YAML types to JSON-compatible types
-		normalized := normalizeYAML(node)
-
-		if err := fn(normalized); err != nil {
-			return err
-		}
-	}
-}
-
-// normalizeYAML converts yaml.v3 decoded values into JSON-compatible Go types:
-//   - map[any]any  -> map[string]any (recursively)
-//   - []any        -> []any (recursively)
-//   - scalar nodes -> left as-is
-//
-// This ensures operations work consistently across JSON and YAML input.
-func normalizeYAML(v any) any {
-	switch vv := v.(type) {
-	case map[any]any:
-		out := make(map[string]any, len(vv))
-		for k, val := range vv {
-			out[toStringKey(k)] = normalizeYAML(val)
+		if p.keepNodes {
+			if err := fn(&node); err != nil {
+				return err
+			}
+			continue
 		}
-		return out
 
-	case map[string]any:
-		out := make(map[string]any, len(vv))
-		for k, val := range vv {
-			out[k] = normalizeYAML(val)
+		normalized, err := ordered.DecodeYAML(&node)
+		if err != nil {
+			return err
 		}
-		return out
 
-	case []any:
-		for i := range vv {
-			vv[i] = normalizeYAML(vv[i])
+		if err := fn(normalized); err != nil {
+			return err
 		}
-		return vv
-
-	default:
-		return v
-	}
-}
-
-// toStringKey converts any key type to a string for map keys.
-// This handles YAML's more flexible key types (numbers, booleans, etc.).
-func toStringKey(k any) string {
-	switch t := k.(type) {
-	case string:
-		return t
-	case int, int8, int16, int32, int64,
-		uint, uint8, uint16, uint32, uint64,
-		float32, float64,
-		bool:
-		return fmt.Sprint(t)
-	case json.Number:
-		return t.String()
-	case []byte:
-		return string(t)
-	default:
-		// Fallback: use fmt.Sprint
-		return strings.TrimSpace(fmt.Sprint(t))
 	}
 }
@@ -2,6 +2,7 @@
 // It provides parsing and formatting of YAML data with:
 //   - Streaming document processing (--- separated documents)
 //   - Normalization to JSON-compatible types
+//   - Comment- and style-preserving edits when both ends are YAML
 //   - Pretty-printed output with 2-space indentation
 package yaml
 
@@ -20,13 +21,17 @@ func (f *Format) Name() string {
 }
 
 // NewParser creates a new YAML streaming parser.
-func (f *Format) NewParser(r io.Reader) (format.Parser, error) {
+// With opts.YAMLNodes set, documents are passed through as *yaml.Node.
+func (f *Format) NewParser(r io.Reader, opts format.ParserOptions) (format.Parser, error) {
+	if opts.YAMLNodes {
+		return NewNodeParser(r), nil
+	}
 	return NewParser(r), nil
 }
 
 // NewFormatter creates a new YAML formatter.
-func (f *Format) NewFormatter(w io.Writer, opts format.FormatterOptions) format.Formatter {
-	return NewFormatter(w, opts)
+func (f *Format) NewFormatter(w io.Writer, opts format.FormatterOptions) (format.Formatter, error) {
+	return NewFormatter(w, opts), nil
 }
 
 // Register the YAML format on package initialization
@@ -2,11 +2,14 @@ package yaml
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/ordered"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 func TestParser_SingleDocument(t *testing.T) {
@@ -23,7 +26,7 @@ age: 30`
 	assert.NoError(t, err)
 	assert.Len(t, docs, 1)
 
-	obj, ok := docs[0].(map[string]any)
+	obj, ok := ordered.ToPlain(docs[0]).(map[string]any)
 	assert.True(t, ok)
 	assert.Equal(t, "Alice", obj["name"])
 	assert.Equal(t, 30, obj["age"])
@@ -49,7 +52,7 @@ name: Charlie`
 
 	names := []string{"Alice", "Bob", "Charlie"}
 	for i, doc := range docs {
-		obj, ok := doc.(map[string]any)
+		obj, ok := ordered.ToPlain(doc).(map[string]any)
 		assert.True(t, ok)
 		assert.Equal(t, names[i], obj["name"])
 	}
@@ -72,7 +75,7 @@ func TestParser_ArrayInYAML(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, docs, 1)
 
-	obj, ok := docs[0].(map[string]any)
+	obj, ok := ordered.ToPlain(docs[0]).(map[string]any)
 	assert.True(t, ok)
 
 	items, ok := obj["items"].([]any)
@@ -95,7 +98,7 @@ true: boolean key`
 	assert.NoError(t, err)
 	assert.Len(t, docs, 1)
 
-	obj, ok := docs[0].(map[string]any)
+	obj, ok := ordered.ToPlain(docs[0]).(map[string]any)
 	assert.True(t, ok)
 	assert.Equal(t, "numeric key", obj["123"])
 	assert.Equal(t, "boolean key", obj["true"])
@@ -123,6 +126,14 @@ func TestFormatter(t *testing.T) {
 	assert.Contains(t, output, "active: true")
 }
 
+func TestFormatter_CloseWithoutDocuments(t *testing.T) {
+	buf := &bytes.Buffer{}
+	formatter := NewFormatter(buf, format.FormatterOptions{})
+
+	assert.NoError(t, formatter.Close())
+	assert.Empty(t, buf.String())
+}
+
 func TestFormat_Integration(t *testing.T) {
 	fmt := &Format{}
 
@@ -131,7 +142,7 @@ func TestFormat_Integration(t *testing.T) {
 
 	// Test parser
 	input := strings.NewReader("name: Alice\nage: 30")
-	parser, err := fmt.NewParser(input)
+	parser, err := fmt.NewParser(input, format.ParserOptions{})
 	assert.NoError(t, err)
 
 	var docs []any
@@ -144,7 +155,8 @@ func TestFormat_Integration(t *testing.T) {
 
 	// Test formatter
 	buf := &bytes.Buffer{}
-	formatter := fmt.NewFormatter(buf, format.FormatterOptions{})
+	formatter, err := fmt.NewFormatter(buf, format.FormatterOptions{})
+	assert.NoError(t, err)
 	err = formatter.Write(docs[0])
 	assert.NoError(t, err)
 	err = formatter.Close()
@@ -153,3 +165,112 @@ func TestFormat_Integration(t *testing.T) {
 	assert.Contains(t, buf.String(), "name:")
 	assert.Contains(t, buf.String(), "Alice")
 }
+
+func TestParser_PreservesKeyOrder(t *testing.T) {
+	input := `kind: Deployment
+apiVersion: apps/v1
+metadata:
+  name: web
+  labels:
+    zeta: "1"
+    alpha: "2"
+`
+	parser := NewParser(strings.NewReader(input))
+
+	var docs []any
+	err := parser.ForEach(func(doc any) error {
+		docs = append(docs, doc)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, docs, 1)
+
+	root, ok := docs[0].(*ordered.Map)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"kind", "apiVersion", "metadata"}, root.Keys())
+
+	metadata, _ := root.Get("metadata")
+	labels, _ := metadata.(*ordered.Map).Get("labels")
+	assert.Equal(t, []string{"zeta", "alpha"}, labels.(*ordered.Map).Keys())
+}
+
+func TestParser_AnchorsAndMergeKeys(t *testing.T) {
+	input := `defaults: &defaults
+  timeout: 30
+  retries: 3
+service:
+  <<: *defaults
+  name: api
+  retries: 5
+ports: &ports [80, 443]
+mirror: *ports
+`
+	parser := NewParser(strings.NewReader(input))
+
+	var docs []any
+	err := parser.ForEach(func(doc any) error {
+		docs = append(docs, doc)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	obj := ordered.ToPlain(docs[0]).(map[string]any)
+	assert.Equal(t, map[string]any{"timeout": 30, "retries": 5, "name": "api"}, obj["service"])
+	assert.Equal(t, []any{80, 443}, obj["mirror"])
+}
+
+func TestFormatter_KeyOrderRoundTrip(t *testing.T) {
+	input := "b: 1\na:\n  z: true\n  c: [1, 2]\n"
+	parser := NewParser(strings.NewReader(input))
+
+	buf := &bytes.Buffer{}
+	formatter := NewFormatter(buf, format.FormatterOptions{})
+	err := parser.ForEach(formatter.Write)
+	assert.NoError(t, err)
+	assert.NoError(t, formatter.Close())
+
+	assert.Equal(t, "b: 1\na:\n  z: true\n  c:\n    - 1\n    - 2\n", buf.String())
+}
+
+func TestNodeParser_RoundTripPreservesCommentsAndStyle(t *testing.T) {
+	input := `# Service settings
+base: &base
+  timeout: 30 # seconds
+service:
+  <<: *base
+  name: "api"
+  ports: [80, 443]
+`
+	parser, err := (&Format{}).NewParser(strings.NewReader(input), format.ParserOptions{YAMLNodes: true})
+	assert.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	formatter := NewFormatter(buf, format.FormatterOptions{})
+	err = parser.ForEach(func(doc any) error {
+		assert.IsType(t, &yaml.Node{}, doc)
+		return formatter.Write(doc)
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, formatter.Close())
+
+	assert.Equal(t, input, buf.String())
+}
+
+// aliasBomb returns a document whose aliases expand exponentially: each of
+// levels anchors is a list of ten aliases to the one before.
+func aliasBomb(levels int) string {
+	var sb strings.Builder
+	sb.WriteString("a0: &a0 [lol, lol, lol, lol, lol, lol, lol, lol, lol, lol]\n")
+	for i := 1; i <= levels; i++ {
+		prev := fmt.Sprintf("*a%d", i-1)
+		_, _ = fmt.Fprintf(&sb, "a%d: &a%d [%s]\n", i, i, strings.Repeat(prev+", ", 9)+prev)
+	}
+	return sb.String()
+}
+
+func TestParser_RejectsExcessiveAliasing(t *testing.T) {
+	parser := NewParser(strings.NewReader(aliasBomb(8)))
+
+	err := parser.ForEach(func(any) error { return nil })
+	assert.EqualError(t, err, "yaml: document contains excessive aliasing")
+}
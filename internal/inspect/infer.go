@@ -0,0 +1,196 @@
+// Package inspect describes the shape of a stream of documents for formats
+// that don't carry a schema of their own (JSON, YAML, CSV), for flow schema.
+package inspect
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/ordered"
+)
+
+// maxExamples is the number of distinct example values kept for each field.
+const maxExamples = 3
+
+// typeOrder is the order observed types, and examples of them, are listed in.
+var typeOrder = []format.Type{
+	format.TypeObject, format.TypeArray, format.TypeString, format.TypeBytes, format.TypeInteger,
+	format.TypeNumber, format.TypeBoolean, format.TypeTimestamp, format.TypeNull,
+}
+
+// Inferrer builds a schema from documents one at a time, recording for every
+// field path the types of the values found there and how often it is missing.
+// Paths use the -pick syntax, with [*] for the elements of arrays.
+type Inferrer struct {
+	documents int
+	root      *field
+	fields    map[string]*field
+	order     []string // field paths in the order they were first seen
+}
+
+// field is what has been observed at one path.
+type field struct {
+	types    map[format.Type]int // values of each type
+	count    int                 // values seen, of any type
+	objects  int                 // values that were objects, whose fields are counted against it
+	parent   *field              // enclosing object's field, nil at the top level
+	examples []example
+}
+
+// example is a value seen at a field, with its type.
+type example struct {
+	value any
+	typ   format.Type
+}
+
+// NewInferrer returns an Inferrer that has seen no documents.
+func NewInferrer() *Inferrer {
+	return &Inferrer{root: newField(nil), fields: map[string]*field{}}
+}
+
+func newField(parent *field) *field {
+	return &field{types: map[format.Type]int{}, parent: parent}
+}
+
+// Add records the fields of doc.
+func (in *Inferrer) Add(doc any) {
+	in.documents++
+	in.observe(in.root, "", doc)
+}
+
+func (in *Inferrer) observe(f *field, path string, v any) {
+	typ := typeOf(v)
+	f.types[typ]++
+	f.count++
+
+	switch vv := v.(type) {
+	case *ordered.Map:
+		f.objects++
+		for k, val := range vv.All() {
+			in.observe(in.child(f, format.JoinPath(path, k)), format.JoinPath(path, k), val)
+		}
+	case map[string]any:
+		f.objects++
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			in.observe(in.child(f, format.JoinPath(path, k)), format.JoinPath(path, k), vv[k])
+		}
+	case []any:
+		for _, val := range vv {
+			in.observe(in.child(nil, path+"[*]"), path+"[*]", val)
+		}
+	default:
+		f.addExample(v, typ)
+	}
+}
+
+// child returns the field at path, creating it with the given enclosing object.
+func (in *Inferrer) child(parent *field, path string) *field {
+	f, ok := in.fields[path]
+	if !ok {
+		f = newField(parent)
+		in.fields[path] = f
+		in.order = append(in.order, path)
+	}
+	return f
+}
+
+func (f *field) addExample(v any, typ format.Type) {
+	if v == nil || len(f.examples) == maxExamples {
+		return
+	}
+	if t, ok := v.(time.Time); ok {
+		v = t.Format(time.RFC3339Nano)
+	}
+	for _, e := range f.examples {
+		if fmt.Sprint(e.value) == fmt.Sprint(v) {
+			return
+		}
+	}
+	f.examples = append(f.examples, example{value: v, typ: typ})
+}
+
+// sortedExamples lists the example values in the order of their types in the
+// field's types, keeping the order they were seen within a type.
+func (f *field) sortedExamples() []any {
+	examples := slices.Clone(f.examples)
+	slices.SortStableFunc(examples, func(a, b example) int { return compareTypes(a.typ, b.typ) })
+
+	out := make([]any, len(examples))
+	for i, e := range examples {
+		out[i] = e.value
+	}
+	return out
+}
+
+// optional reports whether the field is missing from some of the objects
+// that could hold it, or null in some of them.
+func (f *field) optional() bool {
+	if f.types[format.TypeNull] > 0 {
+		return true
+	}
+	return f.parent != nil && f.count < f.parent.objects
+}
+
+// typeOf returns the type of v, as the formatters that infer a schema see it,
+// or its Go type for anything else.
+func typeOf(v any) format.Type {
+	if t := format.TypeOf(v); t != "" {
+		return t
+	}
+	return format.Type(fmt.Sprintf("%T", v))
+}
+
+// Report returns the inferred schema as a document: the number of documents,
+// the types of the documents themselves, and each field path with its types,
+// the number of values seen, whether it is optional, and a few examples.
+func (in *Inferrer) Report() *ordered.Map {
+	report := ordered.NewMap()
+	report.Set("documents", in.documents)
+	report.Set("types", sortedTypes(in.root.types))
+
+	fields := make([]any, 0, len(in.order))
+	for _, path := range in.order {
+		f := in.fields[path]
+		entry := ordered.NewMap()
+		entry.Set("path", path)
+		entry.Set("types", sortedTypes(f.types))
+		entry.Set("count", f.count)
+		entry.Set("optional", f.optional())
+		if len(f.examples) > 0 {
+			entry.Set("examples", f.sortedExamples())
+		}
+		fields = append(fields, entry)
+	}
+	report.Set("fields", fields)
+	return report
+}
+
+// sortedTypes lists the names in types in typeOrder, followed by any others
+// in alphabetical order.
+func sortedTypes(types map[format.Type]int) []any {
+	out := []any{}
+	for _, name := range slices.SortedFunc(maps.Keys(types), compareTypes) {
+		out = append(out, string(name))
+	}
+	return out
+}
+
+// compareTypes orders types as they are listed in a report.
+func compareTypes(a, b format.Type) int {
+	rank := func(t format.Type) int {
+		if i := slices.Index(typeOrder, t); i >= 0 {
+			return i
+		}
+		return len(typeOrder)
+	}
+	return cmp.Or(cmp.Compare(rank(a), rank(b)), cmp.Compare(a, b))
+}
@@ -0,0 +1,109 @@
+package inspect
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/GeoffMall/flow/internal/ordered"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fieldsByPath returns the field entries of a report keyed by path.
+func fieldsByPath(t *testing.T, report *ordered.Map) map[string]map[string]any {
+	t.Helper()
+	fields, ok := report.Get("fields")
+	require.True(t, ok)
+
+	out := map[string]map[string]any{}
+	for _, f := range fields.([]any) {
+		entry := ordered.ToPlain(f).(map[string]any)
+		out[entry["path"].(string)] = entry
+	}
+	return out
+}
+
+func TestInferrer(t *testing.T) {
+	doc1, err := ordered.DecodeJSON([]byte(`{"id": 1, "user": {"name": "Alice"}, "tags": ["a", "b"], "items": [{"sku": "x", "qty": 2}]}`))
+	require.NoError(t, err)
+
+	in := NewInferrer()
+	in.Add(doc1)
+	in.Add(map[string]any{"id": json.Number("2.5"), "user": nil, "items": []any{map[string]any{"sku": "y"}}})
+	in.Add(map[string]any{"id": 3, "user": map[string]any{"name": "Bob", "joined": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}})
+
+	report := in.Report()
+	documents, _ := report.Get("documents")
+	assert.Equal(t, 3, documents)
+	types, _ := report.Get("types")
+	assert.Equal(t, []any{"object"}, types)
+
+	fields := fieldsByPath(t, report)
+
+	assert.Equal(t, map[string]any{
+		"path": "id", "types": []any{"integer", "number"}, "count": 3, "optional": false,
+		"examples": []any{float64(1), 3, json.Number("2.5")},
+	}, fields["id"])
+	assert.Equal(t, []any{"object", "null"}, fields["user"]["types"])
+	assert.Equal(t, true, fields["user"]["optional"])
+	assert.Equal(t, false, fields["user.name"]["optional"], "present in every user object")
+	assert.Equal(t, true, fields["user.joined"]["optional"])
+	assert.Equal(t, []any{"timestamp"}, fields["user.joined"]["types"])
+	assert.Equal(t, []any{"2024-01-02T03:04:05Z"}, fields["user.joined"]["examples"])
+	assert.Equal(t, true, fields["tags"]["optional"])
+	assert.Equal(t, 2, fields["tags[*]"]["count"])
+	assert.Equal(t, false, fields["items[*].sku"]["optional"])
+	assert.Equal(t, true, fields["items[*].qty"]["optional"])
+}
+
+func TestInferrer_FieldOrderAndExamples(t *testing.T) {
+	in := NewInferrer()
+	for _, v := range []string{"a", "b", "a", "c", "d"} {
+		doc := ordered.NewMap()
+		doc.Set("z", v)
+		doc.Set("a", nil)
+		in.Add(doc)
+	}
+	in.Add([]any{1})
+
+	report := in.Report()
+	fields, _ := report.Get("fields")
+	var paths []any
+	for _, f := range fields.([]any) {
+		path, _ := f.(*ordered.Map).Get("path")
+		paths = append(paths, path)
+	}
+	assert.Equal(t, []any{"z", "a", "[*]"}, paths, "fields are listed in the order they were first seen")
+
+	byPath := fieldsByPath(t, report)
+	assert.Equal(t, []any{"a", "b", "c"}, byPath["z"]["examples"], "examples are distinct and limited")
+	assert.NotContains(t, byPath["a"], "examples")
+	assert.Equal(t, true, byPath["a"]["optional"])
+	assert.Equal(t, false, byPath["z"]["optional"], "documents that aren't objects don't make fields optional")
+
+	types, _ := report.Get("types")
+	assert.Equal(t, []any{"object", "array"}, types)
+}
+
+func TestInferrer_TypesMatchWriters(t *testing.T) {
+	in := NewInferrer()
+	in.Add(map[string]any{"big": 1e20, "whole": float64(3), "raw": []byte("x"), "id": uint64(7)})
+
+	fields := fieldsByPath(t, in.Report())
+	assert.Equal(t, []any{"number"}, fields["big"]["types"], "written as a double, not an integer")
+	assert.Equal(t, []any{"integer"}, fields["whole"]["types"])
+	assert.Equal(t, []any{"bytes"}, fields["raw"]["types"])
+	assert.Equal(t, []any{"integer"}, fields["id"]["types"])
+}
+
+func TestInferrer_ExamplesFollowTypeOrder(t *testing.T) {
+	in := NewInferrer()
+	for _, v := range []any{true, 1.5, "x"} {
+		in.Add(map[string]any{"v": v})
+	}
+
+	fields := fieldsByPath(t, in.Report())
+	assert.Equal(t, []any{"string", "number", "boolean"}, fields["v"]["types"])
+	assert.Equal(t, []any{"x", 1.5, true}, fields["v"]["examples"], "examples are listed in the order of their types")
+}
@@ -0,0 +1,164 @@
+package operation
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GeoffMall/flow/internal/ordered"
+)
+
+// number is a numeric value normalised for comparison. Integers are kept
+// exactly as int64 so large IDs compare correctly; anything else is a float64.
+type number struct {
+	i     int64
+	f     float64
+	isInt bool
+}
+
+func (n number) float() float64 {
+	if n.isInt {
+		return float64(n.i)
+	}
+	return n.f
+}
+
+// compareNumbers returns -1, 0 or +1 like cmp.Compare.
+func compareNumbers(a, b number) int {
+	if a.isInt && b.isInt {
+		return cmp.Compare(a.i, b.i)
+	}
+	return cmp.Compare(a.float(), b.float())
+}
+
+// parseNumber parses s as an integer or a finite float.
+func parseNumber(s string) (number, bool) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return number{i: i, isInt: true}, true
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return number{}, false
+	}
+	return number{f: f}, true
+}
+
+// toNumber converts a decoded document value to a number.
+// Strings are not numbers, even if they look like one.
+//
+//nolint:cyclop // One case per Go numeric type
+func toNumber(v any) (number, bool) {
+	switch n := v.(type) {
+	case int:
+		return number{i: int64(n), isInt: true}, true
+	case int8:
+		return number{i: int64(n), isInt: true}, true
+	case int16:
+		return number{i: int64(n), isInt: true}, true
+	case int32:
+		return number{i: int64(n), isInt: true}, true
+	case int64:
+		return number{i: n, isInt: true}, true
+	case uint:
+		return toNumber(uint64(n))
+	case uint8:
+		return number{i: int64(n), isInt: true}, true
+	case uint16:
+		return number{i: int64(n), isInt: true}, true
+	case uint32:
+		return number{i: int64(n), isInt: true}, true
+	case uint64:
+		if n > math.MaxInt64 {
+			return number{f: float64(n)}, true
+		}
+		return number{i: int64(n), isInt: true}, true
+	case float32:
+		return number{f: float64(n)}, true
+	case float64:
+		return number{f: n}, true
+	case json.Number:
+		return parseNumber(string(n))
+	}
+	return number{}, false
+}
+
+// parseTimestamp parses an RFC 3339 timestamp (fractional seconds optional).
+func parseTimestamp(s string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	return t, err == nil
+}
+
+// operand is the right-hand side of a comparison, pre-parsed once so each
+// document only pays for converting its own field value.
+type operand struct {
+	raw    string
+	num    number
+	isNum  bool
+	time   time.Time
+	isTime bool
+}
+
+func newOperand(s string) operand {
+	o := operand{raw: s}
+	o.num, o.isNum = parseNumber(s)
+	o.time, o.isTime = parseTimestamp(s)
+	return o
+}
+
+// compare orders a document value against the operand.
+//
+// Numbers compare numerically and timestamps (time.Time values) compare
+// chronologically. A string is read as the operand's type when the operand is
+// a number or an RFC 3339 timestamp, and compared lexicographically otherwise.
+// ok is false when the two sides cannot be ordered, e.g. a number against a
+// non-numeric operand, a string that doesn't parse as a numeric or timestamp
+// operand, or a bool.
+func (o operand) compare(v any) (result int, ok bool) {
+	if n, isNum := toNumber(v); isNum {
+		if !o.isNum {
+			return 0, false
+		}
+		return compareNumbers(n, o.num), true
+	}
+
+	switch val := v.(type) {
+	case time.Time:
+		if !o.isTime {
+			return 0, false
+		}
+		return val.Compare(o.time), true
+	case string:
+		switch {
+		case o.isNum:
+			n, isNum := parseNumber(val)
+			return compareNumbers(n, o.num), isNum
+		case o.isTime:
+			t, isTime := parseTimestamp(val)
+			return t.Compare(o.time), isTime
+		}
+		return strings.Compare(val, o.raw), true
+	}
+	return 0, false
+}
+
+// scalarText returns the text that match operators test: strings as they are,
+// and numbers, booleans and timestamps in their default string form. Null,
+// objects and arrays have no text and never match.
+func scalarText(v any) (string, bool) {
+	switch val := v.(type) {
+	case nil, map[string]any, *ordered.Map, []any:
+		return "", false
+	case string:
+		return val, true
+	case json.Number:
+		return string(val), true
+	case time.Time:
+		return val.Format(time.RFC3339Nano), true
+	default:
+		return fmt.Sprintf("%v", val), true
+	}
+}
@@ -3,6 +3,8 @@ package operation
 import (
 	"fmt"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Delete holds a list of paths to remove from the input document.
@@ -23,8 +25,13 @@ func (d *Delete) Description() string {
 
 // Apply deletes each requested path from the input document in order.
 // If the root value is not an object/array where a path begins, the
-// corresponding delete is ignored (no-op). Returns the mutated value.
+// corresponding delete is ignored (no-op). Deleting the root path . leaves a
+// null document. Returns the mutated value.
 func (d *Delete) Apply(v any) (any, error) {
+	if doc, ok := v.(*yaml.Node); ok {
+		return doc, d.applyToNode(doc)
+	}
+
 	// We mutate in place if the root is a map[string]any or []any.
 	// If the root is scalar and paths target subfields, this becomes a no-op.
 	for _, raw := range d.Paths {
@@ -34,125 +41,82 @@ func (d *Delete) Apply(v any) (any, error) {
 			return nil, fmt.Errorf("invalid --delete %q: %w", raw, err)
 		}
 
-		// If no expansion occurred, use original path
-		if len(expandedPaths) == 0 {
-			expandedPaths = []string{raw}
-		}
-
-		// Process each expanded path
-		for _, expandedPath := range expandedPaths {
-			segs, err := parsePath(expandedPath)
-			if err != nil {
-				return nil, fmt.Errorf("invalid expanded path %q: %w", expandedPath, err)
-			}
-
-			deleteAtPath(&v, segs)
+		// Delete from the end so earlier array indexes stay valid.
+		// Paths that don't exist expand to nothing.
+		for i := len(expandedPaths) - 1; i >= 0; i-- {
+			deleteAtPath(&v, expandedPaths[i])
 		}
 	}
 
 	return v, nil
 }
 
-// deleteAtPath walks 'v' by segs and deletes the targeted node if present.
-// It mutates 'v' in place when it's a map or slice. If path doesn't exist, no-op.
-func deleteAtPath(v *any, segs []segment) {
-	if len(segs) == 0 || v == nil {
-		return
-	}
-
-	parent, err := navigateToParent(v, segs[:len(segs)-1])
-	if err != nil {
-		return
-	}
-
-	deleteFromParent(parent, segs[len(segs)-1])
-}
-
-// navigateToParent walks through all segments except the last one
-func navigateToParent(v *any, segs []segment) (*any, error) {
-	cur := v
-
-	for _, s := range segs {
-		next, err := stepIntoMap(cur, s.key)
+// applyToNode deletes each requested path from a YAML document in place (see yamlnode.go).
+func (d *Delete) applyToNode(doc *yaml.Node) error {
+	for _, raw := range d.Paths {
+		expandedPaths, err := concretePaths(doc, raw, expandWildcardPaths)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		if s.idx != nil {
-			next, err = stepIntoSlice(next, *s.idx)
-			if err != nil {
-				return nil, err
-			}
+		// Delete from the end so earlier array indexes stay valid
+		for i := len(expandedPaths) - 1; i >= 0; i-- {
+			deleteNodeAtPath(doc, expandedPaths[i])
 		}
-
-		// Navigate to the child without modifying the current value
-		cur = &next
 	}
-
-	return cur, nil
+	return nil
 }
 
-// stepIntoMap steps into a map by key
-func stepIntoMap(cur *any, key string) (any, error) {
-	m, ok := (*cur).(map[string]any)
-	if !ok {
-		return nil, fmt.Errorf("not a map")
-	}
-
-	child, exists := m[key]
-	if !exists {
-		return nil, fmt.Errorf("key not found")
+// deleteAtPath walks 'v' by segs and deletes the targeted node if present.
+// It mutates 'v' in place when it's a map or slice, and sets it to nil for an
+// empty path. If path doesn't exist, no-op.
+func deleteAtPath(v *any, segs []segment) {
+	if v == nil {
+		return
 	}
-
-	return child, nil
+	*v = deleteFrom(*v, segs)
 }
 
-// stepIntoSlice steps into a slice by index
-func stepIntoSlice(val any, idx int) (any, error) {
-	arr, ok := val.([]any)
-	if !ok {
-		return nil, fmt.Errorf("not a slice")
+// deleteFrom deletes the value at segs below cur and returns cur, or the
+// slice that replaces it when an element of cur itself is removed.
+func deleteFrom(cur any, segs []segment) any {
+	if len(segs) == 0 {
+		return nil
 	}
 
-	if idx < 0 || idx >= len(arr) {
-		return nil, fmt.Errorf("index out of bounds")
+	s := segs[0]
+	if !s.hasKey() {
+		return deleteIndex(cur, s, segs[1:])
 	}
 
-	return arr[idx], nil
-}
-
-// deleteFromParent deletes the final segment from its parent container
-func deleteFromParent(parent *any, last segment) {
-	m, ok := (*parent).(map[string]any)
-	if !ok {
-		return
+	child, exists := objectGet(cur, s.key)
+	if !exists {
+		return cur
 	}
-
-	if last.idx == nil {
-		delete(m, last.key)
-		return
+	if s.idx == nil && len(segs) == 1 {
+		objectDelete(cur, s.key)
+		return cur
 	}
-
-	deleteFromSliceInMap(m, last.key, *last.idx)
+	objectSet(cur, s.key, deleteIndex(child, s, segs[1:]))
+	return cur
 }
 
-// deleteFromSliceInMap deletes an element from a slice stored in a map
-func deleteFromSliceInMap(m map[string]any, key string, idx int) {
-	child, exists := m[key]
-	if !exists {
-		return
+// deleteIndex applies the index of s, if it has one, to cur and deletes the
+// rest of the path below it; with no rest, the indexed element is removed.
+func deleteIndex(cur any, s segment, rest []segment) any {
+	if s.idx == nil {
+		return deleteFrom(cur, rest)
 	}
 
-	arr, ok := child.([]any)
-	if !ok {
-		return
+	arr, ok := cur.([]any)
+	if !ok || *s.idx < 0 || *s.idx >= len(arr) {
+		return cur
 	}
 
-	if idx < 0 || idx >= len(arr) {
-		return
+	if len(rest) == 0 {
+		// Remove arr[idx] by shifting left
+		return append(arr[:*s.idx], arr[*s.idx+1:]...)
 	}
-
-	// Remove arr[idx] by shifting left
-	arr = append(arr[:idx], arr[idx+1:]...)
-	m[key] = arr
+	arr[*s.idx] = deleteFrom(arr[*s.idx], rest)
+	return arr
 }
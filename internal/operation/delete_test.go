@@ -252,6 +252,30 @@ func TestDelete_NilInput(t *testing.T) {
 	assert.Nil(t, result)
 }
 
+func TestDelete_RootPaths(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		input any
+		want  any
+	}{
+		{"element of an array root", []string{"[0]"}, []any{"a", "b", "c"}, []any{"b", "c"}},
+		{"from the end", []string{"[-1]", "[0].tmp"}, []any{map[string]any{"id": 1, "tmp": true}, "x"}, []any{map[string]any{"id": 1}}},
+		{"every element", []string{"[*].tmp"}, []any{map[string]any{"tmp": 1}, map[string]any{"tmp": 2, "id": 2}}, []any{map[string]any{}, map[string]any{"id": 2}}},
+		{"nested arrays", []string{"grid[0][1]", "[0]"}, map[string]any{"grid": []any{[]any{1, 2, 3}}}, map[string]any{"grid": []any{[]any{1, 3}}}},
+		{"whole document", []string{"."}, map[string]any{"a": 1}, nil},
+		{"missing index", []string{"[5]", "[0]"}, map[string]any{"a": 1}, map[string]any{"a": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewDelete(tt.paths).Apply(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
 func TestDelete_EmptyPaths(t *testing.T) {
 	del := NewDelete([]string{})
 	input := map[string]any{"name": "alice", "age": 30}
@@ -406,3 +430,93 @@ func TestDelete_PreservesOtherFields(t *testing.T) {
 	}
 	assert.Equal(t, expected, result)
 }
+
+func TestDelete_QuotedKeys(t *testing.T) {
+	del := NewDelete([]string{`metadata.labels["app.kubernetes.io/name"]`, `items[*]["x.y"]`})
+	input := map[string]any{
+		"metadata": map[string]any{
+			"labels": map[string]any{"app.kubernetes.io/name": "web", "tier": "frontend"},
+		},
+		"items": []any{
+			map[string]any{"x.y": 1, "id": 1},
+			map[string]any{"x.y": 2, "id": 2},
+		},
+	}
+
+	result, err := del.Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"metadata": map[string]any{"labels": map[string]any{"tier": "frontend"}},
+		"items":    []any{map[string]any{"id": 1}, map[string]any{"id": 2}},
+	}, result)
+}
+
+func TestDelete_NegativeIndexAndSlices(t *testing.T) {
+	tests := []struct {
+		path string
+		want []any
+	}{
+		{"items[-1]", []any{0, 1, 2, 3, 4}},
+		{"items[1:3]", []any{0, 3, 4, 5}},
+		{"items[::2]", []any{1, 3, 5}},
+		{"items[-2:]", []any{0, 1, 2, 3}},
+		{"items[*]", []any{}},
+		{"items[10:]", []any{0, 1, 2, 3, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			input := map[string]any{"items": []any{0, 1, 2, 3, 4, 5}}
+			result, err := NewDelete([]string{tt.path}).Apply(input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result.(map[string]any)["items"])
+		})
+	}
+}
+
+func TestDelete_MapWildcardAndRecursiveDescent(t *testing.T) {
+	input := map[string]any{
+		"id":     1,
+		"labels": map[string]any{"a": 1, "b": 2},
+		"items": []any{
+			map[string]any{"id": 2, "tags": []any{map[string]any{"id": 3}}},
+			map[string]any{"name": "x"},
+		},
+	}
+
+	result, err := NewDelete([]string{"..id", "labels.*"}).Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"labels": map[string]any{},
+		"items": []any{
+			map[string]any{"tags": []any{map[string]any{}}},
+			map[string]any{"name": "x"},
+		},
+	}, result)
+
+	// Nested matches are deleted before the values that contain them
+	input = map[string]any{"a": map[string]any{"x": map[string]any{"x": 1}}, "b": []any{map[string]any{"x": 2}}}
+	result, err = NewDelete([]string{"**.x"}).Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": map[string]any{}, "b": []any{map[string]any{}}}, result)
+}
+
+func TestDelete_Filters(t *testing.T) {
+	input := map[string]any{
+		"containers": []any{
+			map[string]any{"name": "app", "image": "app:1"},
+			map[string]any{"name": "sidecar", "image": "proxy:1"},
+			map[string]any{"name": "sidecar", "image": "proxy:2"},
+			map[string]any{"name": "logger", "image": "fluent:1", "debug": true},
+		},
+	}
+
+	result, err := NewDelete([]string{"containers[?name=sidecar]", "containers[?image%=fluent:*].debug"}).Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"containers": []any{
+			map[string]any{"name": "app", "image": "app:1"},
+			map[string]any{"name": "logger", "image": "fluent:1"},
+		},
+	}, result)
+}
@@ -0,0 +1,216 @@
+package operation
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ----------------------------- Field usage -----------------------------
+
+// Parsers of columnar formats can skip data a pipeline never looks at. These
+// helpers tell them which fields it reads and which conditions every document
+// it keeps must satisfy.
+
+// Fields returns the paths of the input fields the pipeline reads, as keys
+// from the document root. A path stops at the first array index or wildcard
+// key, so "items[0].id" needs all of items and "user.*" all of user.
+//
+// all is true when the whole document is needed. That is the case unless a
+// Pick chooses what comes out: operations after it (such as Set and Delete)
+// work on the picked values, not on the input, so they add nothing.
+//
+//nolint:cyclop // One case per operation
+func (p *Pipeline) Fields() (paths [][]string, all bool) {
+	for _, op := range p.Ops {
+		switch o := op.(type) {
+		case *Where:
+			for _, cond := range o.conditions {
+				paths = append(paths, fieldKeys(cond.path))
+			}
+		case *Filter:
+			for _, path := range exprPaths(o.expr) {
+				paths = append(paths, fieldKeys(path))
+			}
+		case *Pick:
+			if len(o.Paths) == 0 {
+				return nil, true
+			}
+			for _, s := range o.Paths {
+				path, err := parsePath(s)
+				if err != nil {
+					return nil, true
+				}
+				paths = append(paths, fieldKeys(path))
+			}
+			// A path that starts with a wildcard (*.id, **.id) can be anywhere,
+			// and one that starts at the root (., [0].id) needs all of it
+			if slices.ContainsFunc(paths, func(keys []string) bool { return len(keys) == 0 }) {
+				return nil, true
+			}
+			return paths, false
+		default:
+			// Set, Delete and unknown operations pass the rest of the document through
+			return nil, true
+		}
+	}
+	return nil, true
+}
+
+// fieldKeys returns the keys of path up to and including the first indexed
+// segment, and up to but not including the first wildcard key or index
+// without a key.
+func fieldKeys(path []segment) []string {
+	keys := make([]string, 0, len(path))
+	for _, seg := range path {
+		if seg.anyKeys() || !seg.hasKey() {
+			break
+		}
+		keys = append(keys, seg.key)
+		if seg.indexed() {
+			break
+		}
+	}
+	return keys
+}
+
+// exprPaths returns every path a filter expression looks at.
+func exprPaths(e filterExpr) [][]segment {
+	switch x := e.(type) {
+	case andExpr:
+		return append(exprPaths(x.left), exprPaths(x.right)...)
+	case orExpr:
+		return append(exprPaths(x.left), exprPaths(x.right)...)
+	case notExpr:
+		return exprPaths(x.expr)
+	case compareExpr:
+		return [][]segment{x.cond.path}
+	case nullExpr:
+		return [][]segment{x.path}
+	case inExpr:
+		return [][]segment{x.path}
+	case existsExpr:
+		return [][]segment{x.path}
+	case matchesExpr:
+		return [][]segment{x.path}
+	}
+	return nil
+}
+
+// Predicate is a comparison of one field with a constant, such as
+// status=active or age>=30, that every document a pipeline keeps satisfies.
+type Predicate struct {
+	path []string
+	cond whereCondition
+}
+
+// Path returns the keys of the compared field from the document root.
+func (p Predicate) Path() []string { return p.path }
+
+// MayMatch reports whether a field value between min and max, inclusive,
+// could satisfy the predicate. A nil bound is unknown. Values that can't be
+// ordered against the constant are assumed to match.
+//
+//nolint:cyclop // One case per operator
+func (p Predicate) MayMatch(minValue, maxValue any) bool {
+	// Strings are read as numbers or timestamps when the constant is one,
+	// which a lexicographic range says nothing about
+	if p.cond.value.isNum || p.cond.value.isTime {
+		if _, ok := minValue.(string); ok {
+			return true
+		}
+		if _, ok := maxValue.(string); ok {
+			return true
+		}
+	}
+
+	// below reports whether bound orders before the constant (or equal to it, with orEqual)
+	below := func(bound any, orEqual bool) bool {
+		if bound == nil {
+			return true
+		}
+		result, ok := p.cond.value.compare(bound)
+		return !ok || result < 0 || (orEqual && result == 0)
+	}
+	above := func(bound any, orEqual bool) bool {
+		if bound == nil {
+			return true
+		}
+		result, ok := p.cond.value.compare(bound)
+		return !ok || result > 0 || (orEqual && result == 0)
+	}
+
+	switch p.cond.op {
+	case opEqual:
+		// A null field only equals the text of nil, and nulls are outside any range
+		if p.cond.value.raw == fmt.Sprint(nil) {
+			return true
+		}
+		return below(minValue, true) && above(maxValue, true)
+	case opGreater:
+		return above(maxValue, false)
+	case opGreaterEqual:
+		return above(maxValue, true)
+	case opLess:
+		return below(minValue, false)
+	case opLessEqual:
+		return below(minValue, true)
+	}
+	return true
+}
+
+// Predicates returns the comparisons every document the pipeline keeps
+// satisfies: equality and range conditions of -where, and of a -filter that
+// is a plain chain of "and"s, which run before anything changes the document.
+// Conditions on array elements are left out.
+func (p *Pipeline) Predicates() []Predicate {
+	var preds []Predicate
+	for _, op := range p.Ops {
+		var conds []whereCondition
+		switch o := op.(type) {
+		case *Where:
+			conds = o.conditions
+		case *Filter:
+			conds = conjunctions(o.expr)
+		default:
+			return preds
+		}
+
+		for _, cond := range conds {
+			if pred, ok := newPredicate(cond); ok {
+				preds = append(preds, pred)
+			}
+		}
+	}
+	return preds
+}
+
+// newPredicate turns a condition into a predicate, if it is one that ranges can rule out.
+func newPredicate(cond whereCondition) (Predicate, bool) {
+	switch cond.op {
+	case opEqual, opGreater, opGreaterEqual, opLess, opLessEqual:
+	default:
+		return Predicate{}, false
+	}
+	if len(cond.path) == 0 {
+		return Predicate{}, false
+	}
+	keys := make([]string, 0, len(cond.path))
+	for _, seg := range cond.path {
+		if seg.indexed() || seg.anyKeys() {
+			return Predicate{}, false
+		}
+		keys = append(keys, seg.key)
+	}
+	return Predicate{path: keys, cond: cond}, true
+}
+
+// conjunctions returns the comparisons joined by "and" at the top of a filter expression.
+func conjunctions(e filterExpr) []whereCondition {
+	switch x := e.(type) {
+	case andExpr:
+		return append(conjunctions(x.left), conjunctions(x.right)...)
+	case compareExpr:
+		return []whereCondition{x.cond}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package operation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline_Fields(t *testing.T) {
+	where, err := NewWhere([]string{"status=active", "user.age>=30"})
+	require.NoError(t, err)
+	filter, err := NewFilter("region in [eu] or not exists(meta.deleted)")
+	require.NoError(t, err)
+	set, err := NewSetFromPairs([]string{"extra=1"})
+	require.NoError(t, err)
+	pick := NewPick([]string{"user.id", "items[0].sku"}, false)
+
+	paths, all := NewPipeline(where, filter, pick, set, NewDelete([]string{"x"})).Fields()
+	assert.False(t, all)
+	assert.Equal(t, [][]string{
+		{"status"}, {"user", "age"},
+		{"region"}, {"meta", "deleted"},
+		{"user", "id"}, {"items"},
+	}, paths)
+
+	// Without a Pick the whole document is output
+	_, all = NewPipeline(where).Fields()
+	assert.True(t, all)
+	_, all = NewPipeline(set, pick).Fields()
+	assert.True(t, all)
+	_, all = NewPipeline().Fields()
+	assert.True(t, all)
+
+	// Paths from the root need the whole document
+	_, all = NewPipeline(NewPick([]string{"id", "[0].id"}, false)).Fields()
+	assert.True(t, all)
+	paths, all = NewPipeline(NewPick([]string{"grid[0][1]"}, false)).Fields()
+	assert.False(t, all)
+	assert.Equal(t, [][]string{{"grid"}}, paths)
+}
+
+func TestPipeline_Predicates(t *testing.T) {
+	where, err := NewWhere([]string{"status=active", "age>=30", "name~=^A", "items[0].id=1", "x!=1", ".=1"})
+	require.NoError(t, err)
+	filter, err := NewFilter("score < 5 and (a=1 or b=2)")
+	require.NoError(t, err)
+	pick := NewPick([]string{"id"}, false)
+	later, err := NewWhere([]string{"id=7"})
+	require.NoError(t, err)
+
+	preds := NewPipeline(where, filter, pick, later).Predicates()
+	var paths [][]string
+	for _, p := range preds {
+		paths = append(paths, p.Path())
+	}
+	assert.Equal(t, [][]string{{"status"}, {"age"}, {"score"}}, paths)
+}
+
+func TestPredicate_MayMatch(t *testing.T) {
+	pred := func(cond string) Predicate {
+		t.Helper()
+		w, err := NewWhere([]string{cond})
+		require.NoError(t, err)
+		p, ok := newPredicate(w.conditions[0])
+		require.True(t, ok)
+		return p
+	}
+
+	tests := []struct {
+		cond     string
+		min, max any
+		want     bool
+	}{
+		{"n=5", int64(1), int64(10), true},
+		{"n=5", int64(6), int64(10), false},
+		{"n=5", int64(1), int64(4), false},
+		{"n=5", int32(5), int32(5), true},
+		{"n>10", int64(1), int64(10), false},
+		{"n>=10", int64(1), int64(10), true},
+		{"n<1", int64(1), int64(10), false},
+		{"n<=1", int64(1), int64(10), true},
+		{"n<1", nil, 10.0, true},
+		{"n>10", nil, 9.5, false},
+		{"name=bob", "alice", "carol", true},
+		{"name=dave", "alice", "carol", false},
+		{"name=x", int64(1), int64(2), true},
+		{"n=5", "a", "b", true},
+		{"n=5", "10", "20", true},
+		{"ts>2024-01-01T00:00:00Z", "2020-01-01T00:00:00Z", "2021-01-01T00:00:00Z", true},
+		{"n=<nil>", int64(1), int64(2), true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, pred(tt.cond).MayMatch(tt.min, tt.max), "%s in [%v, %v]", tt.cond, tt.min, tt.max)
+	}
+}
+
+func TestPipeline_Fields_Wildcards(t *testing.T) {
+	paths, all := NewPipeline(NewPick([]string{"user.*", "meta.**.id"}, false)).Fields()
+	assert.False(t, all)
+	assert.Equal(t, [][]string{{"user"}, {"meta"}}, paths)
+
+	// Fields that can be anywhere need the whole document
+	_, all = NewPipeline(NewPick([]string{"user.id", "**.id"}, false)).Fields()
+	assert.True(t, all)
+	where, err := NewWhere([]string{"*.status=active"})
+	require.NoError(t, err)
+	_, all = NewPipeline(where, NewPick([]string{"user.id"}, false)).Fields()
+	assert.True(t, all)
+	assert.Empty(t, NewPipeline(where).Predicates())
+}
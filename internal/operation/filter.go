@@ -0,0 +1,122 @@
+package operation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Filter keeps documents for which a boolean expression is true, and returns
+// Filtered for the rest. Unlike Where, conditions can be combined with or,
+// negated and grouped:
+//
+//	status = ERROR or (latency > 500 and not exists(retry))
+//	region in [eu, us] && matches(host, "^api-")
+type Filter struct {
+	source string
+	expr   filterExpr
+}
+
+// NewFilter parses a filter expression. The grammar is described in filter_parser.go.
+func NewFilter(expr string) (*Filter, error) {
+	parsed, err := parseFilter(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+	}
+	return &Filter{source: strings.TrimSpace(expr), expr: parsed}, nil
+}
+
+// Apply returns v if the expression is true for it, and Filtered otherwise.
+func (f *Filter) Apply(v any) (any, error) {
+	doc, err := documentValue(v)
+	if err != nil {
+		return nil, err
+	}
+	if f.expr.eval(doc) {
+		return v, nil
+	}
+	return Filtered, nil
+}
+
+// Description returns a human-readable description of this operation.
+func (f *Filter) Description() string {
+	return "filter: " + f.source
+}
+
+// ----------------------------- Expression tree -----------------------------
+
+// filterExpr is a node in a parsed filter expression.
+type filterExpr interface {
+	eval(doc any) bool
+}
+
+type (
+	andExpr struct{ left, right filterExpr }
+	orExpr  struct{ left, right filterExpr }
+	notExpr struct{ expr filterExpr }
+
+	// compareExpr is a single comparison, evaluated exactly like a -where condition.
+	compareExpr struct{ cond whereCondition }
+
+	// nullExpr tests a field for null (or, negated, for a non-null value).
+	nullExpr struct {
+		path   []segment
+		negate bool
+	}
+
+	// inExpr is true when a field equals any of the listed values.
+	inExpr struct {
+		path   []segment
+		values []operand
+	}
+
+	// existsExpr is true when a path resolves, even to null.
+	existsExpr struct{ path []segment }
+
+	// matchesExpr is true when a scalar field matches a regular expression.
+	matchesExpr struct {
+		path []segment
+		re   *regexp.Regexp
+	}
+)
+
+func (e andExpr) eval(doc any) bool { return e.left.eval(doc) && e.right.eval(doc) }
+func (e orExpr) eval(doc any) bool  { return e.left.eval(doc) || e.right.eval(doc) }
+func (e notExpr) eval(doc any) bool { return !e.expr.eval(doc) }
+
+func (e compareExpr) eval(doc any) bool {
+	return e.cond.holds(doc)
+}
+
+func (e nullExpr) eval(doc any) bool {
+	for _, fieldValue := range pathValues(doc, e.path) {
+		if (fieldValue == nil) != e.negate {
+			return true
+		}
+	}
+	return false
+}
+
+func (e inExpr) eval(doc any) bool {
+	for _, fieldValue := range pathValues(doc, e.path) {
+		for _, value := range e.values {
+			if (whereCondition{op: opEqual, value: value}).matches(fieldValue) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e existsExpr) eval(doc any) bool {
+	return len(pathValues(doc, e.path)) > 0
+}
+
+func (e matchesExpr) eval(doc any) bool {
+	for _, fieldValue := range pathValues(doc, e.path) {
+		if text, ok := scalarText(fieldValue); ok && e.re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,413 @@
+package operation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Filter expression grammar (keywords are case-insensitive):
+//
+//	expr       = and { ("or" | "||") and }
+//	and        = unary { ("and" | "&&") unary }
+//	unary      = ("not" | "!") unary | primary
+//	primary    = "(" expr ")" | exists | matches | comparison | membership
+//	exists     = "exists" "(" path ")"
+//	matches    = "matches" "(" path "," value ")"
+//	comparison = path operator value      (any -where operator, or "==")
+//	membership = path "in" "[" value { "," value } "]"
+//	value      = quoted string | bare word (number, timestamp, true, false, null, ...)
+//
+// Paths use the same syntax as -pick and -where (user.name, items[0].id).
+
+// ----------------------------- Lexer -----------------------------
+
+type tokenKind int
+
+const (
+	tokEOF      tokenKind = iota
+	tokWord               // path, keyword or bare value
+	tokString             // quoted string (text holds the unquoted value)
+	tokOperator           // comparison operator
+	tokAnd                // &&
+	tokOr                 // ||
+	tokNot                // !
+	tokLParen             // (
+	tokRParen             // )
+	tokLBracket           // [
+	tokRBracket           // ]
+	tokComma              // ,
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // byte offset in the expression, for error messages
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return fmt.Sprintf("%q", t.text)
+	default:
+		return fmt.Sprintf("'%s'", t.text)
+	}
+}
+
+// wordBreaks are the characters that end a bare word.
+const wordBreaks = "()[],\"'=!<>&| \t\r\n"
+
+// tokenize splits a filter expression into tokens, ending with tokEOF.
+//
+//nolint:cyclop,funlen // One case per token shape
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == '[' && listFollows(tokens):
+			tokens = append(tokens, token{tokLBracket, "[", i})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{tokRBracket, "]", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+		case c == '"' || c == '\'':
+			text, end, err := readQuoted(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokString, text, i})
+			i = end
+		case strings.HasPrefix(src[i:], "&&"):
+			tokens = append(tokens, token{tokAnd, "&&", i})
+			i += 2
+		case strings.HasPrefix(src[i:], "||"):
+			tokens = append(tokens, token{tokOr, "||", i})
+			i += 2
+		case strings.HasPrefix(src[i:], "=="):
+			tokens = append(tokens, token{tokOperator, "=", i})
+			i += 2
+		case c == '!' && !strings.HasPrefix(src[i:], "!="):
+			tokens = append(tokens, token{tokNot, "!", i})
+			i++
+		case isOperatorAt(src, i):
+			op, _ := operatorAt(src[i:])
+			tokens = append(tokens, token{tokOperator, string(op), i})
+			i += len(op)
+		case c == '&' || c == '|':
+			return nil, fmt.Errorf("unexpected '%c' at position %d (use && or ||)", c, i+1)
+		default:
+			end := readWord(src, i)
+			tokens = append(tokens, token{tokWord, src[i:end], i})
+			i = end
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(src)}), nil
+}
+
+// listFollows reports whether a '[' after tokens opens the list of an in
+// condition. Anywhere else it starts a path, as in [0].id.
+func listFollows(tokens []token) bool {
+	n := len(tokens)
+	return n > 0 && tokens[n-1].kind == tokWord && strings.EqualFold(tokens[n-1].text, "in")
+}
+
+// readWord returns the end of the bare word starting at start. Bracketed
+// indexes and quoted keys inside a path (items[0], labels["app.kubernetes.io/name"])
+// and backslash escapes are part of the word.
+func readWord(src string, start int) int {
+	i := start
+	for i < len(src) {
+		c := src[i]
+		if c == '[' && !strings.EqualFold(src[start:i], "in") {
+			end := skipPathQuoting(src, i)
+			if end == i {
+				return len(src)
+			}
+			i = end
+			continue
+		}
+		if c == '\\' {
+			i = skipPathQuoting(src, i)
+			continue
+		}
+		if strings.IndexByte(wordBreaks, c) >= 0 || (isOperatorAt(src, i) && !wildcardKeyAt(src, i)) {
+			break
+		}
+		i++
+	}
+	return i
+}
+
+func isOperatorAt(src string, i int) bool {
+	_, ok := operatorAt(src[i:])
+	return ok
+}
+
+// readQuoted reads a quoted string starting at src[start]. A backslash escapes
+// the quote character or another backslash; any other backslash is kept as
+// is, so regular expressions such as "\d+" need no doubling.
+func readQuoted(src string, start int) (text string, end int, err error) {
+	quote := src[start]
+	var b strings.Builder
+	for i := start + 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\\' && i+1 < len(src) && (src[i+1] == quote || src[i+1] == '\\'):
+			b.WriteByte(src[i+1])
+			i++
+		case c == quote:
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string starting at position %d", start+1)
+}
+
+// ----------------------------- Parser -----------------------------
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+// parseFilter parses a complete filter expression.
+func parseFilter(src string) (filterExpr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, errors.New("expression is empty")
+	}
+
+	p := &filterParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.unexpected(tok, "and, or or end of expression")
+	}
+	return expr, nil
+}
+
+func (p *filterParser) peek() token { return p.tokens[p.pos] }
+
+func (p *filterParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// isKeyword reports whether tok is the bare word kw (case-insensitive).
+func isKeyword(tok token, kw string) bool {
+	return tok.kind == tokWord && strings.EqualFold(tok.text, kw)
+}
+
+func (p *filterParser) expect(kind tokenKind, what string) (token, error) {
+	tok := p.next()
+	if tok.kind != kind {
+		return tok, p.unexpected(tok, what)
+	}
+	return tok, nil
+}
+
+func (p *filterParser) unexpected(tok token, want string) error {
+	return fmt.Errorf("expected %s at position %d, found %s", want, tok.pos+1, tok)
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for tok := p.peek(); tok.kind == tokOr || isKeyword(tok, "or"); tok = p.peek() {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for tok := p.peek(); tok.kind == tokAnd || isKeyword(tok, "and"); tok = p.peek() {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterExpr, error) {
+	if tok := p.peek(); tok.kind == tokNot || isKeyword(tok, "not") {
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterExpr, error) {
+	tok := p.peek()
+	isCall := p.tokens[min(p.pos+1, len(p.tokens)-1)].kind == tokLParen
+
+	switch {
+	case tok.kind == tokLParen:
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	case isCall && isKeyword(tok, "exists"):
+		return p.parseExists()
+	case isCall && isKeyword(tok, "matches"):
+		return p.parseMatches()
+	case tok.kind == tokWord:
+		return p.parseComparison()
+	default:
+		return nil, p.unexpected(tok, "a condition")
+	}
+}
+
+func (p *filterParser) parseExists() (filterExpr, error) {
+	p.next() // exists
+	p.next() // (
+	path, err := p.parsePathToken()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokRParen, "')'"); err != nil {
+		return nil, err
+	}
+	return existsExpr{path}, nil
+}
+
+func (p *filterParser) parseMatches() (filterExpr, error) {
+	p.next() // matches
+	p.next() // (
+	path, err := p.parsePathToken()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokComma, "','"); err != nil {
+		return nil, err
+	}
+	pattern := p.next()
+	if pattern.kind != tokString && pattern.kind != tokWord {
+		return nil, p.unexpected(pattern, "a regular expression")
+	}
+	re, err := regexp.Compile(pattern.text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression at position %d: %w", pattern.pos+1, err)
+	}
+	if _, err := p.expect(tokRParen, "')'"); err != nil {
+		return nil, err
+	}
+	return matchesExpr{path, re}, nil
+}
+
+func (p *filterParser) parseComparison() (filterExpr, error) {
+	pathTok := p.peek()
+	path, err := p.parsePathToken()
+	if err != nil {
+		return nil, err
+	}
+
+	opTok := p.next()
+	if isKeyword(opTok, "in") {
+		return p.parseIn(path)
+	}
+	if opTok.kind != tokOperator {
+		return nil, p.unexpected(opTok, fmt.Sprintf("a comparison operator or 'in' after '%s'", pathTok.text))
+	}
+	op := whereOperator(opTok.text)
+
+	valueTok := p.next()
+	switch {
+	case isKeyword(valueTok, "null"):
+		if op != opEqual && op != opNotEqual {
+			return nil, fmt.Errorf("null can only be compared with = or != (position %d)", valueTok.pos+1)
+		}
+		return nullExpr{path: path, negate: op == opNotEqual}, nil
+	case valueTok.kind == tokWord || valueTok.kind == tokString:
+		cond, err := newWhereCondition(path, op, valueTok.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value at position %d: %w", valueTok.pos+1, err)
+		}
+		return compareExpr{cond}, nil
+	default:
+		return nil, p.unexpected(valueTok, "a value")
+	}
+}
+
+func (p *filterParser) parseIn(path []segment) (filterExpr, error) {
+	if _, err := p.expect(tokLBracket, "'['"); err != nil {
+		return nil, err
+	}
+
+	var values []operand
+	for {
+		tok := p.next()
+		if tok.kind != tokWord && tok.kind != tokString {
+			return nil, p.unexpected(tok, "a value")
+		}
+		values = append(values, newOperand(tok.text))
+
+		tok = p.next()
+		if tok.kind == tokRBracket {
+			return inExpr{path, values}, nil
+		}
+		if tok.kind != tokComma {
+			return nil, p.unexpected(tok, "',' or ']'")
+		}
+	}
+}
+
+// parsePathToken consumes a bare word and parses it with the shared path parser.
+func (p *filterParser) parsePathToken() ([]segment, error) {
+	tok, err := p.expect(tokWord, "a field path")
+	if err != nil {
+		return nil, err
+	}
+	path, err := parsePath(tok.text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path at position %d: %w", tok.pos+1, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,201 @@
+package operation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_Expressions(t *testing.T) {
+	doc := map[string]any{
+		"status":  "ERROR",
+		"latency": float64(120),
+		"region":  "eu",
+		"host":    "api-3.internal",
+		"retry":   nil,
+		"user":    map[string]any{"name": "Alice Smith", "tags": []any{"admin", "ops"}},
+	}
+
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{`status=ERROR`, true},
+		{`status == "ERROR"`, true},
+		{`status=ERROR or latency>500`, true},
+		{`status=WARN || latency>500`, false},
+		{`status=WARN or latency>100`, true},
+		{`status=ERROR and latency>500`, false},
+		{`status=ERROR && latency<=120`, true},
+		{`not status=ERROR`, false},
+		{`!(status=WARN)`, true},
+		{`NOT (status=WARN OR region=us)`, true},
+		{`status=WARN or region=eu and latency>100`, true}, // and binds tighter than or
+		{`(status=WARN or region=eu) and latency>500`, false},
+		{`region in [us, eu]`, true},
+		{`region in ["us", 'ap']`, false},
+		{`latency in [100, 120.0]`, true},
+		{`exists(retry)`, true},
+		{`exists(user.email)`, false},
+		{`not exists(user.email)`, true},
+		{`retry = null`, true},
+		{`status != null`, true},
+		{`user.name = "Alice Smith"`, true},
+		{`user.tags[1] = ops`, true},
+		{`matches(host, "^api-\d+\.")`, true},
+		{`matches(user.name, '(?i)^alice')`, true},
+		{`matches(latency, "^12")`, true},
+		{`matches(user, ".*")`, false}, // objects never match
+		{`missing != x`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			filter, err := NewFilter(tt.expr)
+			assert.NoError(t, err)
+
+			result, err := filter.Apply(doc)
+			assert.NoError(t, err)
+			if tt.match {
+				assert.Equal(t, doc, result)
+			} else {
+				assert.Equal(t, Filtered, result)
+			}
+		})
+	}
+}
+
+func TestFilter_ParseErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{``, "expression is empty"},
+		{`status`, "expected a comparison operator or 'in' after 'status' at position 7, found end of expression"},
+		{`status=`, "expected a value at position 8"},
+		{`(status=ERROR`, "expected ')' at position 14"},
+		{`status=ERROR)`, "expected and, or or end of expression at position 13, found ')'"},
+		{`status=ERROR and`, "expected a condition at position 17"},
+		{`a=1 & b=2`, "unexpected '&' at position 5"},
+		{`name="Alice`, "unterminated string starting at position 6"},
+		{`region in [eu us]`, "expected ',' or ']' at position 15, found 'us'"},
+		{`matches(host, "[")`, "invalid regular expression at position 15"},
+		{`latency > null`, "null can only be compared with = or !="},
+		{`items[x]=1`, "invalid path at position 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := NewFilter(tt.expr)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestFilter_Description(t *testing.T) {
+	filter, err := NewFilter("  status=ERROR or latency>500 ")
+	assert.NoError(t, err)
+	assert.Equal(t, "filter: status=ERROR or latency>500", filter.Description())
+}
+
+func TestFilter_MatchOperators(t *testing.T) {
+	doc := map[string]any{"message": "connection timeout", "host": "api-3"}
+
+	for _, expr := range []string{
+		`message *i= TIMEOUT`,
+		`message~="time.*"`,
+		`host %= "api-*" and not message*=refused`,
+	} {
+		filter, err := NewFilter(expr)
+		assert.NoError(t, err, expr)
+		result, err := filter.Apply(doc)
+		assert.NoError(t, err)
+		assert.Equal(t, doc, result, expr)
+	}
+
+	_, err := NewFilter(`message ~= "("`)
+	assert.ErrorContains(t, err, "invalid value at position 12: invalid pattern for operator '~='")
+}
+
+func TestFilter_QuotedKeys(t *testing.T) {
+	doc := map[string]any{
+		"labels": map[string]any{"app.kubernetes.io/name": "web", "tier": "frontend"},
+	}
+
+	for _, expr := range []string{
+		`labels["app.kubernetes.io/name"]=web`,
+		`labels['app.kubernetes.io/name'] == "web" and labels.tier=frontend`,
+		`exists(labels["app.kubernetes.io/name"])`,
+		`labels.app\.kubernetes\.io/name in [web, api]`,
+	} {
+		filter, err := NewFilter(expr)
+		assert.NoError(t, err, expr)
+		result, err := filter.Apply(doc)
+		assert.NoError(t, err)
+		assert.Equal(t, doc, result, expr)
+	}
+}
+
+func TestFilter_NegativeIndexAndSlices(t *testing.T) {
+	doc := map[string]any{
+		"tags":  []any{"a", "b", nil},
+		"items": []any{map[string]any{"id": float64(1)}, map[string]any{"id": float64(2)}},
+	}
+
+	for _, expr := range []string{
+		`tags[-1] = null`,
+		`tags[:2] in [b, c]`,
+		`exists(items[-1].id) and not exists(items[-3])`,
+		`items[1:].id > 1 and not items[:1].id > 1`,
+		`matches(tags[0:2], "^b$")`,
+	} {
+		filter, err := NewFilter(expr)
+		assert.NoError(t, err, expr)
+		result, err := filter.Apply(doc)
+		assert.NoError(t, err)
+		assert.Equal(t, doc, result, expr)
+	}
+}
+
+func TestFilter_MapWildcardAndRecursiveDescent(t *testing.T) {
+	doc := map[string]any{
+		"checks": map[string]any{"db": "ok", "cache": "failing"},
+		"spec":   map[string]any{"containers": []any{map[string]any{"image": "nginx:1.25"}}},
+	}
+
+	for _, expr := range []string{
+		`checks.*=failing`,
+		`checks.* in [degraded, failing] and exists(..image)`,
+		`**.image *= nginx and not exists(**.command)`,
+		`matches(spec.**.image, "^nginx:")`,
+	} {
+		filter, err := NewFilter(expr)
+		assert.NoError(t, err, expr)
+		result, err := filter.Apply(doc)
+		assert.NoError(t, err)
+		assert.Equal(t, doc, result, expr)
+	}
+}
+
+func TestFilter_RootPaths(t *testing.T) {
+	doc := []any{map[string]any{"id": float64(1)}, map[string]any{"id": float64(2), "tags": []any{"a", "prod"}}}
+
+	for _, expr := range []string{
+		`[0].id = 1`,
+		`[*].id > 1 and exists([1].tags)`,
+		`[-1].tags[?. = prod] in [prod]`,
+		`not exists([2]) and [0].id in [1, 3]`,
+	} {
+		filter, err := NewFilter(expr)
+		assert.NoError(t, err, expr)
+		result, err := filter.Apply(doc)
+		assert.NoError(t, err)
+		assert.Equal(t, doc, result, expr)
+	}
+
+	filter, err := NewFilter(`. > 3`)
+	assert.NoError(t, err)
+	result, err := filter.Apply(float64(2))
+	assert.NoError(t, err)
+	assert.Equal(t, Filtered, result)
+}
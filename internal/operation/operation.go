@@ -3,8 +3,12 @@ package operation
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
+
+	"github.com/GeoffMall/flow/internal/ordered"
 )
 
 // Operation represents a transformation applied to a document.
@@ -18,149 +22,683 @@ type Operation interface {
 
 // ----------------------------- Path parsing -----------------------------
 
-// A segment represents one step in a path. Either a key (map) and optional index (array).
+// A segment represents one step in a path. Either a key (map) and optional index or slice (array).
 // Examples:
 //   - "user"                -> {key: "user", idx: nil}
 //   - "items[0]"            -> {key: "items", idx: 0}
-//   - "items[*]"            -> {key: "items", idx: -1} (wildcard)
+//   - "items[-1]"           -> {key: "items", idx: -1} (last element)
+//   - "items[1:3]"          -> {key: "items", slice: {start: 1, end: 3, step: 1}}
+//   - "items[*]"            -> {key: "items", slice: {step: 1}} (wildcard)
+//   - "labels[\"a.b/c\"]"    -> {key: "labels"}, {key: "a.b/c"}
+//   - "user.*"              -> {key: "user"}, {anyKey: true} (every value of user)
+//   - "**.id" or "..id"     -> {descend: true}, {key: "id"} (id at any depth)
+//   - "items[?price>10]"    -> {key: "items", filter: price>10} (elements it is true for)
+//   - "[0].id"              -> {key: "", idx: 0}, {key: "id"} (an array at the root)
+//   - "grid[0][1]"          -> {key: "grid", idx: 0}, {key: "", idx: 1}
+//   - "m[\"\"]"              -> {key: "m"}, {key: "", emptyKey: true}
+//
+// A segment without a key (see hasKey) indexes the value it is at, and the
+// path "." has no segments: it is the whole document.
 type segment struct {
-	key string
-	idx *int // optional array index, -1 for wildcard
+	key      string      // "" for an index without a key, on the root or after another index
+	emptyKey bool        // the key is "" itself, written [""]
+	anyKey   bool        // "*": every value of an object, instead of key
+	descend  bool        // "**": the value and everything below it, instead of key
+	idx      *int        // optional array index, negative counts from the end
+	slice    *sliceRange // optional range of array elements
+	filter   *Filter     // optional condition on array elements, with paths relative to each
+}
+
+// sliceRange selects every step-th element of an array from start up to, but
+// not including, end. Missing bounds are the ends of the array; negative ones
+// count from the end, like Python slices. [*] is the whole array.
+type sliceRange struct {
+	start, end *int
+	step       int
+}
+
+// hasKey reports whether the segment steps into an object by its key, which
+// may be the empty key [""].
+func (s segment) hasKey() bool {
+	return s.key != "" || s.emptyKey
+}
+
+// indexed reports whether the segment has an index, slice or filter.
+func (s segment) indexed() bool {
+	return s.idx != nil || s.slice != nil || s.filter != nil
+}
+
+// indexes returns the positions the segment's index, slice or filter selects
+// in arr, in ascending order.
+func (s segment) indexes(arr []any) []int {
+	n := len(arr)
+	if s.idx != nil {
+		if i, ok := resolveIndex(*s.idx, n); ok {
+			return []int{i}
+		}
+		return nil
+	}
+
+	if s.filter != nil {
+		var out []int
+		for i, elem := range arr {
+			if s.filter.expr.eval(elem) {
+				out = append(out, i)
+			}
+		}
+		return out
+	}
+
+	start, end := 0, n
+	if s.slice.start != nil {
+		start = clampIndex(*s.slice.start, n)
+	}
+	if s.slice.end != nil {
+		end = clampIndex(*s.slice.end, n)
+	}
+
+	var out []int
+	for i := start; i < end; i += s.slice.step {
+		out = append(out, i)
+	}
+	return out
+}
+
+// resolveIndex turns an index that may count from the end into a position in
+// an array of length n, reporting false if it is out of range.
+func resolveIndex(idx, n int) (int, bool) {
+	if idx < 0 {
+		idx += n
+	}
+	return idx, idx >= 0 && idx < n
+}
+
+// clampIndex turns a slice bound into a position between 0 and n.
+func clampIndex(idx, n int) int {
+	if idx < 0 {
+		idx += n
+	}
+	return min(max(idx, 0), n)
 }
 
+// parsePath parses a path such as user.name, items[0].id,
+// metadata.labels["app.kubernetes.io/name"], spec.**.image or [0].name.
+//
+// Keys are separated by dots. A * in place of a key matches every value of an
+// object, and ** (or an empty key, as in a..b or ..id) matches a value and
+// every value nested in it, at any depth. A key that contains dots, brackets or quotes can
+// be written in brackets and quotes, ["a.b"] or ['a.b'], or with those
+// characters escaped by a backslash, a\.b. Inside quotes a backslash escapes
+// the next character, so ["say \"hi\""] is the key say "hi".
+//
+// The path . is the whole document, and a path may start with an index, as in
+// [0].name or [*].id, to look into a document that is an array. Like in jq, a
+// leading dot can be written before the first key or index: .user, .[0].
 func parsePath(path string) ([]segment, error) {
-	if path == "" {
+	switch path {
+	case "":
 		return nil, errors.New("empty path")
+	case rootPath:
+		return []segment{}, nil
 	}
 
-	parts := strings.Split(path, ".")
-	segs := make([]segment, 0, len(parts))
+	var segs []segment
+	i := 0
+	switch {
+	case strings.HasPrefix(path, ".."):
+		segs = append(segs, segment{descend: true})
+		i = 2
+	case strings.HasPrefix(path, "."):
+		i = 1
+	}
+	pathStart := i
+	for {
+		// A part is an optional bare key followed by bracketed indexes and quoted keys
+		start, first := i, len(segs)
 
-	for _, part := range parts {
-		// Allowed shapes:
-		//   - key
-		//   - key[idx]
-		//   - key[*] (wildcard)
-		s := segment{}
+		key, end, err := readBareKey(path, i)
+		if err != nil {
+			return nil, err
+		}
+		i = end
+		switch raw := path[start:end]; {
+		case raw == "*":
+			segs = append(segs, segment{anyKey: true})
+		case raw == "**" && endsWithDescend(segs):
+			first-- // a.**.**.b is a.**.b
+		case raw == "**":
+			segs = append(segs, segment{descend: true})
+		case key != "":
+			segs = append(segs, segment{key: key})
+		}
 
-		// Look for bracketed index
-		open := strings.IndexByte(part, '[')
-		if open < 0 {
-			// Just a key
-			s.key = part
-			segs = append(segs, s)
-			continue
+		for i < len(path) && path[i] == '[' {
+			text, quoted, end, err := readBracket(path, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+
+			if quoted {
+				segs = append(segs, segment{key: text, emptyKey: text == ""})
+				continue
+			}
+
+			// An index applies to the key just before it. Without one, at the
+			// start of the path or after another index, it indexes the value there.
+			if len(segs) == first && start != pathStart {
+				return nil, fmt.Errorf("invalid segment %q", path[start:i])
+			}
+			if len(segs) == first || segs[len(segs)-1].indexed() {
+				segs = append(segs, segment{})
+			}
+			if err := parseIndex(&segs[len(segs)-1], text, path[start:i]); err != nil {
+				return nil, err
+			}
 		}
 
-		// key[idx] expected
-		if !strings.HasSuffix(part, "]") || open == 0 {
-			return nil, fmt.Errorf("invalid segment %q", part)
+		if len(segs) == first {
+			return nil, fmt.Errorf("empty key in %q", path)
+		}
+		if i == len(path) {
+			return segs, nil
+		}
+		if path[i] != '.' {
+			return nil, fmt.Errorf("invalid segment %q (expected '.' or '[' after ']')", path[start:])
 		}
+		i++
 
-		s.key = part[:open]
-		idxStr := part[open+1 : len(part)-1]
+		// An empty key between two dots descends
+		if i < len(path) && path[i] == '.' {
+			if !endsWithDescend(segs) {
+				segs = append(segs, segment{descend: true})
+			}
+			i++
+		}
+	}
+}
+
+// endsWithDescend reports whether the last segment is a ** without an index,
+// which another ** right after it would only repeat.
+func endsWithDescend(segs []segment) bool {
+	n := len(segs)
+	return n > 0 && segs[n-1].descend && !segs[n-1].indexed()
+}
 
-		if idxStr == "" {
-			return nil, fmt.Errorf("empty index in %q", part)
+// readBareKey reads an unquoted key starting at path[start], up to the next
+// unescaped '.' or '['. A backslash makes the character after it part of the key.
+func readBareKey(path string, start int) (key string, end int, err error) {
+	var b strings.Builder
+	i := start
+	for ; i < len(path) && path[i] != '.' && path[i] != '['; i++ {
+		if path[i] == '\\' {
+			if i+1 == len(path) {
+				return "", 0, fmt.Errorf("invalid escape at end of %q", path)
+			}
+			i++
 		}
+		b.WriteByte(path[i])
+	}
+	return b.String(), i, nil
+}
 
-		// Handle wildcard
-		if idxStr == "*" {
-			wildcardIdx := -1
-			s.idx = &wildcardIdx
-		} else {
-			n, err := strconv.Atoi(idxStr)
-			if err != nil || n < 0 {
-				return nil, fmt.Errorf("invalid non-negative index in %q", part)
+// readBracket reads the bracketed selector starting at path[start], which is
+// '['. It returns the text between the brackets (unquoted and unescaped for a
+// quoted key), whether it was quoted, and the position after the ']'.
+// A filter, [?...], ends at the ']' that balances its '[', outside of quotes.
+func readBracket(path string, start int) (text string, quoted bool, end int, err error) {
+	i := start + 1
+	if i < len(path) && (path[i] == '"' || path[i] == '\'') {
+		quote := path[i]
+		var b strings.Builder
+		for i++; i < len(path) && path[i] != quote; i++ {
+			if path[i] == '\\' && i+1 < len(path) {
+				i++
+			}
+			b.WriteByte(path[i])
+		}
+		if i+1 >= len(path) || path[i+1] != ']' {
+			return "", false, 0, fmt.Errorf("invalid segment %q (unterminated quoted key)", path[start:])
+		}
+		return b.String(), true, i + 2, nil
+	}
+
+	if i < len(path) && path[i] == '?' {
+		if end := filterEnd(path, i); end > 0 {
+			return path[i : end-1], false, end, nil
+		}
+		return "", false, 0, fmt.Errorf("invalid segment %q (missing ']')", path[start:])
+	}
+
+	closing := strings.IndexByte(path[i:], ']')
+	if closing < 0 {
+		return "", false, 0, fmt.Errorf("invalid segment %q (missing ']')", path[start:])
+	}
+	return path[i : i+closing], false, i + closing + 1, nil
+}
+
+// filterEnd returns the position after the ']' that closes the filter
+// starting at path[start], or 0 if it isn't closed.
+func filterEnd(path string, start int) int {
+	depth := 1
+	for i := start; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '"', '\'':
+			_, end, err := readQuoted(path, i)
+			if err != nil {
+				return 0
 			}
-			s.idx = &n
+			i = end - 1
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// parseIndex parses the text of a bracketed index into seg: a number (negative
+// counts from the end), a slice start:end:step with optional parts, or *.
+func parseIndex(seg *segment, text, part string) error {
+	switch {
+	case text == "":
+		return fmt.Errorf("empty index in %q", part)
+	case text == "*":
+		seg.slice = &sliceRange{step: 1}
+		return nil
+	case strings.HasPrefix(text, "?"):
+		filter, err := NewFilter(text[1:])
+		if err != nil {
+			return fmt.Errorf("in %q: %w", part, err)
+		}
+		seg.filter = filter
+		return nil
+	case !strings.Contains(text, ":"):
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("invalid index in %q", part)
+		}
+		seg.idx = &n
+		return nil
+	}
+
+	bounds := strings.Split(text, ":")
+	if len(bounds) > 3 {
+		return fmt.Errorf("invalid slice in %q (expected start:end:step)", part)
+	}
+	nums := make([]*int, 3)
+	for i, b := range bounds {
+		if b == "" {
+			continue
+		}
+		n, err := strconv.Atoi(b)
+		if err != nil {
+			return fmt.Errorf("invalid slice in %q", part)
+		}
+		nums[i] = &n
+	}
+
+	r := &sliceRange{start: nums[0], end: nums[1], step: 1}
+	if nums[2] != nil {
+		if *nums[2] <= 0 {
+			return fmt.Errorf("invalid slice in %q (step must be positive)", part)
+		}
+		r.step = *nums[2]
+	}
+	seg.slice = r
+	return nil
+}
+
+// keyString returns the key of seg as written in a path: quoted if needed, * or
+// **, or "" if the segment has no key.
+func keyString(seg segment) string {
+	switch {
+	case seg.anyKey:
+		return "*"
+	case seg.descend:
+		return "**"
+	case !seg.hasKey():
+		return ""
+	}
+	return quoteKey(seg.key)
+}
+
+// indexString returns the bracketed index or slice of seg as written in a
+// path, or "" if it has none.
+func indexString(seg segment) string {
+	switch {
+	case seg.idx != nil:
+		return fmt.Sprintf("[%d]", *seg.idx)
+	case seg.filter != nil:
+		return "[?" + seg.filter.source + "]"
+	case seg.slice == nil:
+		return ""
+	case seg.slice.start == nil && seg.slice.end == nil && seg.slice.step == 1:
+		return "[*]"
+	}
+
+	bound := func(n *int) string {
+		if n == nil {
+			return ""
+		}
+		return strconv.Itoa(*n)
+	}
+	s := "[" + bound(seg.slice.start) + ":" + bound(seg.slice.end)
+	if seg.slice.step != 1 {
+		s += ":" + strconv.Itoa(seg.slice.step)
+	}
+	return s + "]"
+}
+
+// skipPathQuoting returns the position after the escaped character or
+// bracketed selector at s[i], or i if neither starts there. Scanners that look
+// for an operator after a path use it to step over quoted keys.
+func skipPathQuoting(s string, i int) int {
+	switch s[i] {
+	case '\\':
+		return min(i+2, len(s))
+	case '[':
+		if _, _, end, err := readBracket(s, i); err == nil {
+			return end
+		}
+	}
+	return i
+}
+
+// anyKeys reports whether the segment matches values under keys it doesn't name (* or **).
+func (s segment) anyKeys() bool {
+	return s.anyKey || s.descend
+}
+
+// wildcardKeyAt reports whether s[i] is a * of a * or ** key in a path, so
+// scanners looking for an operator after a path don't take it for *= or *i=.
+func wildcardKeyAt(s string, i int) bool {
+	if s[i] != '*' {
+		return false
+	}
+	keyStart := func(j int) bool { return j == 0 || s[j-1] == '.' }
+	return keyStart(i) || (s[i-1] == '*' && keyStart(i-1))
+}
+
+// hasWildcard reports whether a path can match more than one value.
+func hasWildcard(segs []segment) bool {
+	for _, s := range segs {
+		if s.slice != nil || s.filter != nil || s.anyKeys() {
+			return true
+		}
+	}
+	return false
+}
+
+// isConcrete reports whether a path names a single place without looking at
+// the document, so it can be created if it is missing: no wildcards, slices
+// or indexes counting from the end.
+func isConcrete(segs []segment) bool {
+	for _, s := range segs {
+		if s.slice != nil || s.filter != nil || s.anyKeys() || (s.idx != nil && *s.idx < 0) {
+			return false
 		}
-		segs = append(segs, s)
 	}
+	return true
+}
+
+// rootPath is the path of the whole document.
+const rootPath = "."
+
+// pathKeyQuoting are the characters that make a key need brackets and quotes.
+const pathKeyQuoting = ".[]\\\"'"
+
+// quoteKey returns key as written in a path: bare when it can be, otherwise
+// as a bracketed, quoted key such as ["app.kubernetes.io/name"].
+func quoteKey(key string) string {
+	if key != "" && key != "*" && key != "**" && !strings.ContainsAny(key, pathKeyQuoting) {
+		return key
+	}
+	var b strings.Builder
+	b.WriteString(`["`)
+	for i := 0; i < len(key); i++ {
+		if key[i] == '"' || key[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(key[i])
+	}
+	b.WriteString(`"]`)
+	return b.String()
+}
+
+// ----------------------------- Objects -----------------------------
 
-	return segs, nil
+// Objects in a document are either map[string]any (Avro, Parquet and CSV
+// input, and values built in code) or *ordered.Map (JSON and YAML input, which
+// keep their key order). These helpers treat both alike.
+
+func isObject(v any) bool {
+	switch v.(type) {
+	case map[string]any, *ordered.Map:
+		return true
+	}
+	return false
+}
+
+// objectGet returns the value under key, or false if v is not an object or lacks key.
+func objectGet(v any, key string) (any, bool) {
+	if om, ok := v.(*ordered.Map); ok {
+		return om.Get(key)
+	}
+	m, ok := asStringMap(v)
+	if !ok {
+		return nil, false
+	}
+	val, ok := m[key]
+	return val, ok
+}
+
+// objectSet stores val under key. It is a no-op if obj is not an object.
+func objectSet(obj any, key string, val any) {
+	switch o := obj.(type) {
+	case *ordered.Map:
+		o.Set(key, val)
+	case map[string]any:
+		o[key] = val
+	}
+}
+
+// objectDelete removes key. It is a no-op if obj is not an object.
+func objectDelete(obj any, key string) {
+	switch o := obj.(type) {
+	case *ordered.Map:
+		o.Delete(key)
+	case map[string]any:
+		delete(o, key)
+	}
+}
+
+// objectKeys returns the keys of an object in order: insertion order for an
+// *ordered.Map and sorted for a map[string]any. It is nil if v is not an object.
+func objectKeys(v any) []string {
+	switch o := v.(type) {
+	case *ordered.Map:
+		return o.Keys()
+	case map[string]any:
+		return slices.Sorted(maps.Keys(o))
+	}
+	return nil
+}
+
+func objectLen(obj any) int {
+	switch o := obj.(type) {
+	case *ordered.Map:
+		return o.Len()
+	case map[string]any:
+		return len(o)
+	}
+	return 0
+}
+
+// newObjectLike returns an empty object of the same kind as like, so that
+// objects created inside an ordered document keep their key order too.
+func newObjectLike(like any) any {
+	if _, ok := like.(*ordered.Map); ok {
+		return ordered.NewMap()
+	}
+	return make(map[string]any)
+}
+
+// cloneValue returns a deep copy of objects and arrays in v, so a value can be
+// placed in several documents (or several places) without them sharing it.
+func cloneValue(v any) any {
+	switch t := v.(type) {
+	case *ordered.Map:
+		out := ordered.NewMap()
+		for k, val := range t.All() {
+			out.Set(k, cloneValue(val))
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			out[k] = cloneValue(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, val := range t {
+			out[i] = cloneValue(val)
+		}
+		return out
+	}
+	return v
 }
 
 // ----------------------------- Wildcard expansion -----------------------------
 
+// Wildcards, slices, filters and indexes that count from the end are expanded
+// against a document into concrete paths: segments with plain keys and
+// non-negative indexes only, which getAtPath, setAtPathOverwrite and
+// deleteAtPath follow without looking anything up.
+
 // expandWildcardPaths takes a path with wildcards and returns all concrete paths
-func expandWildcardPaths(v any, pathStr string) ([]string, error) {
+func expandWildcardPaths(v any, pathStr string) ([][]segment, error) {
 	segs, err := parsePath(pathStr)
 	if err != nil {
 		return nil, err
 	}
 
-	return expandSegments(v, segs, "")
+	return expandSegments(v, segs, nil), nil
 }
 
-// expandSegments recursively expands wildcard segments into concrete paths
-func expandSegments(v any, segs []segment, currentPath string) ([]string, error) {
+// expandSegments recursively expands wildcard segments into concrete paths,
+// each starting with current
+func expandSegments(v any, segs []segment, current []segment) [][]segment {
 	if len(segs) == 0 {
-		return []string{currentPath}, nil
+		return [][]segment{current}
 	}
 
 	seg := segs[0]
 	remaining := segs[1:]
 
-	// Handle map key
-	if seg.key != "" {
-		m, ok := v.(map[string]any)
-		if !ok {
-			return nil, nil // Path doesn't exist
+	switch {
+	case seg.descend:
+		return expandDescendants(v, seg, remaining, current)
+
+	case seg.anyKey:
+		// Every value of an object
+		var allPaths [][]segment
+		for _, key := range objectKeys(v) {
+			child, _ := objectGet(v, key)
+			allPaths = append(allPaths, expandIndex(child, seg, remaining, withKey(current, key))...)
 		}
+		return allPaths
 
-		child, exists := m[seg.key]
+	case seg.hasKey():
+		// Handle map key
+		child, exists := objectGet(v, seg.key)
 		if !exists {
-			return nil, nil
+			return nil // Path doesn't exist
 		}
 
-		newPath := buildPath(currentPath, seg.key)
+		return expandIndex(child, seg, remaining, withKey(current, seg.key))
+	}
 
-		// If no index, continue with child
-		if seg.idx == nil {
-			return expandSegments(child, remaining, newPath)
-		}
+	// An index without a key applies to v itself
+	return expandArrayIndex(v, seg, remaining, current)
+}
+
+// expandIndex continues expanding at the value a segment's key selected,
+// applying the segment's index or slice first if it has one.
+func expandIndex(child any, seg segment, remaining []segment, current []segment) [][]segment {
+	// If no index, continue with child
+	if !seg.indexed() {
+		return expandSegments(child, remaining, current)
+	}
+
+	// Handle array index (potentially wildcard or slice)
+	return expandArrayIndex(child, seg, remaining, current)
+}
+
+// expandDescendants expands the rest of the path at v and at every value
+// nested in it, depth first, so a value's paths come before those inside it.
+func expandDescendants(v any, seg segment, remaining []segment, current []segment) [][]segment {
+	allPaths := expandIndex(v, seg, remaining, current)
 
-		// Handle array index (potentially wildcard)
-		return expandArrayIndex(child, seg.idx, remaining, newPath)
+	if arr, ok := v.([]any); ok {
+		for i, child := range arr {
+			allPaths = append(allPaths, expandDescendants(child, seg, remaining, withIndex(current, i))...)
+		}
+		return allPaths
 	}
 
-	return nil, nil
+	for _, key := range objectKeys(v) {
+		child, _ := objectGet(v, key)
+		allPaths = append(allPaths, expandDescendants(child, seg, remaining, withKey(current, key))...)
+	}
+	return allPaths
 }
 
-// expandArrayIndex handles array indexing with wildcard support
-func expandArrayIndex(v any, idx *int, remaining []segment, currentPath string) ([]string, error) {
+// expandArrayIndex handles array indexing with wildcard, slice, filter and
+// from-the-end index support. Expanded paths use plain positions.
+func expandArrayIndex(v any, seg segment, remaining []segment, current []segment) [][]segment {
 	arr, ok := v.([]any)
 	if !ok {
-		return nil, nil
+		return nil
 	}
 
-	// Check if this is a wildcard
-	if *idx == -1 {
-		var allPaths []string
-		for i := 0; i < len(arr); i++ {
-			indexPath := fmt.Sprintf("%s[%d]", currentPath, i)
-			expandedPaths, err := expandSegments(arr[i], remaining, indexPath)
-			if err != nil {
-				return nil, err
-			}
-			allPaths = append(allPaths, expandedPaths...)
-		}
-		return allPaths, nil
+	var allPaths [][]segment
+	for _, i := range seg.indexes(arr) {
+		allPaths = append(allPaths, expandSegments(arr[i], remaining, withIndex(current, i))...)
 	}
+	return allPaths
+}
 
-	// Regular index
-	if *idx < 0 || *idx >= len(arr) {
-		return nil, nil
-	}
+// withKey returns a copy of path with a step into key appended.
+func withKey(path []segment, key string) []segment {
+	return append(slices.Clip(path), segment{key: key, emptyKey: key == ""})
+}
 
-	indexPath := fmt.Sprintf("%s[%d]", currentPath, *idx)
-	return expandSegments(arr[*idx], remaining, indexPath)
+// withIndex returns a copy of path with a step to element i appended, as the
+// index of its last key if that has none yet.
+func withIndex(path []segment, i int) []segment {
+	if n := len(path); n > 0 && path[n-1].hasKey() && path[n-1].idx == nil {
+		out := slices.Clone(path)
+		out[n-1].idx = &i
+		return out
+	}
+	return append(slices.Clip(path), segment{idx: &i})
 }
 
+// buildPath appends key to a path, quoting it if it needs to be, so the
+// result parses back to the same keys.
 func buildPath(current, key string) string {
-	if current == "" {
-		return key
+	return joinPath(current, quoteKey(key))
+}
+
+// joinPath appends a key, as written in a path, to a path.
+func joinPath(current, key string) string {
+	if current == "" || key == "" || strings.HasPrefix(key, "[") {
+		return current + key
 	}
 	return current + "." + key
 }
@@ -1,8 +1,11 @@
 package operation
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
+	"github.com/GeoffMall/flow/internal/ordered"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -57,8 +60,9 @@ func TestParsePath_WithWildcard(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, segs, 1)
 	assert.Equal(t, "items", segs[0].key)
-	require.NotNil(t, segs[0].idx)
-	assert.Equal(t, -1, *segs[0].idx)
+	assert.Nil(t, segs[0].idx)
+	require.NotNil(t, segs[0].slice)
+	assert.Equal(t, sliceRange{step: 1}, *segs[0].slice)
 }
 
 func TestParsePath_NestedWithArray(t *testing.T) {
@@ -121,10 +125,77 @@ func TestParsePath_InvalidEmptyBrackets(t *testing.T) {
 	assert.Contains(t, err.Error(), "empty index")
 }
 
-func TestParsePath_InvalidNegativeIndex(t *testing.T) {
-	_, err := parsePath("items[-1]")
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "invalid")
+func TestParsePath_NegativeIndex(t *testing.T) {
+	segs, err := parsePath("items[-1]")
+	require.NoError(t, err)
+	require.Len(t, segs, 1)
+	require.NotNil(t, segs[0].idx)
+	assert.Equal(t, -1, *segs[0].idx)
+	assert.Nil(t, segs[0].slice)
+}
+
+func TestParsePath_Slices(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+	tests := []struct {
+		path  string
+		slice sliceRange
+	}{
+		{"items[1:3]", sliceRange{start: intPtr(1), end: intPtr(3), step: 1}},
+		{"items[:5]", sliceRange{end: intPtr(5), step: 1}},
+		{"items[2:]", sliceRange{start: intPtr(2), step: 1}},
+		{"items[::2]", sliceRange{step: 2}},
+		{"items[-3:-1]", sliceRange{start: intPtr(-3), end: intPtr(-1), step: 1}},
+		{"items[:]", sliceRange{step: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			segs, err := parsePath(tt.path)
+			require.NoError(t, err)
+			require.Len(t, segs, 1)
+			assert.Nil(t, segs[0].idx)
+			require.NotNil(t, segs[0].slice)
+			assert.Equal(t, tt.slice, *segs[0].slice)
+		})
+	}
+}
+
+func TestParsePath_InvalidSlices(t *testing.T) {
+	for _, path := range []string{"items[1:2:3:4]", "items[a:2]", "items[::0]", "items[::-1]", "items[1.5]"} {
+		_, err := parsePath(path)
+		assert.ErrorContains(t, err, "invalid", path)
+	}
+}
+
+func TestSegmentIndexes(t *testing.T) {
+	tests := []struct {
+		path string
+		want []int
+	}{
+		{"a[0]", []int{0}},
+		{"a[-1]", []int{4}},
+		{"a[-5]", []int{0}},
+		{"a[-6]", nil},
+		{"a[5]", nil},
+		{"a[*]", []int{0, 1, 2, 3, 4}},
+		{"a[1:3]", []int{1, 2}},
+		{"a[:2]", []int{0, 1}},
+		{"a[3:]", []int{3, 4}},
+		{"a[::2]", []int{0, 2, 4}},
+		{"a[1::2]", []int{1, 3}},
+		{"a[-2:]", []int{3, 4}},
+		{"a[:-3]", []int{0, 1}},
+		{"a[:100]", []int{0, 1, 2, 3, 4}},
+		{"a[3:1]", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			segs, err := parsePath(tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, segs[0].indexes(make([]any, 5)))
+		})
+	}
 }
 
 func TestParsePath_InvalidNonNumericIndex(t *testing.T) {
@@ -133,10 +204,47 @@ func TestParsePath_InvalidNonNumericIndex(t *testing.T) {
 	assert.Contains(t, err.Error(), "invalid")
 }
 
-func TestParsePath_InvalidBracketAtStart(t *testing.T) {
-	_, err := parsePath("[0]")
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "invalid")
+func TestParsePath_RootPaths(t *testing.T) {
+	segs, err := parsePath(".")
+	require.NoError(t, err)
+	assert.Empty(t, segs)
+
+	tests := []struct {
+		path string
+		keys []string
+		idx  []int // index of each segment, -99 for none
+	}{
+		{"[0]", []string{""}, []int{0}},
+		{"[-1].name", []string{"", "name"}, []int{-1, -99}},
+		{".[2]", []string{""}, []int{2}},
+		{".user.name", []string{"user", "name"}, []int{-99, -99}},
+		{"[0][1]", []string{"", ""}, []int{0, 1}},
+		{"grid[1][2].x", []string{"grid", "", "x"}, []int{1, 2, -99}},
+		{`["a"][0]`, []string{"a"}, []int{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			segs, err := parsePath(tt.path)
+			require.NoError(t, err)
+			require.Len(t, segs, len(tt.keys))
+			for i, seg := range segs {
+				assert.Equal(t, tt.keys[i], seg.key)
+				if tt.idx[i] == -99 {
+					assert.Nil(t, seg.idx)
+				} else {
+					require.NotNil(t, seg.idx)
+					assert.Equal(t, tt.idx[i], *seg.idx)
+				}
+			}
+		})
+	}
+
+	segs, err = parsePath("[*].id")
+	require.NoError(t, err)
+	require.Len(t, segs, 2)
+	assert.NotNil(t, segs[0].slice)
+	assert.True(t, hasWildcard(segs))
 }
 
 func TestParsePath_MultipleWildcards(t *testing.T) {
@@ -145,12 +253,227 @@ func TestParsePath_MultipleWildcards(t *testing.T) {
 	require.Len(t, segs, 2)
 
 	assert.Equal(t, "items", segs[0].key)
-	require.NotNil(t, segs[0].idx)
-	assert.Equal(t, -1, *segs[0].idx)
+	require.NotNil(t, segs[0].slice)
 
 	assert.Equal(t, "tags", segs[1].key)
+	require.NotNil(t, segs[1].slice)
+}
+
+func TestParsePath_QuotedKeys(t *testing.T) {
+	tests := []struct {
+		path string
+		keys []string
+	}{
+		{`labels["app.kubernetes.io/name"]`, []string{"labels", "app.kubernetes.io/name"}},
+		{`metadata.annotations['foo.bar/baz'].x`, []string{"metadata", "annotations", "foo.bar/baz", "x"}},
+		{`["a.b"]`, []string{"a.b"}},
+		{`["a[0]"]["b"]`, []string{"a[0]", "b"}},
+		{`m["say \"hi\""]`, []string{"m", `say "hi"`}},
+		{`m["back\\slash"]`, []string{"m", `back\slash`}},
+		{`m['it\'s']`, []string{"m", "it's"}},
+		{`app\.kubernetes\.io/name`, []string{"app.kubernetes.io/name"}},
+		{`a\[0\].b`, []string{"a[0]", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			segs, err := parsePath(tt.path)
+			require.NoError(t, err)
+			keys := make([]string, 0, len(segs))
+			for _, seg := range segs {
+				assert.Nil(t, seg.idx)
+				keys = append(keys, seg.key)
+			}
+			assert.Equal(t, tt.keys, keys)
+		})
+	}
+}
+
+func TestParsePath_QuotedKeyWithIndex(t *testing.T) {
+	segs, err := parsePath(`spec["my.items"][2].name`)
+	require.NoError(t, err)
+	require.Len(t, segs, 3)
+	assert.Equal(t, "spec", segs[0].key)
+	assert.Nil(t, segs[0].idx)
+	assert.Equal(t, "my.items", segs[1].key)
 	require.NotNil(t, segs[1].idx)
-	assert.Equal(t, -1, *segs[1].idx)
+	assert.Equal(t, 2, *segs[1].idx)
+	assert.Equal(t, "name", segs[2].key)
+}
+
+func TestParsePath_InvalidQuotedKeys(t *testing.T) {
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{`labels["app`, "unterminated quoted key"},
+		{`labels["app"`, "unterminated quoted key"},
+		{`labels["a"]x`, "invalid segment"},
+		{`a...b`, "empty key"},
+		{`a..`, "empty key"},
+		{`a.`, "empty key"},
+		{`a\`, "invalid escape"},
+		{`a.[0]`, "invalid segment"},
+		{`..`, "empty key"},
+		{`...`, "empty key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := parsePath(tt.path)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestParsePath_Wildcards(t *testing.T) {
+	tests := []struct {
+		path string
+		want string // segments as key/*/** joined by spaces
+	}{
+		{"user.*", "user *"},
+		{"*.name", "* name"},
+		{"**.id", "** id"},
+		{"..id", "** id"},
+		{"spec..image", "spec ** image"},
+		{"a.**", "a **"},
+		{"a.**.**.b", "a ** b"},
+		{"a..**.b", "a ** b"},
+		{"items[*].*", "items *"},
+		{`\*.\*\*`, "\\* \\*\\*"},
+		{`["*"]`, "\\*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			segs, err := parsePath(tt.path)
+			require.NoError(t, err)
+			parts := make([]string, 0, len(segs))
+			for _, seg := range segs {
+				switch {
+				case seg.anyKey:
+					parts = append(parts, "*")
+				case seg.descend:
+					parts = append(parts, "**")
+				default:
+					parts = append(parts, strings.ReplaceAll(seg.key, "*", "\\*"))
+				}
+			}
+			assert.Equal(t, tt.want, strings.Join(parts, " "))
+		})
+	}
+}
+
+func TestParsePath_Filters(t *testing.T) {
+	tests := []struct {
+		path   string
+		source string
+		rest   int // segments after the filtered one
+	}{
+		{"items[?status=active].name", "status=active", 1},
+		{"items[?price>10]", "price>10", 0},
+		{`items[? tags in [a, "b]"] and not exists(meta.deleted) ].id`, `tags in [a, "b]"] and not exists(meta.deleted)`, 1},
+		{`items[?labels["app.kubernetes.io/name"]=web]`, `labels["app.kubernetes.io/name"]=web`, 0},
+		{"items[?parts[?ok=true].id=1]", "parts[?ok=true].id=1", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			segs, err := parsePath(tt.path)
+			require.NoError(t, err)
+			require.Len(t, segs, 1+tt.rest)
+			assert.Equal(t, "items", segs[0].key)
+			require.NotNil(t, segs[0].filter)
+			assert.Equal(t, tt.source, segs[0].filter.source)
+		})
+	}
+
+	for _, path := range []string{"items[?]", "items[?status=]", "items[?a=1", `items[?a="1]`} {
+		_, err := parsePath(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestQuoteKey(t *testing.T) {
+	assert.Equal(t, "name", quoteKey("name"))
+	assert.Equal(t, "app/name-1", quoteKey("app/name-1"))
+	assert.Equal(t, `["app.kubernetes.io/name"]`, quoteKey("app.kubernetes.io/name"))
+	assert.Equal(t, `["a[0]"]`, quoteKey("a[0]"))
+	assert.Equal(t, `["say \"hi\" \\o/"]`, quoteKey(`say "hi" \o/`))
+	assert.Equal(t, `[""]`, quoteKey(""))
+	assert.Equal(t, `["*"]`, quoteKey("*"))
+	assert.Equal(t, `["**"]`, quoteKey("**"))
+	assert.Equal(t, "a*b", quoteKey("a*b"))
+}
+
+func TestBuildPath_RoundTrips(t *testing.T) {
+	for _, key := range []string{
+		"", "name", "*", "**", "a.b", "[0]", "a]b", `say "hi"`, `back\slash`, "'", " spaced ", "..", "?",
+	} {
+		for _, parent := range []string{"", "m"} {
+			segs, err := parsePath(buildPath(parent, key))
+			require.NoError(t, err, key)
+			last := segs[len(segs)-1]
+			assert.Equal(t, key, last.key)
+			assert.True(t, last.hasKey(), key)
+			assert.False(t, last.anyKeys() || last.indexed(), key)
+			assert.Equal(t, buildPath(parent, key), pathToString(segs))
+		}
+	}
+
+	segs, err := parsePath(`m[""][0]`)
+	require.NoError(t, err)
+	require.Len(t, segs, 2)
+	assert.True(t, segs[1].emptyKey)
+	require.NotNil(t, segs[1].idx)
+}
+
+func TestPathToString_RoundTrips(t *testing.T) {
+	for _, path := range []string{
+		"user.name",
+		"items[*].tags[3]",
+		"items[-1].tags[1:3]",
+		"items[:5].tags[::2]",
+		"items[-2:].tags[1:-1:3]",
+		`metadata.labels["app.kubernetes.io/name"]`,
+		`a["b[0]"][1].c`,
+		`m["say \"hi\""]`,
+		"user.*",
+		"**.id",
+		"spec.**.containers[*].image",
+		`m["*"].*`,
+		"items[?status=active].name",
+		`items[?tags in [a, "b]"]]`,
+		".",
+		"[0].name",
+		"[*][1:3]",
+		"grid[0][-1].x",
+		"**[0]",
+	} {
+		segs, err := parsePath(path)
+		require.NoError(t, err)
+		assert.Equal(t, path, pathToString(segs))
+	}
+
+	// Escaped keys come back in their quoted form
+	segs, err := parsePath(`app\.kubernetes\.io/name`)
+	require.NoError(t, err)
+	assert.Equal(t, `["app.kubernetes.io/name"]`, pathToString(segs))
+}
+
+// expandToStrings expands path in v like expandWildcardPaths, returning the
+// concrete paths as strings.
+func expandToStrings(v any, path string) ([]string, error) {
+	expanded, err := expandWildcardPaths(v, path)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, segs := range expanded {
+		paths = append(paths, pathToString(segs))
+	}
+	return paths, nil
 }
 
 // Tests for expandWildcardPaths function
@@ -158,7 +481,7 @@ func TestExpandWildcardPaths_NoWildcard(t *testing.T) {
 	input := map[string]any{
 		"name": "alice",
 	}
-	paths, err := expandWildcardPaths(input, "name")
+	paths, err := expandToStrings(input, "name")
 	require.NoError(t, err)
 	// No wildcards means it returns the path (since the path exists)
 	require.Len(t, paths, 1)
@@ -169,7 +492,7 @@ func TestExpandWildcardPaths_SimpleWildcard(t *testing.T) {
 	input := map[string]any{
 		"items": []any{"first", "second", "third"},
 	}
-	paths, err := expandWildcardPaths(input, "items[*]")
+	paths, err := expandToStrings(input, "items[*]")
 	require.NoError(t, err)
 	require.Len(t, paths, 3)
 	assert.Equal(t, "items[0]", paths[0])
@@ -184,7 +507,7 @@ func TestExpandWildcardPaths_WildcardWithNestedField(t *testing.T) {
 			map[string]any{"name": "bob"},
 		},
 	}
-	paths, err := expandWildcardPaths(input, "users[*].name")
+	paths, err := expandToStrings(input, "users[*].name")
 	require.NoError(t, err)
 	require.Len(t, paths, 2)
 	assert.Equal(t, "users[0].name", paths[0])
@@ -195,7 +518,7 @@ func TestExpandWildcardPaths_EmptyArray(t *testing.T) {
 	input := map[string]any{
 		"items": []any{},
 	}
-	paths, err := expandWildcardPaths(input, "items[*]")
+	paths, err := expandToStrings(input, "items[*]")
 	require.NoError(t, err)
 	assert.Empty(t, paths)
 }
@@ -204,7 +527,7 @@ func TestExpandWildcardPaths_NonExistentPath(t *testing.T) {
 	input := map[string]any{
 		"name": "alice",
 	}
-	paths, err := expandWildcardPaths(input, "missing[*].field")
+	paths, err := expandToStrings(input, "missing[*].field")
 	require.NoError(t, err)
 	assert.Empty(t, paths)
 }
@@ -213,7 +536,7 @@ func TestExpandWildcardPaths_TypeMismatch(t *testing.T) {
 	input := map[string]any{
 		"items": "not an array",
 	}
-	paths, err := expandWildcardPaths(input, "items[*]")
+	paths, err := expandToStrings(input, "items[*]")
 	require.NoError(t, err)
 	assert.Empty(t, paths)
 }
@@ -229,7 +552,7 @@ func TestExpandWildcardPaths_MultipleWildcards(t *testing.T) {
 			},
 		},
 	}
-	paths, err := expandWildcardPaths(input, "teams[*].members[*]")
+	paths, err := expandToStrings(input, "teams[*].members[*]")
 	require.NoError(t, err)
 	require.Len(t, paths, 5)
 	assert.Equal(t, "teams[0].members[0]", paths[0])
@@ -251,7 +574,7 @@ func TestExpandWildcardPaths_DeepNesting(t *testing.T) {
 			},
 		},
 	}
-	paths, err := expandWildcardPaths(input, "a.b[*].c.d")
+	paths, err := expandToStrings(input, "a.b[*].c.d")
 	require.NoError(t, err)
 	require.Len(t, paths, 1)
 	assert.Equal(t, "a.b[0].c.d", paths[0])
@@ -259,10 +582,90 @@ func TestExpandWildcardPaths_DeepNesting(t *testing.T) {
 
 func TestExpandWildcardPaths_InvalidPath(t *testing.T) {
 	input := map[string]any{"name": "alice"}
-	_, err := expandWildcardPaths(input, "")
+	_, err := expandToStrings(input, "")
 	assert.Error(t, err)
 }
 
+func TestExpandWildcardPaths_QuotedKeys(t *testing.T) {
+	input := map[string]any{
+		"pods": []any{
+			map[string]any{"labels": map[string]any{"app.kubernetes.io/name": "web"}},
+			map[string]any{"labels": map[string]any{"app.kubernetes.io/name": "db"}},
+		},
+	}
+
+	paths, err := expandToStrings(input, `pods[*].labels["app.kubernetes.io/name"]`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`pods[0].labels["app.kubernetes.io/name"]`,
+		`pods[1].labels["app.kubernetes.io/name"]`,
+	}, paths)
+
+	for _, path := range paths {
+		segs, err := parsePath(path)
+		require.NoError(t, err)
+		_, ok := getAtPath(input, segs)
+		assert.True(t, ok, path)
+	}
+}
+
+func TestExpandWildcardPaths_MapWildcard(t *testing.T) {
+	input := ordered.NewMap()
+	input.Set("b", map[string]any{"id": 2})
+	input.Set("a", map[string]any{"id": 1, "x": map[string]any{"y": 1, "id": 3}})
+	input.Set("c", "scalar")
+
+	paths, err := expandToStrings(input, "*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "a", "c"}, paths) // key order of the document
+
+	paths, err = expandToStrings(input, "*.id")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b.id", "a.id"}, paths)
+
+	paths, err = expandToStrings(input, "a.*")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.id", "a.x"}, paths) // map[string]any keys are sorted
+}
+
+func TestExpandWildcardPaths_RecursiveDescent(t *testing.T) {
+	input := map[string]any{
+		"id": 1,
+		"spec": map[string]any{
+			"containers": []any{
+				map[string]any{"name": "app", "image": "app:1"},
+				map[string]any{"name": "sidecar", "image": "proxy:2", "env": []any{map[string]any{"image": "x"}}},
+			},
+			"id": 2,
+		},
+	}
+
+	paths, err := expandToStrings(input, "..image")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"spec.containers[0].image",
+		"spec.containers[1].image",
+		"spec.containers[1].env[0].image",
+	}, paths)
+
+	paths, err = expandToStrings(input, "**.id")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "spec.id"}, paths)
+
+	paths, err = expandToStrings(input, "spec.**.containers[*].name")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"spec.containers[0].name", "spec.containers[1].name"}, paths)
+
+	// ** matches the value itself too
+	paths, err = expandToStrings(input, "spec.containers[0].**")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"spec.containers[0]",
+		"spec.containers[0].image",
+		"spec.containers[0].name",
+	}, paths)
+}
+
 // Tests for buildPath helper function
 func TestBuildPath_EmptyCurrent(t *testing.T) {
 	result := buildPath("", "key")
@@ -279,6 +682,11 @@ func TestBuildPath_Nested(t *testing.T) {
 	assert.Equal(t, "a.b.c.d", result)
 }
 
+func TestBuildPath_QuotesKey(t *testing.T) {
+	assert.Equal(t, `labels["app.kubernetes.io/name"]`, buildPath("labels", "app.kubernetes.io/name"))
+	assert.Equal(t, `["a.b"]`, buildPath("", "a.b"))
+}
+
 // Tests for asStringMap helper function
 func TestAsStringMap_ValidMap(t *testing.T) {
 	input := map[string]any{"key": "value"}
@@ -398,3 +806,74 @@ func TestGetAtPath_ComplexNested(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, "bob", val)
 }
+
+// ----------------------------- Ordered documents -----------------------------
+
+// orderedDoc decodes JSON into an order-preserving document, as the JSON parser does.
+func orderedDoc(t *testing.T, s string) any {
+	t.Helper()
+	v, err := ordered.DecodeJSON([]byte(s))
+	require.NoError(t, err)
+	return v
+}
+
+func marshal(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestOrdered_SetKeepsOrderAndAppendsNewKeys(t *testing.T) {
+	doc := orderedDoc(t, `{"kind":"Deployment","spec":{"replicas":1,"template":{"image":"a"}},"status":{}}`)
+
+	set, err := NewSetFromPairs([]string{"spec.replicas=3", "spec.paused=true", "metadata.labels.app=web", "spec.ports[1]=80"})
+	require.NoError(t, err)
+
+	out, err := set.Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t,
+		`{"kind":"Deployment","spec":{"replicas":3,"template":{"image":"a"},"paused":true,"ports":[null,80]},"status":{},"metadata":{"labels":{"app":"web"}}}`,
+		marshal(t, out))
+}
+
+func TestOrdered_DeleteKeepsOrder(t *testing.T) {
+	doc := orderedDoc(t, `{"c":1,"b":{"z":1,"y":2,"x":3},"a":[{"q":1,"p":2}]}`)
+
+	out, err := NewDelete([]string{"b.y", "a[0].q", "c"}).Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, `{"b":{"z":1,"x":3},"a":[{"p":2}]}`, marshal(t, out))
+}
+
+func TestOrdered_Pick(t *testing.T) {
+	doc := orderedDoc(t, `{"user":{"name":"alice","id":7},"items":[{"id":1},{"id":2}]}`)
+
+	// Multiple paths come out in the order they were requested
+	out, err := NewPick([]string{"user.name", "items[*].id"}, false).Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"alice","id":[1,2]}`, marshal(t, out))
+
+	out, err = NewPick([]string{"user.id", "user.name"}, true).Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, `{"user":{"id":7,"name":"alice"}}`, marshal(t, out))
+
+	out, err = NewPick([]string{"user"}, false).Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"alice","id":7}`, marshal(t, out))
+}
+
+func TestOrdered_WhereAndFilter(t *testing.T) {
+	doc := orderedDoc(t, `{"user":{"name":"alice","tags":["a","b"]},"n":5}`)
+
+	where, err := NewWhere([]string{"user.name=alice", "user.tags[1]=b", "n>4"})
+	require.NoError(t, err)
+	out, err := where.Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, doc, out)
+
+	filter, err := NewFilter(`exists(user.tags) and not matches(user, ".*")`)
+	require.NoError(t, err)
+	out, err = filter.Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, doc, out)
+}
@@ -1,6 +1,7 @@
 package operation
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -338,12 +339,52 @@ func TestPick_InvalidPath_MissingCloseBracket(t *testing.T) {
 	assert.Contains(t, err.Error(), "invalid")
 }
 
-func TestPick_InvalidPath_NegativeIndex(t *testing.T) {
-	pick := NewPick([]string{"items[-1]"}, false)
+func TestPick_NegativeIndex(t *testing.T) {
 	input := map[string]any{"items": []any{1, 2, 3}}
-	_, err := pick.Apply(input)
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "invalid")
+
+	result, err := NewPick([]string{"items[-1]"}, false).Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result)
+
+	result, err = NewPick([]string{"items[-3]"}, false).Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result)
+
+	result, err = NewPick([]string{"items[-4]"}, false).Apply(input)
+	require.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestPick_Slices(t *testing.T) {
+	input := map[string]any{
+		"items": []any{
+			map[string]any{"id": 0}, map[string]any{"id": 1}, map[string]any{"id": 2},
+			map[string]any{"id": 3}, map[string]any{"id": 4},
+		},
+		"tags": []any{"a", "b", "c"},
+	}
+
+	tests := []struct {
+		path string
+		want []any
+	}{
+		{"items[1:3].id", []any{1, 2}},
+		{"items[:2].id", []any{0, 1}},
+		{"items[::2].id", []any{0, 2, 4}},
+		{"items[-2:].id", []any{3, 4}},
+		{"items[10:].id", []any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result, err := NewPick([]string{tt.path}, false).Apply(input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+
+	result, err := NewPick([]string{"items[-1].id", "tags[:2]"}, false).Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": 4, "tags": []any{"a", "b"}}, result)
 }
 
 func TestPick_ArrayIndexOutOfBounds(t *testing.T) {
@@ -563,3 +604,204 @@ func TestPick_AllTypes(t *testing.T) {
 	}
 	assert.Equal(t, expected, result)
 }
+
+func TestPick_QuotedKeys(t *testing.T) {
+	input := map[string]any{
+		"metadata": map[string]any{
+			"labels": map[string]any{"app.kubernetes.io/name": "web", "tier": "frontend"},
+		},
+		"items": []any{
+			map[string]any{"a.b": 1},
+			map[string]any{"a.b": 2},
+		},
+	}
+
+	pick := NewPick([]string{`metadata.labels["app.kubernetes.io/name"]`}, false)
+	result, err := pick.Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, "web", result)
+
+	pick = NewPick([]string{`metadata.labels.app\.kubernetes\.io/name`}, false)
+	result, err = pick.Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, "web", result)
+
+	pick = NewPick([]string{`items[*]["a.b"]`}, false)
+	result, err = pick.Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, []any{1, 2}, result)
+
+	pick = NewPick([]string{`metadata.labels["app.kubernetes.io/name"]`, "metadata.labels.tier"}, false)
+	result, err = pick.Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"app.kubernetes.io/name": "web", "tier": "frontend"}, result)
+
+	pick = NewPick([]string{`metadata.labels["app.kubernetes.io/name"]`}, true)
+	result, err = pick.Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"metadata": map[string]any{"labels": map[string]any{"app.kubernetes.io/name": "web"}},
+	}, result)
+}
+
+func TestPick_WildcardWithOneMatch(t *testing.T) {
+	input := map[string]any{"items": []any{map[string]any{"name": "a"}}}
+
+	result, err := NewPick([]string{"items[*].name"}, false).Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"a"}, result)
+
+	result, err = NewPick([]string{"items[*].name", "items"}, false).Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"a"}, result.(map[string]any)["name"])
+}
+
+func TestPick_MapWildcardAndRecursiveDescent(t *testing.T) {
+	// A rendered Helm chart: images sit at different depths in each kind of resource
+	input := map[string]any{
+		"kind": "List",
+		"items": []any{
+			map[string]any{
+				"kind": "Deployment",
+				"spec": map[string]any{"template": map[string]any{"spec": map[string]any{
+					"initContainers": []any{map[string]any{"image": "busybox:1.36"}},
+					"containers":     []any{map[string]any{"image": "web:2.1"}},
+				}}},
+			},
+			map[string]any{
+				"kind": "CronJob",
+				"spec": map[string]any{"jobTemplate": map[string]any{"spec": map[string]any{"template": map[string]any{"spec": map[string]any{
+					"containers": []any{map[string]any{"image": "backup:0.9"}},
+				}}}}},
+			},
+		},
+		"resources": map[string]any{"cpu": "100m", "memory": "128Mi"},
+	}
+
+	tests := []struct {
+		path string
+		want any
+	}{
+		{"..image", []any{"web:2.1", "busybox:1.36", "backup:0.9"}},
+		{"**.image", []any{"web:2.1", "busybox:1.36", "backup:0.9"}},
+		{"items[*]..containers[*].image", []any{"web:2.1", "backup:0.9"}},
+		{"items[*].kind", []any{"Deployment", "CronJob"}},
+		{"resources.*", []any{"100m", "128Mi"}},
+		{"items[0].spec.*.spec.containers[0].image", []any{"web:2.1"}},
+		{"**.missing", []any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result, err := NewPick([]string{tt.path}, false).Apply(input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+
+	result, err := NewPick([]string{"..image", "resources.*"}, false).Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"image":     []any{"web:2.1", "busybox:1.36", "backup:0.9"},
+		"resources": []any{"100m", "128Mi"},
+	}, result)
+}
+
+func TestPick_Filters(t *testing.T) {
+	input := map[string]any{
+		"items": []any{
+			map[string]any{"name": "a", "status": "active", "price": float64(5)},
+			map[string]any{"name": "b", "status": "inactive", "price": float64(15)},
+			map[string]any{"name": "c", "status": "active", "price": float64(25), "tags": []any{"sale"}},
+		},
+	}
+
+	tests := []struct {
+		path string
+		want any
+	}{
+		{"items[?status=active].name", []any{"a", "c"}},
+		{"items[?price>10].name", []any{"b", "c"}},
+		{"items[?status=active and price>10].name", []any{"c"}},
+		{"items[?exists(tags)].name", []any{"c"}},
+		{"items[?name in [a, b]].price", []any{float64(5), float64(15)}},
+		{"items[?status=deleted].name", []any{}},
+		{"items[?price>20]", []any{input["items"].([]any)[2]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			result, err := NewPick([]string{tt.path}, false).Apply(input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+
+	_, err := NewPick([]string{"items[?price>]"}, false).Apply(input)
+	assert.ErrorContains(t, err, "invalid --pick")
+}
+
+func TestPick_RootPaths(t *testing.T) {
+	input := []any{
+		map[string]any{"id": 1, "name": "a"},
+		map[string]any{"id": 2, "name": "b"},
+	}
+
+	tests := []struct {
+		paths []string
+		want  any
+	}{
+		{[]string{"."}, input},
+		{[]string{"[0].name"}, "a"},
+		{[]string{".[-1].id"}, 2},
+		{[]string{"[*].id"}, []any{1, 2}},
+		{[]string{"[?id>1]"}, []any{input[1]}},
+		{[]string{"[5]"}, nil},
+		{[]string{"[0].id", "[*].name"}, map[string]any{"id": 1, "name": []any{"a", "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.paths, ","), func(t *testing.T) {
+			result, err := NewPick(tt.paths, false).Apply(input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+
+	grid := map[string]any{"grid": []any{[]any{1, 2}, []any{3, 4}}}
+	result, err := NewPick([]string{"grid[1][0]"}, false).Apply(grid)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result)
+
+	result, err = NewPick([]string{"grid[*][1]"}, false).Apply(grid)
+	require.NoError(t, err)
+	assert.Equal(t, []any{2, 4}, result)
+}
+
+func TestPick_RootPaths_PreserveHierarchy(t *testing.T) {
+	input := []any{
+		map[string]any{"id": 1, "name": "a"},
+		map[string]any{"id": 2, "name": "b"},
+	}
+
+	result, err := NewPick([]string{"[1].name"}, true).Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, []any{nil, map[string]any{"name": "b"}}, result)
+
+	result, err = NewPick([]string{"."}, true).Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, input, result)
+}
+
+func TestPick_EmptyKey(t *testing.T) {
+	input := map[string]any{"labels": map[string]any{"": "blank", "app": "web"}}
+
+	result, err := NewPick([]string{"labels.*"}, false).Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"blank", "web"}, result)
+
+	result, err = NewPick([]string{`labels[""]`}, false).Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, "blank", result)
+
+	result, err = NewPick([]string{"labels.*"}, true).Apply(input)
+	require.NoError(t, err)
+	assert.Equal(t, input, result)
+}
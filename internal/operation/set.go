@@ -3,7 +3,10 @@ package operation
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 // ----------------------------- Set -----------------------------
@@ -61,109 +64,126 @@ func (s *Set) Description() string {
 }
 
 func (s *Set) Apply(v any) (any, error) {
-	// If the root isn't an object and we need to set a nested key, we convert to object.
-	// If it's nil, start a new object.
-	root, ok := v.(map[string]any)
-	if !ok {
-		// If caller pipes in a scalar/array and sets "": reject; we don't support empty/root path.
-		// Otherwise, we start from an empty object and place values there.
-		root = make(map[string]any)
+	if doc, ok := v.(*yaml.Node); ok {
+		return doc, s.applyToNode(doc)
 	}
 
+	// The root is edited like any other value: an array root stays an array
+	// for paths like [0].name, and is only replaced when a path needs an object
+	// there (or a scalar or nil root needs either).
+	root := v
 	for _, a := range s.Assignments {
 		// Expand wildcards into concrete paths
-		expandedPaths, err := expandWildcardPaths(root, a.Path)
+		expandedPaths, err := assignPaths(root, a.Path)
 		if err != nil {
 			return nil, fmt.Errorf("invalid path %q: %w", a.Path, err)
 		}
 
-		// If no expansion occurred, use original path
-		if len(expandedPaths) == 0 {
-			expandedPaths = []string{a.Path}
-		}
-
 		// Process each expanded path
-		for _, expandedPath := range expandedPaths {
-			segs, err := parsePath(expandedPath)
-			if err != nil {
-				return nil, fmt.Errorf("invalid expanded path %q: %w", expandedPath, err)
-			}
-
-			setAtPathOverwrite(root, segs, a.Value)
+		for _, segs := range expandedPaths {
+			// Each place gets its own copy, so later edits (or documents
+			// processed concurrently) never share the parsed value
+			root = setAtPathOverwrite(root, segs, cloneValue(a.Value), v)
 		}
 	}
 
 	return root, nil
 }
 
-// setAtPathOverwrite walks/creates maps/slices to place val at segs.
-// If it encounters an incompatible existing type, it OVERWRITES it with the needed container.
-func setAtPathOverwrite(root map[string]any, segs []segment, val any) {
-	cur := root
-
-	for i, s := range segs {
-		isLast := i == len(segs)-1
+// assignPaths returns the concrete paths an assignment to pathStr sets in v.
+// Unlike a lookup, the last key doesn't have to exist yet: items[*].enabled
+// adds enabled to every item that is an object, and a missing concrete path
+// is returned as is so it is created. Paths that only make sense against
+// existing values (slices, filters, items[-1], **) expand to what exists.
+func assignPaths(v any, pathStr string) ([][]segment, error) {
+	segs, err := parsePath(pathStr)
+	if err != nil {
+		return nil, err
+	}
 
-		if s.idx == nil {
-			cur = handleMapSegmentOverwrite(cur, s.key, val, isLast)
-			if isLast {
-				return
-			}
-		} else {
-			cur = handleSliceSegmentOverwrite(cur, s.key, *s.idx, val, isLast)
-			if isLast {
-				return
+	var expandedPaths [][]segment
+	if len(segs) > 1 && addsKey(segs) {
+		last := segs[len(segs)-1]
+		for _, parent := range expandSegments(v, segs[:len(segs)-1], nil) {
+			if val, ok := getAtPath(v, parent); ok && isObject(val) {
+				expandedPaths = append(expandedPaths, append(slices.Clip(parent), last))
 			}
 		}
+	} else {
+		expandedPaths = expandSegments(v, segs, nil)
 	}
-}
 
-// handleMapSegmentOverwrite processes a segment without an index (simple map traversal)
-func handleMapSegmentOverwrite(cur map[string]any, key string, val any, isLast bool) map[string]any {
-	if isLast {
-		cur[key] = val
-		return cur
+	if len(expandedPaths) == 0 && isConcrete(segs) {
+		expandedPaths = [][]segment{segs}
 	}
+	return expandedPaths, nil
+}
 
-	return ensureNestedMap(cur, key)
+// addsKey reports whether the last segment of a path is a key that can be
+// added to the objects the rest of it selects: a concrete key, under parents
+// that don't descend.
+func addsKey(segs []segment) bool {
+	last := segs[len(segs)-1]
+	descends := slices.ContainsFunc(segs, func(s segment) bool { return s.descend })
+	return last.hasKey() && !descends && isConcrete([]segment{last})
 }
 
-// handleSliceSegmentOverwrite processes a segment with an index (slice operation)
-func handleSliceSegmentOverwrite(cur map[string]any, key string, idx int, val any, isLast bool) map[string]any {
-	slice := ensureSliceOverwrite(cur, key)
-	slice = expandSliceToIndex(slice, idx)
+// applyToNode performs the assignments on a YAML document in place (see yamlnode.go).
+func (s *Set) applyToNode(doc *yaml.Node) error {
+	for _, a := range s.Assignments {
+		expandedPaths, err := concretePaths(doc, a.Path, assignPaths)
+		if err != nil {
+			return err
+		}
 
-	if isLast {
-		slice[idx] = val
-		cur[key] = slice
-		return cur
+		for _, segs := range expandedPaths {
+			if err := setNodeAtPath(doc, segs, a.Value); err != nil {
+				return fmt.Errorf("set %q: %w", pathToString(segs), err)
+			}
+		}
 	}
-
-	nextMap := ensureMapAtSliceIndex(slice, idx)
-	cur[key] = slice
-	return nextMap
+	return nil
 }
 
-// ensureNestedMap gets or creates a map under the given key, overwriting if necessary
-func ensureNestedMap(cur map[string]any, key string) map[string]any {
-	child, exists := cur[key]
-	if next, ok := child.(map[string]any); exists && ok && next != nil {
-		return next
+// setAtPathOverwrite places val at segs below cur and returns cur, or the
+// value that replaces it: val itself for an empty path, or a new container
+// when cur is not the object or array the next segment needs. Values of the
+// wrong kind are OVERWRITTEN, and missing array elements are filled with null.
+// New objects are created with the same kind as the nearest object above
+// them, or as like if there is none.
+func setAtPathOverwrite(cur any, segs []segment, val, like any) any {
+	if len(segs) == 0 {
+		return val
+	}
+
+	s := segs[0]
+	if !s.hasKey() {
+		return setIndexOverwrite(cur, s, segs[1:], val, like)
 	}
 
-	next := make(map[string]any)
-	cur[key] = next
-	return next
+	obj := cur
+	if !isObject(obj) {
+		obj = newObjectLike(like)
+	}
+	child, _ := objectGet(obj, s.key)
+	objectSet(obj, s.key, setIndexOverwrite(child, s, segs[1:], val, obj))
+	return obj
 }
 
-// ensureSliceOverwrite gets or creates a slice under the given key, overwriting if necessary
-func ensureSliceOverwrite(cur map[string]any, key string) []any {
-	child, exists := cur[key]
-	if slice, ok := child.([]any); exists && ok && slice != nil {
-		return slice
+// setIndexOverwrite applies the index of s, if it has one, to cur and places
+// val at the rest of the path below it, returning the new value for cur.
+func setIndexOverwrite(cur any, s segment, rest []segment, val, like any) any {
+	if s.idx == nil {
+		return setAtPathOverwrite(cur, rest, val, like)
 	}
 
-	return make([]any, 0)
+	slice, ok := cur.([]any)
+	if !ok {
+		slice = make([]any, 0)
+	}
+	slice = expandSliceToIndex(slice, *s.idx)
+	slice[*s.idx] = setAtPathOverwrite(slice[*s.idx], rest, val, like)
+	return slice
 }
 
 // expandSliceToIndex grows the slice to accommodate the given index
@@ -177,17 +197,6 @@ func expandSliceToIndex(slice []any, idx int) []any {
 	return newSlice
 }
 
-// ensureMapAtSliceIndex gets or creates a map at the specified slice index
-func ensureMapAtSliceIndex(slice []any, idx int) map[string]any {
-	if next, ok := slice[idx].(map[string]any); ok && next != nil {
-		return next
-	}
-
-	next := make(map[string]any)
-	slice[idx] = next
-	return next
-}
-
 // parseJSONish tries to unmarshal JSON first (so numbers/bools/objects/arrays work).
 // If it fails, the raw string is returned as a plain string.
 func parseJSONish(s string) (any, error) {
@@ -203,12 +212,18 @@ func parseJSONish(s string) (any, error) {
 	return s, nil
 }
 
-// splitOnce splits on the first sep. Returns (left, right, true) if sep found.
+// splitOnce splits on the first sep that is not inside a quoted or escaped
+// key of the path on its left. Returns (left, right, true) if sep found.
 func splitOnce(s string, sep byte) (string, string, bool) {
-	i := strings.IndexByte(s, sep)
-	if i < 0 {
-		return s, "", false
+	for i := 0; i < len(s); i++ {
+		if next := skipPathQuoting(s, i); next > i {
+			i = next - 1
+			continue
+		}
+		if s[i] == sep {
+			return s[:i], s[i+1:], true
+		}
 	}
 
-	return s[:i], s[i+1:], true
+	return s, "", false
 }
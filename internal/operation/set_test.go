@@ -335,6 +335,51 @@ func TestSet_ScalarInput(t *testing.T) {
 	assert.Equal(t, expected, result)
 }
 
+func TestSet_ArrayRoot(t *testing.T) {
+	set, err := NewSetFromPairs([]string{"[0].name=alice", "[-1].age=31", "[*].active=true", "[3]={\"name\":\"dan\"}"})
+	require.NoError(t, err)
+
+	result, err := set.Apply([]any{
+		map[string]any{"name": "al"},
+		map[string]any{"name": "bob", "age": 30},
+	})
+	require.NoError(t, err)
+
+	expected := []any{
+		map[string]any{"name": "alice", "active": true},
+		map[string]any{"name": "bob", "age": float64(31), "active": true},
+		nil,
+		map[string]any{"name": "dan"},
+	}
+	assert.Equal(t, expected, result)
+}
+
+func TestSet_RootPaths(t *testing.T) {
+	tests := []struct {
+		name  string
+		pairs []string
+		input any
+		want  any
+	}{
+		{"replace document", []string{".={\"a\":1}"}, []any{1, 2}, map[string]any{"a": float64(1)}},
+		{"then edit it", []string{".={}", "a=1"}, "scalar", map[string]any{"a": float64(1)}},
+		{"index a nil root", []string{"[1]=x"}, nil, []any{nil, "x"}},
+		{"key on an array root", []string{"name=alice"}, []any{1}, map[string]any{"name": "alice"}},
+		{"nested arrays", []string{"[0][1]=7"}, []any{[]any{1, 2}}, []any{[]any{1, float64(7)}}},
+		{"nested arrays under a key", []string{"grid[1][0]=5"}, map[string]any{}, map[string]any{"grid": []any{nil, []any{float64(5)}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := NewSetFromPairs(tt.pairs)
+			require.NoError(t, err)
+			result, err := set.Apply(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
+
 func TestSet_InvalidPair_NoEquals(t *testing.T) {
 	_, err := NewSetFromPairs([]string{"invalid"})
 	assert.Error(t, err)
@@ -529,3 +574,167 @@ func TestSplitOnce_Multiple(t *testing.T) {
 	assert.Equal(t, "a", left)
 	assert.Equal(t, "b=c", right)
 }
+
+func TestSet_ObjectValueIsNotShared(t *testing.T) {
+	set, err := NewSetFromPairs([]string{`meta={"tags":["a"]}`})
+	require.NoError(t, err)
+
+	first, err := set.Apply(map[string]any{})
+	require.NoError(t, err)
+	second, err := set.Apply(map[string]any{})
+	require.NoError(t, err)
+
+	// Editing one document's copy leaves the other and the assignment alone
+	first.(map[string]any)["meta"].(map[string]any)["tags"] = "changed"
+	assert.Equal(t, []any{"a"}, second.(map[string]any)["meta"].(map[string]any)["tags"])
+	assert.Equal(t, []any{"a"}, set.Assignments[0].Value.(map[string]any)["tags"])
+}
+
+func TestSet_QuotedKeys(t *testing.T) {
+	set, err := NewSetFromPairs([]string{
+		`metadata.labels["app.kubernetes.io/name"]=web`,
+		`metadata.annotations["checksum/config=v2"]=abc`,
+		`data.a\.b=1`,
+	})
+	require.NoError(t, err)
+
+	result, err := set.Apply(map[string]any{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"metadata": map[string]any{
+			"labels":      map[string]any{"app.kubernetes.io/name": "web"},
+			"annotations": map[string]any{"checksum/config=v2": "abc"},
+		},
+		"data": map[string]any{"a.b": float64(1)},
+	}, result)
+}
+
+func TestSplitOnce_SkipsQuotedKeys(t *testing.T) {
+	left, right, ok := splitOnce(`labels["a=b"]=c`, '=')
+	assert.True(t, ok)
+	assert.Equal(t, `labels["a=b"]`, left)
+	assert.Equal(t, "c", right)
+
+	left, right, ok = splitOnce(`a\=b=c`, '=')
+	assert.True(t, ok)
+	assert.Equal(t, `a\=b`, left)
+	assert.Equal(t, "c", right)
+}
+
+func TestSet_NegativeIndexAndSlices(t *testing.T) {
+	newInput := func() map[string]any {
+		return map[string]any{"items": []any{
+			map[string]any{"id": 0}, map[string]any{"id": 1}, map[string]any{"id": 2}, map[string]any{"id": 3},
+		}}
+	}
+	ids := func(doc any) []any {
+		var out []any
+		for _, item := range doc.(map[string]any)["items"].([]any) {
+			out = append(out, item.(map[string]any)["id"])
+		}
+		return out
+	}
+
+	tests := []struct {
+		assignment string
+		want       []any
+	}{
+		{"items[-1].id=9", []any{0, 1, 2, float64(9)}},
+		{"items[1:3].id=9", []any{0, float64(9), float64(9), 3}},
+		{"items[::2].id=9", []any{float64(9), 1, float64(9), 3}},
+		{"items[:-3].id=9", []any{float64(9), 1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.assignment, func(t *testing.T) {
+			set, err := NewSetFromPairs([]string{tt.assignment})
+			require.NoError(t, err)
+			result, err := set.Apply(newInput())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ids(result))
+		})
+	}
+}
+
+func TestSet_NegativeIndexOnMissingArray(t *testing.T) {
+	// There is no last element to replace, so nothing is created
+	set, err := NewSetFromPairs([]string{"items[-1]=1", "tags[1:]=x", "other[*].a=1"})
+	require.NoError(t, err)
+
+	result, err := set.Apply(map[string]any{"items": []any{}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"items": []any{}}, result)
+}
+
+func TestSet_MapWildcardAndRecursiveDescent(t *testing.T) {
+	set, err := NewSetFromPairs([]string{"limits.*=0", "..image=registry.local/app:1", "missing.*.x=1"})
+	require.NoError(t, err)
+
+	result, err := set.Apply(map[string]any{
+		"limits": map[string]any{"cpu": "1", "memory": "1Gi"},
+		"image":  "app:0",
+		"spec": map[string]any{
+			"containers": []any{map[string]any{"image": "app:0"}, map[string]any{"name": "no-image"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"limits": map[string]any{"cpu": float64(0), "memory": float64(0)},
+		"image":  "registry.local/app:1",
+		"spec": map[string]any{
+			"containers": []any{map[string]any{"image": "registry.local/app:1"}, map[string]any{"name": "no-image"}},
+		},
+	}, result)
+}
+
+func TestSet_Filters(t *testing.T) {
+	set, err := NewSetFromPairs([]string{"items[?status=active].priority=high", "items[?price>10].price=10"})
+	require.NoError(t, err)
+
+	result, err := set.Apply(map[string]any{
+		"items": []any{
+			map[string]any{"status": "active", "price": float64(5)},
+			map[string]any{"status": "inactive", "price": float64(15)},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"items": []any{
+			map[string]any{"status": "active", "price": float64(5), "priority": "high"},
+			map[string]any{"status": "inactive", "price": float64(10)},
+		},
+	}, result)
+}
+
+func TestSet_WildcardAddsMissingKeys(t *testing.T) {
+	set, err := NewSetFromPairs([]string{"items[*].enabled=true", "labels.*.team=core"})
+	require.NoError(t, err)
+
+	result, err := set.Apply(map[string]any{
+		"items":  []any{map[string]any{"id": 1}, "not an object", map[string]any{"id": 2, "enabled": false}},
+		"labels": map[string]any{"a": map[string]any{}, "b": "x"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"items": []any{
+			map[string]any{"id": 1, "enabled": true},
+			"not an object",
+			map[string]any{"id": 2, "enabled": true},
+		},
+		"labels": map[string]any{"a": map[string]any{"team": "core"}, "b": "x"},
+	}, result)
+}
+
+func TestSetAndDelete_EmptyKey(t *testing.T) {
+	set, err := NewSetFromPairs([]string{`m[""]=1`, `n[""][0]=2`})
+	require.NoError(t, err)
+	result, err := set.Apply(map[string]any{"m": map[string]any{"a": 0}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"m": map[string]any{"a": 0, "": float64(1)},
+		"n": map[string]any{"": []any{float64(2)}},
+	}, result)
+
+	result, err = NewDelete([]string{`m[""]`, `n.*[0]`}).Apply(result)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"m": map[string]any{"a": 0}, "n": map[string]any{"": []any{}}}, result)
+}
@@ -30,7 +30,8 @@ func (p *Pipeline) Empty() bool { return len(p.Ops) == 0 }
 
 // Apply runs every operation in order, passing the output of each as the
 // input to the next. If any step fails, it returns a StepError describing
-// which operation failed and why.
+// which operation failed and why. A step that returns Filtered ends the run,
+// so later operations never see the sentinel.
 func (p *Pipeline) Apply(v any) (any, error) {
 	current := v
 	for i, op := range p.Ops {
@@ -43,6 +44,10 @@ func (p *Pipeline) Apply(v any) (any, error) {
 			}
 		}
 
+		if next == Filtered {
+			return Filtered, nil
+		}
+
 		current = next
 	}
 
@@ -105,6 +105,17 @@ func TestPipeline_ApplyChaining(t *testing.T) {
 	assert.Equal(t, expected, result)
 }
 
+func TestPipeline_FilteredStopsPipeline(t *testing.T) {
+	where, err := NewWhere([]string{"name=bob"})
+	require.NoError(t, err)
+	pick := NewPick([]string{"name"}, false)
+	pipe := NewPipeline(where, pick)
+
+	result, err := pipe.Apply(map[string]any{"name": "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, Filtered, result)
+}
+
 func TestPipeline_ErrorInFirstOp(t *testing.T) {
 	pick := NewPick([]string{""}, false) // Invalid path
 	pipe := NewPipeline(pick)
@@ -1,8 +1,12 @@
 package operation
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
+
+	"github.com/GeoffMall/flow/internal/ordered"
 )
 
 // filteredMarker is a sentinel value returned by WHERE when a document doesn't match.
@@ -12,21 +16,53 @@ type filteredMarker struct{}
 // Filtered is the singleton instance of filteredMarker used to indicate filtered documents.
 var Filtered = &filteredMarker{}
 
-// Where filters documents based on key=value conditions.
+// Where filters documents based on field comparisons such as name=Alice or age>=30.
 // If a document doesn't match all conditions, it returns Filtered (which filters it out).
 // All conditions are AND'ed together.
 type Where struct {
 	conditions []whereCondition
 }
 
-// whereCondition represents a single key=value filter condition.
+// whereOperator is the comparison in a where condition.
+type whereOperator string
+
+const (
+	opEqual        whereOperator = "="
+	opNotEqual     whereOperator = "!="
+	opGreater      whereOperator = ">"
+	opGreaterEqual whereOperator = ">="
+	opLess         whereOperator = "<"
+	opLessEqual    whereOperator = "<="
+	opRegex        whereOperator = "~="  // matches a regular expression
+	opRegexFold    whereOperator = "~i=" // case-insensitive ~=
+	opContains     whereOperator = "*="  // contains a substring
+	opContainsFold whereOperator = "*i=" // case-insensitive *=
+	opGlob         whereOperator = "%="  // matches a glob (* and ? wildcards, [...] classes)
+	opGlobFold     whereOperator = "%i=" // case-insensitive %=
+)
+
+// whereOperators are tried in order at each position of a condition, so
+// longer operators win over their prefixes.
+var whereOperators = []whereOperator{
+	opRegexFold, opContainsFold, opGlobFold,
+	opNotEqual, opGreaterEqual, opLessEqual, opRegex, opContains, opGlob,
+	opEqual, opGreater, opLess,
+}
+
+// whereCondition represents a single key<op>value filter condition.
 type whereCondition struct {
-	path  []segment
-	value string
+	path    []segment
+	op      whereOperator
+	value   operand
+	pattern *regexp.Regexp // compiled once for ~=, %= and their case-insensitive forms
+	needle  string         // substring for *= (lower-cased for *i=)
 }
 
-// NewWhere creates a new Where operation from a list of key=value pairs.
-// Example: NewWhere([]string{"user.name=Alice", "status=active"})
+// NewWhere creates a new Where operation from a list of conditions.
+// Each condition is a path, an operator and a value. Comparison operators are
+// =, !=, >, >=, < and <=; match operators are ~= (regex), *= (substring) and
+// %= (glob), with case-insensitive forms ~i=, *i= and %i=.
+// Example: NewWhere([]string{"user.name=Alice", "salary>=80000", "message*i=timeout"})
 func NewWhere(pairs []string) (*Where, error) {
 	if len(pairs) == 0 {
 		return &Where{conditions: nil}, nil
@@ -35,32 +71,133 @@ func NewWhere(pairs []string) (*Where, error) {
 	conditions := make([]whereCondition, 0, len(pairs))
 
 	for _, pair := range pairs {
-		// Parse key=value
-		parts := strings.SplitN(pair, "=", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid where condition '%s': must be in format key=value", pair)
+		condition, err := parseWhereCondition(pair)
+		if err != nil {
+			return nil, fmt.Errorf("invalid where condition '%s': %w", pair, err)
 		}
+		conditions = append(conditions, condition)
+	}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
+	return &Where{conditions: conditions}, nil
+}
 
-		if key == "" {
-			return nil, fmt.Errorf("invalid where condition '%s': key cannot be empty", pair)
-		}
+// parseWhereCondition splits a condition at its first operator.
+func parseWhereCondition(s string) (whereCondition, error) {
+	key, op, value, found := splitOperator(s)
+	if !found {
+		return whereCondition{}, errors.New("must be in format key=value (or key!=value, key>value, key>=value, key<value, key<=value, key~=regex, key*=substring, key%=glob)")
+	}
 
-		// Parse the path
-		path, err := parsePath(key)
-		if err != nil {
-			return nil, fmt.Errorf("invalid where condition '%s': %w", pair, err)
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+
+	if key == "" {
+		return whereCondition{}, errors.New("key cannot be empty")
+	}
+	if value == "" && op != opEqual && op != opNotEqual {
+		return whereCondition{}, fmt.Errorf("value cannot be empty for operator '%s'", op)
+	}
+
+	// Parse the path
+	path, err := parsePath(key)
+	if err != nil {
+		return whereCondition{}, err
+	}
+
+	return newWhereCondition(path, op, value)
+}
+
+// newWhereCondition builds a condition, compiling the pattern for match operators.
+func newWhereCondition(path []segment, op whereOperator, value string) (whereCondition, error) {
+	cond := whereCondition{path: path, op: op, value: newOperand(value)}
+
+	var expr string
+	switch op {
+	case opRegex:
+		expr = value
+	case opRegexFold:
+		expr = "(?i)" + value
+	case opContains:
+		cond.needle = value
+		return cond, nil
+	case opContainsFold:
+		cond.needle = strings.ToLower(value)
+		return cond, nil
+	case opGlob:
+		expr = globToRegexp(value)
+	case opGlobFold:
+		expr = "(?i)" + globToRegexp(value)
+	default:
+		return cond, nil
+	}
+
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return whereCondition{}, fmt.Errorf("invalid pattern for operator '%s': %w", op, err)
+	}
+	cond.pattern = pattern
+	return cond, nil
+}
+
+// globToRegexp translates a glob into an anchored regular expression.
+// * matches any run of characters (including '/'), ? matches one character
+// and [...] is a character class; everything else is literal.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
 		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
 
-		conditions = append(conditions, whereCondition{
-			path:  path,
-			value: value,
-		})
+// operatorAt returns the operator at the start of s, if there is one.
+func operatorAt(s string) (whereOperator, bool) {
+	for _, candidate := range whereOperators {
+		if strings.HasPrefix(s, string(candidate)) {
+			return candidate, true
+		}
 	}
+	return "", false
+}
 
-	return &Where{conditions: conditions}, nil
+// splitOperator finds the leftmost operator in s and returns the text on either
+// side of it. Operator characters in quoted or escaped keys of the path are
+// skipped, and so are wildcard keys: checks.*=ok compares every value of checks.
+func splitOperator(s string) (key string, op whereOperator, value string, found bool) {
+	for i := 0; i < len(s); i++ {
+		if next := skipPathQuoting(s, i); next > i {
+			i = next - 1
+			continue
+		}
+		if wildcardKeyAt(s, i) {
+			continue
+		}
+		if op, ok := operatorAt(s[i:]); ok {
+			return s[:i], op, s[i+len(op):], true
+		}
+	}
+	return "", "", "", false
 }
 
 // Apply filters the document based on all WHERE conditions.
@@ -72,20 +209,15 @@ func (w *Where) Apply(v any) (any, error) {
 		return v, nil
 	}
 
+	doc, err := documentValue(v)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check each condition
 	for _, condition := range w.conditions {
-		// Navigate to the field
-		fieldValue, err := navigatePath(v, condition.path)
-		if err != nil {
-			// Field doesn't exist or path invalid - doesn't match
-			return Filtered, nil
-		}
-
-		// Convert field value to string for comparison
-		fieldStr := fmt.Sprintf("%v", fieldValue)
-
-		// Check if it matches the expected value
-		if fieldStr != condition.value {
+		// A field that doesn't exist or an invalid path doesn't match
+		if !condition.holds(doc) {
 			// Doesn't match - filter out
 			return Filtered, nil
 		}
@@ -95,6 +227,78 @@ func (w *Where) Apply(v any) (any, error) {
 	return v, nil
 }
 
+// matches reports whether a field value satisfies the condition.
+// Equality falls back to comparing the value's string form when the two sides
+// cannot be ordered (e.g. booleans); ordering operators never match such values.
+// Match operators test the string form of scalar values.
+//
+//nolint:cyclop // One case per operator
+func (c whereCondition) matches(fieldValue any) bool {
+	switch {
+	case c.pattern != nil:
+		text, ok := scalarText(fieldValue)
+		return ok && c.pattern.MatchString(text)
+	case c.op == opContains:
+		text, ok := scalarText(fieldValue)
+		return ok && strings.Contains(text, c.needle)
+	case c.op == opContainsFold:
+		text, ok := scalarText(fieldValue)
+		return ok && strings.Contains(strings.ToLower(text), c.needle)
+	}
+
+	result, ok := c.value.compare(fieldValue)
+	if !ok {
+		switch c.op {
+		case opEqual:
+			return fmt.Sprintf("%v", fieldValue) == c.value.raw
+		case opNotEqual:
+			return fmt.Sprintf("%v", fieldValue) != c.value.raw
+		default:
+			return false
+		}
+	}
+
+	switch c.op {
+	case opEqual:
+		return result == 0
+	case opNotEqual:
+		return result != 0
+	case opGreater:
+		return result > 0
+	case opGreaterEqual:
+		return result >= 0
+	case opLess:
+		return result < 0
+	case opLessEqual:
+		return result <= 0
+	}
+	return false
+}
+
+// holds reports whether the condition is true for doc: whether the value at
+// its path, or any of the values for a path with wildcards, matches. A path
+// that resolves to nothing only satisfies !=.
+func (c whereCondition) holds(doc any) bool {
+	if !hasWildcard(c.path) {
+		fieldValue, err := navigatePath(doc, c.path)
+		if err != nil {
+			return c.op == opNotEqual
+		}
+		return c.matches(fieldValue)
+	}
+
+	values := pathValues(doc, c.path)
+	if len(values) == 0 {
+		return c.op == opNotEqual
+	}
+	for _, fieldValue := range values {
+		if c.matches(fieldValue) {
+			return true
+		}
+	}
+	return false
+}
+
 // Description returns a human-readable description of this operation.
 func (w *Where) Description() string {
 	if len(w.conditions) == 0 {
@@ -104,7 +308,7 @@ func (w *Where) Description() string {
 	parts := make([]string, 0, len(w.conditions))
 	for _, cond := range w.conditions {
 		pathStr := pathToString(cond.path)
-		parts = append(parts, fmt.Sprintf("%s=%s", pathStr, cond.value))
+		parts = append(parts, fmt.Sprintf("%s%s%s", pathStr, cond.op, cond.value.raw))
 	}
 
 	return fmt.Sprintf("where: %s", strings.Join(parts, " AND "))
@@ -118,55 +322,82 @@ func navigatePath(v any, path []segment) (any, error) {
 	current := v
 
 	for _, seg := range path {
-		switch c := current.(type) {
-		case map[string]any:
-			val, ok := c[seg.key]
-			if !ok {
-				return nil, fmt.Errorf("field '%s' not found", seg.key)
-			}
-			current = val
+		if seg.slice != nil || seg.filter != nil || seg.anyKeys() {
+			return nil, fmt.Errorf("path segment '%s' selects several values", keyString(seg)+indexString(seg))
+		}
 
-			// Handle array index if specified
-			if seg.idx != nil {
-				arr, ok := current.([]any)
+		if seg.hasKey() {
+			switch c := current.(type) {
+			case map[string]any, *ordered.Map:
+				val, ok := objectGet(c, seg.key)
 				if !ok {
-					return nil, fmt.Errorf("field '%s' is not an array", seg.key)
-				}
-				idx := *seg.idx
-				if idx < 0 || idx >= len(arr) {
-					return nil, fmt.Errorf("index %d out of range for array '%s'", idx, seg.key)
+					return nil, fmt.Errorf("field '%s' not found", seg.key)
 				}
-				current = arr[idx]
+				current = val
+			case []any:
+				return nil, fmt.Errorf("array requires index")
+			default:
+				return nil, fmt.Errorf("cannot navigate through %T", current)
 			}
+		}
 
-		case []any:
-			// If current is an array, we need an index
-			if seg.idx == nil {
-				return nil, fmt.Errorf("array requires index")
+		// Handle array index if specified
+		if seg.idx != nil {
+			arr, ok := current.([]any)
+			if !ok {
+				if !seg.hasKey() {
+					return nil, fmt.Errorf("cannot index %T", current)
+				}
+				return nil, fmt.Errorf("field '%s' is not an array", seg.key)
 			}
-			idx := *seg.idx
-			if idx < 0 || idx >= len(c) {
-				return nil, fmt.Errorf("index %d out of range", idx)
+			idx, ok := resolveIndex(*seg.idx, len(arr))
+			if !ok {
+				return nil, fmt.Errorf("index %d out of range", *seg.idx)
 			}
-			current = c[idx]
-
-		default:
-			return nil, fmt.Errorf("cannot navigate through %T", current)
+			current = arr[idx]
 		}
 	}
 
 	return current, nil
 }
 
-// pathToString converts a path back to string representation for display.
+// pathValues returns the values path selects in v: the single value of a
+// plain path, or every match of a path with wildcards or slices. A path that
+// doesn't resolve selects nothing. Conditions on a path with several values
+// hold if any of them satisfies the condition.
+func pathValues(v any, path []segment) []any {
+	if !hasWildcard(path) {
+		val, err := navigatePath(v, path)
+		if err != nil {
+			return nil
+		}
+		return []any{val}
+	}
+
+	expandedPaths := expandSegments(v, path, nil)
+	values := make([]any, 0, len(expandedPaths))
+	for _, segs := range expandedPaths {
+		if val, err := navigatePath(v, segs); err == nil {
+			values = append(values, val)
+		}
+	}
+	return values
+}
+
+// pathToString converts a path back to string representation, quoting keys
+// where needed so that it parses back to the same path.
 func pathToString(path []segment) string {
-	var parts []string
+	if len(path) == 0 {
+		return rootPath
+	}
+	var s string
 	for _, seg := range path {
-		if seg.idx != nil {
-			parts = append(parts, fmt.Sprintf("%s[%d]", seg.key, *seg.idx))
+		if seg.hasKey() {
+			s = buildPath(s, seg.key)
 		} else {
-			parts = append(parts, seg.key)
+			s = joinPath(s, keyString(seg))
 		}
+		s += indexString(seg)
 	}
-	return strings.Join(parts, ".")
+	return s
 }
@@ -1,7 +1,9 @@
 package operation
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -79,3 +81,355 @@ func TestWhere_Description(t *testing.T) {
 	assert.Contains(t, desc, "age=30")
 	assert.Contains(t, desc, "AND")
 }
+
+func TestWhere_Operators(t *testing.T) {
+	doc := map[string]any{
+		"name":       "Bob",
+		"salary":     float64(85000),
+		"level":      json.Number("3"),
+		"id":         int64(9007199254740993),
+		"active":     true,
+		"created_at": "2024-03-15T10:00:00Z",
+		"updated_at": time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC),
+		"code":       "abc",
+		"count":      "42",
+		"tags":       []any{},
+	}
+
+	tests := []struct {
+		condition string
+		match     bool
+	}{
+		{"salary>=80000", true},
+		{"salary>=85000", true},
+		{"salary>85000", false},
+		{"salary<100000.5", true},
+		{"salary<=84999", false},
+		{"salary!=85000", false},
+		{"salary=85000.0", true},
+		{"level>2", true},
+		{"level<3", false},
+		{"id>9007199254740992", true}, // beyond float64 precision
+		{"name>Alice", true},
+		{"name<Alice", false},
+		{"name!=Alice", true},
+		{"active=true", true},
+		{"active!=true", false},
+		{"active>false", false}, // booleans are not ordered
+		{"salary>abc", false},   // number against non-number
+		{"created_at>2024-01-01T00:00:00Z", true},
+		{"created_at<2024-03-15T11:00:00+01:00", false}, // same instant
+		{"created_at<=2024-03-15T11:00:00+01:00", true},
+		{"updated_at<2024-01-01T00:00:00Z", true},
+		{"code>=80000", false}, // non-numeric string against a number
+		{"code<80000", false},
+		{"code!=80000", true},
+		{"count>9", true}, // numeric strings compare as numbers
+		{"count=42.0", true},
+		{"name>2024-01-01T00:00:00Z", false}, // non-timestamp string against a timestamp
+		{"name<2024-01-01T00:00:00Z", false},
+		{"missing>x", false}, // missing fields only match !=
+		{"missing=x", false},
+		{"missing!=x", true},
+		{"tags[*]!=x", true}, // no values
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			where, err := NewWhere([]string{tt.condition})
+			assert.NoError(t, err)
+
+			result, err := where.Apply(doc)
+			assert.NoError(t, err)
+			if tt.match {
+				assert.Equal(t, doc, result)
+			} else {
+				assert.Equal(t, Filtered, result)
+			}
+		})
+	}
+}
+
+func TestWhere_OperatorParsing(t *testing.T) {
+	where, err := NewWhere([]string{"url=http://x?a=b", "a>=1", "b<=2", "c!=", "d<e>f"})
+	assert.NoError(t, err)
+	assert.Equal(t, "where: url=http://x?a=b AND a>=1 AND b<=2 AND c!= AND d<e>f", where.Description())
+}
+
+func TestWhere_MalformedConditions(t *testing.T) {
+	tests := []struct {
+		condition string
+		wantErr   string
+	}{
+		{"salary", "must be in format key=value"},
+		{">=5", "key cannot be empty"},
+		{"salary>", "value cannot be empty for operator '>'"},
+		{"salary<= ", "value cannot be empty for operator '<='"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			_, err := NewWhere([]string{tt.condition})
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.ErrorContains(t, err, "invalid where condition '"+tt.condition+"'")
+		})
+	}
+}
+
+func TestWhere_MatchOperators(t *testing.T) {
+	doc := map[string]any{
+		"message": "upstream request Timeout after 30s",
+		"path":    "/api/v1/users",
+		"code":    float64(504),
+		"ok":      false,
+		"meta":    map[string]any{"host": "api-3"},
+	}
+
+	tests := []struct {
+		condition string
+		match     bool
+	}{
+		{"message*=Timeout", true},
+		{"message*=timeout", false},
+		{"message*i=timeout", true},
+		{"message~=after \\d+s$", true},
+		{"message~=^timeout", false},
+		{"message~i=time(out)?", true},
+		{"path%=/api/*/users", true},
+		{"path%=/api/v?/users", true},
+		{"path%=/api/v[!0-1]/*", false},
+		{"path%=/API/*", false},
+		{"path%i=/API/*", true},
+		{"code~=^5\\d\\d$", true}, // numbers match by their string form
+		{"code*=0", true},         // numbers match by their string form
+		{"ok%=f*", true},          // booleans too
+		{"meta~=.*", false},       // objects never match
+		{"meta.host%=api-[0-9]", true},
+		{"missing*=x", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			where, err := NewWhere([]string{tt.condition})
+			assert.NoError(t, err)
+
+			result, err := where.Apply(doc)
+			assert.NoError(t, err)
+			if tt.match {
+				assert.Equal(t, doc, result)
+			} else {
+				assert.Equal(t, Filtered, result)
+			}
+		})
+	}
+}
+
+func TestWhere_MatchOperatorErrors(t *testing.T) {
+	_, err := NewWhere([]string{"message~=("})
+	assert.ErrorContains(t, err, "invalid pattern for operator '~='")
+
+	_, err = NewWhere([]string{"message*="})
+	assert.ErrorContains(t, err, "value cannot be empty for operator '*='")
+}
+
+func TestWhere_MatchOperatorParsing(t *testing.T) {
+	where, err := NewWhere([]string{"items[*].id*=7", "a~i=b=c", "url%=http://*"})
+	assert.NoError(t, err)
+	assert.Equal(t, "where: items[*].id*=7 AND a~i=b=c AND url%=http://*", where.Description())
+}
+
+func BenchmarkWhere_Contains(b *testing.B) {
+	where, err := NewWhere([]string{"message*i=timeout"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	doc := map[string]any{"message": "upstream request failed after 30s with status 502"}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_, _ = where.Apply(doc)
+	}
+}
+
+func TestWhere_QuotedKeys(t *testing.T) {
+	doc := map[string]any{
+		"labels": map[string]any{"app.kubernetes.io/name": "web", "a=b": "c"},
+	}
+
+	for _, cond := range []string{
+		`labels["app.kubernetes.io/name"]=web`,
+		`labels.app\.kubernetes\.io/name=web`,
+		`labels["a=b"]=c`,
+		`labels['a=b']!=d`,
+	} {
+		where, err := NewWhere([]string{cond})
+		assert.NoError(t, err, cond)
+		result, err := where.Apply(doc)
+		assert.NoError(t, err)
+		assert.Equal(t, doc, result, cond)
+	}
+
+	where, err := NewWhere([]string{`labels.app\.kubernetes\.io/name=web`})
+	assert.NoError(t, err)
+	assert.Equal(t, `where: labels["app.kubernetes.io/name"]=web`, where.Description())
+}
+
+func TestWhere_NegativeIndexAndSlices(t *testing.T) {
+	doc := map[string]any{
+		"events": []any{
+			map[string]any{"status": "created"},
+			map[string]any{"status": "running"},
+			map[string]any{"status": "failed"},
+		},
+	}
+
+	tests := []struct {
+		cond  string
+		match bool
+	}{
+		{"events[-1].status=failed", true},
+		{"events[-3].status=created", true},
+		{"events[-4].status=created", false},
+		{"events[:2].status=running", true},
+		{"events[:2].status=failed", false},
+		{"events[*].status=failed", true},
+		{"events[::2].status=running", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cond, func(t *testing.T) {
+			where, err := NewWhere([]string{tt.cond})
+			assert.NoError(t, err)
+			result, err := where.Apply(doc)
+			assert.NoError(t, err)
+			if tt.match {
+				assert.Equal(t, doc, result)
+			} else {
+				assert.Equal(t, Filtered, result)
+			}
+		})
+	}
+
+	where, err := NewWhere([]string{"events[-1].status=failed", "events[1:3].status!=x"})
+	assert.NoError(t, err)
+	assert.Equal(t, "where: events[-1].status=failed AND events[1:3].status!=x", where.Description())
+}
+
+func TestWhere_MapWildcardAndRecursiveDescent(t *testing.T) {
+	doc := map[string]any{
+		"checks": map[string]any{"db": "ok", "cache": "failing"},
+		"spec":   map[string]any{"containers": []any{map[string]any{"image": "nginx:1.25"}}},
+	}
+
+	tests := []struct {
+		cond  string
+		match bool
+	}{
+		{"checks.*=failing", true},
+		{"checks.*=degraded", false},
+		{"..image%=nginx:*", true},
+		{"**.image%=redis:*", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cond, func(t *testing.T) {
+			where, err := NewWhere([]string{tt.cond})
+			assert.NoError(t, err)
+			result, err := where.Apply(doc)
+			assert.NoError(t, err)
+			if tt.match {
+				assert.Equal(t, doc, result)
+			} else {
+				assert.Equal(t, Filtered, result)
+			}
+		})
+	}
+
+	where, err := NewWhere([]string{"..image*=nginx", "checks.*=ok"})
+	assert.NoError(t, err)
+	assert.Equal(t, "where: **.image*=nginx AND checks.*=ok", where.Description())
+}
+
+func TestWhere_Filters(t *testing.T) {
+	doc := map[string]any{
+		"items": []any{
+			map[string]any{"sku": "a", "status": "active", "qty": float64(0)},
+			map[string]any{"sku": "b", "status": "backorder", "qty": float64(3)},
+		},
+	}
+
+	tests := []struct {
+		cond  string
+		match bool
+	}{
+		{"items[?status=active].qty=0", true},
+		{"items[?status=active].qty>0", false},
+		{"items[?qty>=1].sku=b", true},
+		{"items[?status!=active].sku=a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cond, func(t *testing.T) {
+			where, err := NewWhere([]string{tt.cond})
+			assert.NoError(t, err)
+			result, err := where.Apply(doc)
+			assert.NoError(t, err)
+			if tt.match {
+				assert.Equal(t, doc, result)
+			} else {
+				assert.Equal(t, Filtered, result)
+			}
+		})
+	}
+
+	where, err := NewWhere([]string{"items[?status=active].qty>0"})
+	assert.NoError(t, err)
+	assert.Equal(t, "where: items[?status=active].qty>0", where.Description())
+}
+
+func TestWhere_RootPaths(t *testing.T) {
+	tests := []struct {
+		cond  string
+		doc   any
+		match bool
+	}{
+		{"[0].status=active", []any{map[string]any{"status": "active"}}, true},
+		{"[-1]>2", []any{float64(1), float64(3)}, true},
+		{"[*].id=2", []any{map[string]any{"id": float64(1)}, map[string]any{"id": float64(2)}}, true},
+		{"[1].status=active", []any{map[string]any{"status": "active"}}, false},
+		{".=hello", "hello", true},
+		{".>10", float64(5), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cond, func(t *testing.T) {
+			where, err := NewWhere([]string{tt.cond})
+			assert.NoError(t, err)
+			result, err := where.Apply(tt.doc)
+			assert.NoError(t, err)
+			if tt.match {
+				assert.Equal(t, tt.doc, result)
+			} else {
+				assert.Equal(t, Filtered, result)
+			}
+		})
+	}
+
+	where, err := NewWhere([]string{".=x"})
+	assert.NoError(t, err)
+	assert.Equal(t, "where: .=x", where.Description())
+}
+
+func TestWhereAndPick_AgreeOnWildcardKeys(t *testing.T) {
+	// Every key a wildcard reaches is looked up directly, whatever it looks like
+	doc := map[string]any{"labels": map[string]any{"": "blank", `a"]["b`: "odd", "a.b": "dotted"}}
+
+	for _, value := range []string{"blank", "odd", "dotted"} {
+		where, err := NewWhere([]string{"labels.*=" + value})
+		assert.NoError(t, err)
+		result, err := where.Apply(doc)
+		assert.NoError(t, err)
+		assert.Equal(t, doc, result, value)
+	}
+
+	result, err := NewPick([]string{"labels.*"}, false).Apply(doc)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"blank", "odd", "dotted"}, result)
+}
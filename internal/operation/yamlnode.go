@@ -0,0 +1,207 @@
+package operation
+
+import (
+	"fmt"
+
+	"github.com/GeoffMall/flow/internal/ordered"
+	"gopkg.in/yaml.v3"
+)
+
+// ----------------------------- YAML edit mode -----------------------------
+//
+// When YAML is both read and written, documents arrive as *yaml.Node trees so
+// that comments, key order, quoting and block/flow style survive. Set and
+// Delete edit the node tree in place; operations that only read a document
+// (Where, Filter, Pick) work on its decoded value instead.
+
+// documentValue returns the plain value of v for operations that read it:
+// a *yaml.Node document is decoded, anything else is returned as is.
+func documentValue(v any) (any, error) {
+	if n, ok := v.(*yaml.Node); ok {
+		return ordered.DecodeYAML(n)
+	}
+	return v, nil
+}
+
+// resolveAlias returns the node an alias points to, or n itself.
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+// documentRoot returns the root content node of a document, adding a null
+// one if the document is empty.
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc.Kind != yaml.DocumentNode {
+		return doc
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{newNullNode()}
+	}
+	return doc.Content[0]
+}
+
+func newMappingNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+func newSequenceNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+}
+
+func newNullNode() *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+}
+
+// mappingValue returns the value node for key in mapping m, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// valueNode encodes a Go value as a node.
+func valueNode(v any) (*yaml.Node, error) {
+	var n yaml.Node
+	if err := n.Encode(v); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// replaceNode overwrites n with repl in place, so aliases to n see the new
+// value. n keeps its comments and anchor, and a scalar keeps its quoting style
+// when the new value has the same type.
+func replaceNode(n, repl *yaml.Node) {
+	repl.HeadComment, repl.LineComment, repl.FootComment = n.HeadComment, n.LineComment, n.FootComment
+	repl.Anchor = n.Anchor
+	if n.Kind == yaml.ScalarNode && repl.Kind == yaml.ScalarNode && n.Tag == repl.Tag {
+		repl.Style = n.Style
+	}
+	*n = *repl
+}
+
+// setNodeAtPath places val at segs below doc, creating mappings and sequences
+// as needed and overwriting values of the wrong kind, like setAtPathOverwrite.
+// Intermediate aliases are followed, so edits below them change the anchored
+// node; a final alias is replaced by the new value.
+func setNodeAtPath(doc *yaml.Node, segs []segment, val any) error {
+	repl, err := valueNode(val)
+	if err != nil {
+		return err
+	}
+
+	cur := documentRoot(doc)
+	for _, s := range segs {
+		if s.hasKey() {
+			cur = childNode(ensureKind(resolveAlias(cur), yaml.MappingNode), s.key)
+		}
+
+		if s.idx != nil {
+			seq := ensureKind(resolveAlias(cur), yaml.SequenceNode)
+			for len(seq.Content) <= *s.idx {
+				seq.Content = append(seq.Content, newNullNode())
+			}
+			cur = seq.Content[*s.idx]
+		}
+	}
+	replaceNode(cur, repl)
+	return nil
+}
+
+// childNode returns the value node for key in mapping m, appending the key
+// with a null value if it is missing.
+func childNode(m *yaml.Node, key string) *yaml.Node {
+	if child := mappingValue(m, key); child != nil {
+		return child
+	}
+	child := newNullNode()
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+	return child
+}
+
+// ensureKind turns n into an empty mapping or sequence unless it already is one.
+func ensureKind(n *yaml.Node, kind yaml.Kind) *yaml.Node {
+	if n.Kind == kind {
+		return n
+	}
+	if kind == yaml.SequenceNode {
+		replaceNode(n, newSequenceNode())
+	} else {
+		replaceNode(n, newMappingNode())
+	}
+	return n
+}
+
+// deleteNodeAtPath removes the node at segs from doc. Missing paths are a
+// no-op, and deleting the root path . leaves a null document.
+func deleteNodeAtPath(doc *yaml.Node, segs []segment) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return
+	}
+	if len(segs) == 0 {
+		replaceNode(doc.Content[0], newNullNode())
+		return
+	}
+
+	cur := resolveAlias(doc.Content[0])
+	for i, s := range segs {
+		isLast := i == len(segs)-1
+
+		if s.hasKey() {
+			if cur.Kind != yaml.MappingNode {
+				return
+			}
+			keyIdx := mappingKeyIndex(cur, s.key)
+			if keyIdx < 0 {
+				return
+			}
+			if s.idx == nil && isLast {
+				cur.Content = append(cur.Content[:keyIdx], cur.Content[keyIdx+2:]...)
+				return
+			}
+			cur = resolveAlias(cur.Content[keyIdx+1])
+		}
+		if s.idx == nil {
+			continue
+		}
+
+		if cur.Kind != yaml.SequenceNode || *s.idx < 0 || *s.idx >= len(cur.Content) {
+			return
+		}
+		if isLast {
+			cur.Content = append(cur.Content[:*s.idx], cur.Content[*s.idx+1:]...)
+			return
+		}
+		cur = resolveAlias(cur.Content[*s.idx])
+	}
+}
+
+// mappingKeyIndex returns the index in m.Content of key's key node, or -1.
+func mappingKeyIndex(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// concretePaths expands wildcards in path against the decoded value of doc,
+// with expandWildcardPaths for Delete or assignPaths for Set.
+func concretePaths(doc *yaml.Node, path string, expand func(v any, path string) ([][]segment, error)) ([][]segment, error) {
+	plain, err := ordered.DecodeYAML(doc)
+	if err != nil {
+		return nil, err
+	}
+	expanded, err := expand(plain, path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", path, err)
+	}
+	return expanded, nil
+}
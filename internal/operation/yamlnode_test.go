@@ -0,0 +1,241 @@
+package operation
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const valuesYAML = `# Default values for web.
+replicaCount: 1 # bump for production
+
+image:
+  repository: "nginx"
+  tag: '1.25'
+  pullPolicy: IfNotPresent
+
+ports: [80, 443]
+
+defaults: &defaults
+  timeout: 30
+service:
+  <<: *defaults
+  name: web
+
+# Debug settings
+debug:
+  enabled: true
+`
+
+// decodeNode parses src into a document node like the YAML node parser does.
+func decodeNode(t *testing.T, src string) *yaml.Node {
+	t.Helper()
+	var doc yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte(src), &doc))
+	return &doc
+}
+
+// encodeNode writes doc back out like the YAML formatter does.
+func encodeNode(t *testing.T, doc any) string {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	require.NoError(t, enc.Encode(doc))
+	require.NoError(t, enc.Close())
+	return buf.String()
+}
+
+func TestSet_YAMLNode_PreservesUntouchedParts(t *testing.T) {
+	doc := decodeNode(t, valuesYAML)
+	set, err := NewSetFromPairs([]string{"image.tag=v1.26", "replicaCount=3"})
+	require.NoError(t, err)
+
+	out, err := set.Apply(doc)
+	require.NoError(t, err)
+	assert.Same(t, doc, out)
+
+	got := encodeNode(t, out)
+	assert.Contains(t, got, "# Default values for web.")
+	assert.Contains(t, got, "replicaCount: 3 # bump for production")
+	assert.Contains(t, got, `repository: "nginx"`)
+	assert.Contains(t, got, "tag: 'v1.26'")
+	assert.Contains(t, got, "ports: [80, 443]")
+	assert.Contains(t, got, "defaults: &defaults")
+	assert.Contains(t, got, "<<: *defaults")
+	assert.Contains(t, got, "# Debug settings")
+}
+
+func TestSet_YAMLNode_NewKeysAndTypeChange(t *testing.T) {
+	doc := decodeNode(t, "a: 1 # keep\n")
+	set, err := NewSetFromPairs([]string{"b.c=x", "items[1]=true", "a=\"text\""})
+	require.NoError(t, err)
+
+	out, err := set.Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, "a: text # keep\nb:\n  c: x\nitems:\n  - null\n  - true\n", encodeNode(t, out))
+}
+
+func TestSet_YAMLNode_Wildcard(t *testing.T) {
+	doc := decodeNode(t, "items:\n  - name: a # first\n  - name: b\n")
+	set, err := NewSetFromPairs([]string{"items[*].name=z"})
+	require.NoError(t, err)
+
+	out, err := set.Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, "items:\n  - name: z # first\n  - name: z\n", encodeNode(t, out))
+}
+
+func TestSet_YAMLNode_EditThroughAlias(t *testing.T) {
+	doc := decodeNode(t, "base: &b\n  x: 1\nother: *b\n")
+	set, err := NewSetFromPairs([]string{"other.x=2"})
+	require.NoError(t, err)
+
+	out, err := set.Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, "base: &b\n  x: 2\nother: *b\n", encodeNode(t, out))
+}
+
+func TestSet_YAMLNode_EmptyDocument(t *testing.T) {
+	doc := &yaml.Node{Kind: yaml.DocumentNode}
+	set, err := NewSetFromPairs([]string{"a=1"})
+	require.NoError(t, err)
+
+	out, err := set.Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, "a: 1\n", encodeNode(t, out))
+}
+
+func TestDelete_YAMLNode(t *testing.T) {
+	doc := decodeNode(t, valuesYAML)
+	del := NewDelete([]string{"debug", "image.pullPolicy", "ports[0]", "missing.key"})
+
+	out, err := del.Apply(doc)
+	require.NoError(t, err)
+
+	got := encodeNode(t, out)
+	assert.NotContains(t, got, "debug")
+	assert.NotContains(t, got, "pullPolicy")
+	assert.Contains(t, got, "ports: [443]")
+	assert.Contains(t, got, "replicaCount: 1 # bump for production")
+}
+
+func TestDelete_YAMLNode_Wildcard(t *testing.T) {
+	doc := decodeNode(t, "items:\n  - {id: 1, tmp: x}\n  - {id: 2, tmp: y}\n")
+	del := NewDelete([]string{"items[*].tmp"})
+
+	out, err := del.Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, "items:\n  - {id: 1}\n  - {id: 2}\n", encodeNode(t, out))
+}
+
+func TestWhereAndFilter_YAMLNode_ReturnDocument(t *testing.T) {
+	doc := decodeNode(t, valuesYAML)
+
+	where, err := NewWhere([]string{"replicaCount=1", "service.timeout>=30"})
+	require.NoError(t, err)
+	out, err := where.Apply(doc)
+	require.NoError(t, err)
+	assert.Same(t, doc, out)
+
+	filter, err := NewFilter("image.repository=nginx and not debug.enabled=false")
+	require.NoError(t, err)
+	out, err = filter.Apply(doc)
+	require.NoError(t, err)
+	assert.Same(t, doc, out)
+
+	filter, err = NewFilter("image.repository=redis")
+	require.NoError(t, err)
+	out, err = filter.Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, Filtered, out)
+}
+
+func TestPick_YAMLNode(t *testing.T) {
+	doc := decodeNode(t, valuesYAML)
+	pick := NewPick([]string{"image.tag"}, false)
+
+	out, err := pick.Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, "1.25", out)
+}
+
+func TestSetAndDelete_YAMLNode_QuotedKeys(t *testing.T) {
+	doc := decodeNode(t, "labels:\n  app.kubernetes.io/name: web # name\n  tier: frontend\n")
+	set, err := NewSetFromPairs([]string{`labels["app.kubernetes.io/name"]=api`})
+	require.NoError(t, err)
+
+	out, err := set.Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, "labels:\n  app.kubernetes.io/name: api # name\n  tier: frontend\n", encodeNode(t, out))
+
+	out, err = NewDelete([]string{`labels.app\.kubernetes\.io/name`}).Apply(out)
+	require.NoError(t, err)
+	assert.Equal(t, "labels:\n  tier: frontend\n", encodeNode(t, out))
+}
+
+func TestSetAndDelete_YAMLNode_NegativeIndexAndSlices(t *testing.T) {
+	doc := decodeNode(t, "ports: [80, 443, 8080, 9090]\n")
+	set, err := NewSetFromPairs([]string{"ports[-1]=9091", "ports[:1]=81"})
+	require.NoError(t, err)
+
+	out, err := set.Apply(doc)
+	require.NoError(t, err)
+	assert.Equal(t, "ports: [81, 443, 8080, 9091]\n", encodeNode(t, out))
+
+	out, err = NewDelete([]string{"ports[1:3]"}).Apply(out)
+	require.NoError(t, err)
+	assert.Equal(t, "ports: [81, 9091]\n", encodeNode(t, out))
+}
+
+func TestSetAndDelete_YAMLNode_RecursiveDescent(t *testing.T) {
+	doc := decodeNode(t, "spec:\n  containers:\n    - image: app:1 # main\n    - image: proxy:1\n  debug: {a: 1, b: 2}\n")
+	set, err := NewSetFromPairs([]string{"..image=mirror/app:2"})
+	require.NoError(t, err)
+
+	out, err := set.Apply(doc)
+	require.NoError(t, err)
+
+	out, err = NewDelete([]string{"spec.debug.*"}).Apply(out)
+	require.NoError(t, err)
+	assert.Equal(t, "spec:\n  containers:\n    - image: mirror/app:2 # main\n    - image: mirror/app:2\n  debug: {}\n", encodeNode(t, out))
+}
+
+func TestSetAndDelete_YAMLNode_Filters(t *testing.T) {
+	doc := decodeNode(t, "containers:\n  - name: app # main\n    image: app:1\n  - name: sidecar\n    image: proxy:1\n")
+	set, err := NewSetFromPairs([]string{"containers[?name=app].image=app:2", "containers[?name=app].pullPolicy=Always"})
+	require.NoError(t, err)
+
+	out, err := set.Apply(doc)
+	require.NoError(t, err)
+
+	out, err = NewDelete([]string{"containers[?name=sidecar]"}).Apply(out)
+	require.NoError(t, err)
+	assert.Equal(t, "containers:\n  - name: app # main\n    image: app:2\n    pullPolicy: Always\n", encodeNode(t, out))
+}
+
+func TestSetAndDelete_YAMLNode_RootPaths(t *testing.T) {
+	doc := decodeNode(t, "- name: a # first\n  v: 1\n- name: b\n- name: c\n")
+	set, err := NewSetFromPairs([]string{"[0].v=2", "[*].ok=true", "[-1].tags[0]=x"})
+	require.NoError(t, err)
+
+	out, err := set.Apply(doc)
+	require.NoError(t, err)
+
+	out, err = NewDelete([]string{"[1]", "[?name=c].ok"}).Apply(out)
+	require.NoError(t, err)
+	assert.Equal(t, "- name: a # first\n  v: 2\n  ok: true\n- name: c\n  tags:\n    - x\n", encodeNode(t, out))
+
+	set, err = NewSetFromPairs([]string{".=[1, 2]"})
+	require.NoError(t, err)
+	out, err = set.Apply(out)
+	require.NoError(t, err)
+	assert.Equal(t, "- 1\n- 2\n", encodeNode(t, out))
+
+	out, err = NewDelete([]string{"."}).Apply(out)
+	require.NoError(t, err)
+	assert.Equal(t, "null\n", encodeNode(t, out))
+}
@@ -0,0 +1,382 @@
+// Package ordered provides an object type that keeps its keys in insertion
+// order, so documents can be written back out in the order they were read.
+package ordered
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Map is a string-keyed object that remembers the order its keys were added
+// in. Setting an existing key keeps its position; new keys go at the end.
+// The zero value is not usable; create maps with NewMap.
+type Map struct {
+	keys   []string
+	values map[string]any
+}
+
+// NewMap returns an empty Map.
+func NewMap() *Map {
+	return &Map{values: make(map[string]any)}
+}
+
+// Len returns the number of keys in m.
+func (m *Map) Len() int { return len(m.keys) }
+
+// Keys returns a copy of m's keys in order.
+func (m *Map) Keys() []string { return slices.Clone(m.keys) }
+
+// Get returns the value stored under key and whether it was present.
+func (m *Map) Get(key string) (any, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+// Set stores value under key, appending key if it is new.
+func (m *Map) Set(key string, value any) {
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Delete removes key from m. It is a no-op if key is not present.
+func (m *Map) Delete(key string) {
+	if _, exists := m.values[key]; !exists {
+		return
+	}
+	delete(m.values, key)
+	m.keys = slices.DeleteFunc(m.keys, func(k string) bool { return k == key })
+}
+
+// All iterates over the key/value pairs of m in order. The map must not be
+// modified during iteration.
+func (m *Map) All() iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		for _, k := range m.keys {
+			if !yield(k, m.values[k]) {
+				return
+			}
+		}
+	}
+}
+
+// MarshalJSON encodes m as a JSON object with its keys in order.
+// HTML characters are left as is; the calling encoder escapes them if it is
+// configured to.
+func (m *Map) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	buf.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := enc.Encode(k); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(':')
+		if err := enc.Encode(m.values[k]); err != nil {
+			return nil, fmt.Errorf("key %q: %w", k, err)
+		}
+	}
+	buf.WriteByte('}')
+
+	// Encoder.Encode terminates every value with a newline; JSON allows the
+	// whitespace, and the calling encoder compacts it away.
+	return buf.Bytes(), nil
+}
+
+// MarshalYAML encodes m as a YAML mapping with its keys in order.
+func (m *Map) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, k := range m.keys {
+		var key, value yaml.Node
+		if err := key.Encode(k); err != nil {
+			return nil, err
+		}
+		if err := value.Encode(m.values[k]); err != nil {
+			return nil, fmt.Errorf("key %q: %w", k, err)
+		}
+		node.Content = append(node.Content, &key, &value)
+	}
+	return node, nil
+}
+
+// DecodeJSON decodes a single JSON value, producing *Map for objects, []any
+// for arrays, float64 for numbers and the usual Go types for other scalars.
+// It is the order-preserving equivalent of json.Unmarshal into an any.
+func DecodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	v, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("invalid character after top-level value")
+	}
+	return v, nil
+}
+
+func decodeValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		m := NewMap()
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyTok.(string) // the decoder guarantees object keys are strings
+			val, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			m.Set(key, val)
+		}
+		_, err := dec.Token() // '}'
+		return m, err
+	case json.Delim('['):
+		arr := []any{}
+		for dec.More() {
+			val, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		_, err := dec.Token() // ']'
+		return arr, err
+	default:
+		return tok, nil
+	}
+}
+
+// ToPlain returns v with every *Map replaced by an equivalent map[string]any,
+// recursively, for consumers that don't care about key order. Arrays are
+// copied; other values are returned as is.
+func ToPlain(v any) any {
+	switch vv := v.(type) {
+	case *Map:
+		out := make(map[string]any, vv.Len())
+		for k, val := range vv.All() {
+			out[k] = ToPlain(val)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(vv))
+		for k, val := range vv {
+			out[k] = ToPlain(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(vv))
+		for i, val := range vv {
+			out[i] = ToPlain(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// errExcessiveAliasing is yaml.v3's error for a document whose aliases expand
+// to far more than it contains, such as a "billion laughs" document.
+var errExcessiveAliasing = errors.New("yaml: document contains excessive aliasing")
+
+// DecodeYAML converts a yaml.v3 node tree into JSON-compatible Go types:
+//   - mapping nodes  -> *Map (keys in document order, merge keys applied)
+//   - sequence nodes -> []any
+//   - scalar nodes   -> the value yaml.v3 resolves them to (string, int, float64, bool, nil, ...)
+//   - aliases        -> the value of the anchored node
+//
+// Aliases are expanded with the same limit yaml.v3 applies when it decodes,
+// so a document that expands exponentially is rejected instead of decoded.
+//
+// It is the YAML counterpart of DecodeJSON.
+func DecodeYAML(n *yaml.Node) (any, error) {
+	var d yamlDecoder
+	return d.decode(n)
+}
+
+// yamlDecoder counts the nodes it decodes, and how many of them were reached
+// through an alias, to detect excessive aliasing as yaml.v3 does.
+type yamlDecoder struct {
+	decodeCount int
+	aliasCount  int
+	aliasDepth  int
+}
+
+// allowedAliasRatio mirrors yaml.v3: the share of decoded nodes that may come
+// from aliases, which shrinks from 99% to 10% as documents grow.
+func allowedAliasRatio(decodeCount int) float64 {
+	switch {
+	case decodeCount <= 400_000:
+		return 0.99
+	case decodeCount >= 4_000_000:
+		return 0.10
+	default:
+		return 0.99 - 0.89*(float64(decodeCount-400_000)/3_600_000)
+	}
+}
+
+// count records that a node is being decoded, failing once aliases account for
+// too much of the document.
+func (d *yamlDecoder) count() error {
+	d.decodeCount++
+	if d.aliasDepth > 0 {
+		d.aliasCount++
+	}
+	if d.aliasCount > 100 && d.decodeCount > 1000 &&
+		float64(d.aliasCount)/float64(d.decodeCount) > allowedAliasRatio(d.decodeCount) {
+		return errExcessiveAliasing
+	}
+	return nil
+}
+
+// alias decodes the node an alias points to.
+func (d *yamlDecoder) alias(n *yaml.Node) (any, error) {
+	d.aliasDepth++
+	defer func() { d.aliasDepth-- }()
+	return d.decode(n.Alias)
+}
+
+func (d *yamlDecoder) decode(n *yaml.Node) (any, error) {
+	if err := d.count(); err != nil {
+		return nil, err
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return d.decode(n.Content[0])
+
+	case yaml.MappingNode:
+		out := NewMap()
+		if err := d.mergeMapping(out, n); err != nil {
+			return nil, err
+		}
+		return out, nil
+
+	case yaml.SequenceNode:
+		out := make([]any, 0, len(n.Content))
+		for _, item := range n.Content {
+			v, err := d.decode(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+
+	case yaml.AliasNode:
+		return d.alias(n)
+
+	default:
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// mergeMapping adds the key/value pairs of mapping node n to out.
+// Keys written in n take precedence over keys pulled in with a << merge key,
+// as in the YAML merge key spec.
+func (d *yamlDecoder) mergeMapping(out *Map, n *yaml.Node) error {
+	var merges []*yaml.Node
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		keyNode, valNode := n.Content[i], n.Content[i+1]
+		if keyNode.Tag == "!!merge" {
+			merges = append(merges, valNode)
+			continue
+		}
+
+		key, err := d.decode(keyNode)
+		if err != nil {
+			return err
+		}
+		val, err := d.decode(valNode)
+		if err != nil {
+			return err
+		}
+		out.Set(toStringKey(key), val)
+	}
+
+	for _, m := range merges {
+		if err := d.mergeInto(out, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeInto applies the value of a << merge key: a mapping (or alias to
+// one), or a sequence of them, whose keys are added where not already set.
+func (d *yamlDecoder) mergeInto(out *Map, n *yaml.Node) error {
+	if n.Kind == yaml.AliasNode {
+		d.aliasDepth++
+		defer func() { d.aliasDepth-- }()
+		n = n.Alias
+	}
+	if err := d.count(); err != nil {
+		return err
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		src := NewMap()
+		if err := d.mergeMapping(src, n); err != nil {
+			return err
+		}
+		for k, v := range src.All() {
+			if _, exists := out.Get(k); !exists {
+				out.Set(k, v)
+			}
+		}
+		return nil
+	case yaml.SequenceNode:
+		for _, item := range n.Content {
+			if err := d.mergeInto(out, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("line %d: merge key value must be a mapping or a list of mappings", n.Line)
+	}
+}
+
+// toStringKey converts any key type to a string for map keys.
+// This handles YAML's more flexible key types (numbers, booleans, etc.).
+func toStringKey(k any) string {
+	switch t := k.(type) {
+	case string:
+		return t
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(t)
+	}
+}
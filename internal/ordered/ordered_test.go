@@ -0,0 +1,109 @@
+package ordered
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMap_SetGetDelete(t *testing.T) {
+	m := NewMap()
+	m.Set("b", 1)
+	m.Set("a", 2)
+	m.Set("c", 3)
+	m.Set("b", 4) // existing key keeps its position
+
+	assert.Equal(t, []string{"b", "a", "c"}, m.Keys())
+	assert.Equal(t, 3, m.Len())
+
+	v, ok := m.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, 4, v)
+
+	m.Delete("a")
+	m.Delete("missing")
+	assert.Equal(t, []string{"b", "c"}, m.Keys())
+	_, ok = m.Get("a")
+	assert.False(t, ok)
+
+	m.Set("a", 5) // re-added keys go to the end
+	assert.Equal(t, []string{"b", "c", "a"}, m.Keys())
+}
+
+func TestMap_All(t *testing.T) {
+	m := NewMap()
+	m.Set("x", 1)
+	m.Set("y", 2)
+	m.Set("z", 3)
+
+	var keys []string
+	for k := range m.All() {
+		if k == "z" {
+			break
+		}
+		keys = append(keys, k)
+	}
+	assert.Equal(t, []string{"x", "y"}, keys)
+}
+
+func TestDecodeJSON(t *testing.T) {
+	v, err := DecodeJSON([]byte(`{"z": 1, "a": [true, null, {"k": "v", "b": 2.5}], "m": {}}`))
+	assert.NoError(t, err)
+
+	m, ok := v.(*Map)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"z", "a", "m"}, m.Keys())
+
+	arr, _ := m.Get("a")
+	assert.Equal(t, true, arr.([]any)[0])
+	assert.Nil(t, arr.([]any)[1])
+	assert.Equal(t, []string{"k", "b"}, arr.([]any)[2].(*Map).Keys())
+
+	z, _ := m.Get("z")
+	assert.Equal(t, float64(1), z)
+
+	assert.Equal(t, map[string]any{
+		"z": float64(1),
+		"a": []any{true, nil, map[string]any{"k": "v", "b": 2.5}},
+		"m": map[string]any{},
+	}, ToPlain(v))
+}
+
+func TestDecodeJSON_Errors(t *testing.T) {
+	for _, input := range []string{`{"a": 1`, `{"a" 1}`, `[1, 2`, `{} {}`, ``} {
+		_, err := DecodeJSON([]byte(input))
+		assert.Error(t, err, input)
+	}
+}
+
+func TestMap_MarshalJSON(t *testing.T) {
+	inner := NewMap()
+	inner.Set("y", "<b>")
+	inner.Set("x", []any{1, 2})
+
+	m := NewMap()
+	m.Set("name", "app")
+	m.Set("inner", inner)
+	m.Set("empty", NewMap())
+
+	// json.Marshal escapes HTML by default, and applies that to Marshaler output too
+	b, err := json.Marshal(m)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"app","inner":{"y":"\u003cb\u003e","x":[1,2]},"empty":{}}`, string(b))
+}
+
+func TestMap_MarshalYAML(t *testing.T) {
+	inner := NewMap()
+	inner.Set("y", 1)
+	inner.Set("x", "two")
+
+	m := NewMap()
+	m.Set("name", "app")
+	m.Set("inner", inner)
+
+	b, err := yaml.Marshal(m)
+	assert.NoError(t, err)
+	assert.Equal(t, "name: app\ninner:\n    \"y\": 1\n    x: two\n", string(b))
+}
@@ -1,15 +1,17 @@
 package runner
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
-	"strings"
+	"unicode/utf8"
 
 	"github.com/GeoffMall/flow/internal/cli"
+	"github.com/GeoffMall/flow/internal/compression"
 	"github.com/GeoffMall/flow/internal/format"
 	_ "github.com/GeoffMall/flow/internal/format/avro"    // Register Avro format
+	_ "github.com/GeoffMall/flow/internal/format/csv"     // Register CSV and TSV formats
 	_ "github.com/GeoffMall/flow/internal/format/json"    // Register JSON format
 	_ "github.com/GeoffMall/flow/internal/format/parquet" // Register Parquet format
 	_ "github.com/GeoffMall/flow/internal/format/yaml"    // Register YAML format
@@ -21,6 +23,14 @@ func Run() {
 	// Enable color by default unless --no-color is specified
 	f.Color = !f.NoColor
 
+	// Handle in-place editing of -in or -in-dir files
+	if f.InPlace {
+		if err := processInPlace(f); err != nil {
+			fatalf("In-place editing error: %v\n", err)
+		}
+		return
+	}
+
 	// Handle directory mode
 	if f.InputDir != "" {
 		if err := processDirectory(f); err != nil {
@@ -29,24 +39,62 @@ func Run() {
 		return
 	}
 
-	// Handle single file/stdin mode
+	// Handle single file/stdin mode, detecting the file's format for -from auto
+	if f.InputFile != "" {
+		resolved, err := resolveAutoFormat(f, f.InputFile)
+		if err != nil {
+			fatalf("Error opening input: %v\n", err)
+		}
+		f = resolved
+	}
+
 	in, inClose, err := openInput(f.InputFile)
 	if err != nil {
 		fatalf("Error opening input: %v\n", err)
 	}
 	defer inClose()
 
-	out, outClose, err := openOutput(f.OutputFile)
+	// Stdin has no name to go by, so decompress it and detect its format
+	// from the content
+	if f.InputFile == "" {
+		if in, _, err = compression.NewReader(in); err != nil {
+			fatalf("Error reading input: %v\n", err)
+		}
+		if f.FromFormat == "" || f.FromFormat == autoFormat {
+			if in, f.FromFormat, err = sniffInput(in); err != nil {
+				fatalf("Error reading input: %v\n", err)
+			}
+		}
+	}
+
+	codec, err := outputCodec(f)
 	if err != nil {
 		fatalf("Error opening output: %v\n", err)
 	}
-	defer outClose()
+	out, outClose, err := openOutput(f.OutputFile, codec)
+	if err != nil {
+		fatalf("Error opening output: %v\n", err)
+	}
+
+	if f.SchemaMode {
+		err = runSchema(in, out, f)
+		closeOutput(outClose, &err)
+		if err != nil {
+			fatalf("Schema error: %v\n", err)
+		}
+		return
+	}
 
-	if err := run(in, out, f); err != nil {
+	err = run(in, out, f)
+	closeOutput(outClose, &err)
+	if err != nil {
 		fatalf("Processing error: %v\n", err)
 	}
 }
 
+// openInput opens the input file, decompressing it if it is compressed, or
+// returns stdin if path is empty. Stdin is left as is, since looking at it
+// blocks until input arrives (Run decompresses it when it starts reading).
 func openInput(path string) (io.Reader, func(), error) {
 	if path == "" {
 		return os.Stdin, func() {}, nil
@@ -56,118 +104,161 @@ func openInput(path string) (io.Reader, func(), error) {
 	if err != nil {
 		return nil, func() {}, err
 	}
-	return f, func() { _ = f.Close() }, nil
+
+	r, _, err := compression.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, func() {}, err
+	}
+	return r, func() { _ = f.Close() }, nil
 }
 
-func openOutput(path string) (io.Writer, func(), error) {
-	if path == "" {
-		return os.Stdout, func() {}, nil
+// openOutput creates the output file, or uses stdout if path is empty, and
+// compresses what is written to it with codec unless codec is nil. The
+// returned function flushes the compressed stream and closes the file; its
+// error means the output is incomplete.
+func openOutput(path string, codec *compression.Codec) (io.Writer, func() error, error) {
+	var out io.Writer = os.Stdout
+	closeOut := func() error { return nil }
+	if path != "" {
+		// #nosec G304 - CLI tool trusts user-provided file paths
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, closeOut, err
+		}
+		out, closeOut = f, f.Close
 	}
-	// #nosec G304 - CLI tool trusts user-provided file paths
-	f, err := os.Create(path)
+
+	if codec == nil {
+		return out, closeOut, nil
+	}
+	cw, err := codec.NewWriter(out)
 	if err != nil {
-		return nil, func() {}, err
+		_ = closeOut()
+		return nil, func() error { return nil }, err
+	}
+	return cw, func() error {
+		return errors.Join(cw.Close(), closeOut())
+	}, nil
+}
+
+// outputCodec returns the codec for compressing output: the -compress codec,
+// or the one named by the -out file's extension (e.g. .gz). It is nil for
+// uncompressed output.
+func outputCodec(opts *cli.Flags) (*compression.Codec, error) {
+	switch opts.Compress {
+	case "none":
+		return nil, nil
+	case "":
+		return compression.ForPath(opts.OutputFile), nil
+	default:
+		return compression.Get(opts.Compress)
 	}
-	return f, func() { _ = f.Close() }, nil
 }
 
 // processDirectory processes all files in a directory that match the specified format.
-// It walks the directory tree, filters files by extension, and processes each matching file.
+// It walks the directory tree, selects files by extension and the -include/-exclude
+// patterns (see fileSelector), and processes each selected file.
 // Errors are collected and reported at the end (continue-on-error behavior).
-//
-//nolint:cyclop,funlen // Directory walking requires multiple error checks and comprehensive handling
-func processDirectory(opts *cli.Flags) error {
-	// Determine which extensions to process based on -from flag
-	var extensions []string
-	switch opts.FromFormat {
-	case "avro":
-		extensions = []string{".avro"}
-	case "parquet":
-		extensions = []string{".parquet"}
-	case "yaml":
-		extensions = []string{".yaml", ".yml"}
-	case "json", "":
-		extensions = []string{".json"}
-	default:
-		return fmt.Errorf("unknown format for directory processing: %s", opts.FromFormat)
+func processDirectory(opts *cli.Flags) (err error) {
+	selector, err := newFileSelector(opts)
+	if err != nil {
+		return err
+	}
+
+	pipe, err := buildPipeline(opts)
+	if err != nil {
+		return err
 	}
 
 	// Open output once for all files
-	out, outClose, err := openOutput(opts.OutputFile)
+	codec, err := outputCodec(opts)
+	if err != nil {
+		return err
+	}
+	out, outClose, err := openOutput(opts.OutputFile, codec)
 	if err != nil {
 		return fmt.Errorf("failed to open output: %w", err)
 	}
-	defer outClose()
+	defer closeOutput(outClose, &err)
 
-	// Collect errors from processing
-	var errors []error
-	fileCount := 0
+	// Share one formatter across files so container formats (e.g. Avro)
+	// produce a single valid output
+	formatter, err := newFormatter(out, opts)
+	if err != nil {
+		return err
+	}
 
-	// Walk the directory
-	err = filepath.WalkDir(opts.InputDir, func(path string, d os.DirEntry, err error) error {
-		if err != nil {
-			errors = append(errors, fmt.Errorf("error accessing %s: %w", path, err))
-			return nil // Continue processing other files
-		}
+	files, errors, err := selector.findFiles(opts.InputDir)
+	errors = append(errors, processFiles(files, opts, formatter, pipe)...)
 
-		// Skip directories
-		if d.IsDir() {
-			return nil
-		}
+	if cerr := formatter.Close(); cerr != nil {
+		errors = append(errors, fmt.Errorf("failed to finalize output: %w", cerr))
+	}
 
-		// Check if file extension matches
-		ext := strings.ToLower(filepath.Ext(path))
-		matched := false
-		for _, allowedExt := range extensions {
-			if ext == allowedExt {
-				matched = true
-				break
-			}
-		}
-		if !matched {
-			return nil // Skip files that don't match
-		}
+	if err != nil {
+		return fmt.Errorf("error walking directory: %w", err)
+	}
 
-		fileCount++
+	if len(files) == 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "Warning: no files with extensions %v found in %s\n", selector.extensions, opts.InputDir)
+	}
 
-		// Process this file
-		// #nosec G304 - CLI tool processes user-specified directory paths
-		file, err := os.Open(path)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("failed to open %s: %w", path, err))
-			return nil // Continue processing other files
-		}
-		defer file.Close()
+	return reportErrors(errors)
+}
 
-		// Process the file with metadata (filename and row tracking)
-		if err := runWithMetadata(file, out, opts, path); err != nil {
-			errors = append(errors, fmt.Errorf("failed to process %s: %w", path, err))
-			return nil // Continue processing other files
-		}
+// processFileWithMetadata opens path and streams its documents to write
+// wrapped with metadata (see processWithMetadata).
+func processFileWithMetadata(path string, opts *cli.Flags, write func(any) error, pipe *operation.Pipeline) error {
+	fileOpts, err := resolveAutoFormat(opts, path)
+	if err != nil {
+		return err
+	}
 
-		return nil
-	})
+	// Mixed-format runs record each file's detected format
+	formatName := ""
+	if opts.FromFormat == autoFormat {
+		formatName = fileOpts.FromFormat
+	}
 
+	// #nosec G304 - CLI tool processes user-specified directory paths
+	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("error walking directory: %w", err)
+		return fmt.Errorf("failed to open %s: %w", path, err)
 	}
+	defer file.Close()
 
-	if fileCount == 0 {
-		_, _ = fmt.Fprintf(os.Stderr, "Warning: no files with extensions %v found in %s\n", extensions, opts.InputDir)
+	in, _, err := compression.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
 	}
 
-	// Report collected errors
-	if len(errors) > 0 {
-		_, _ = fmt.Fprintf(os.Stderr, "\nEncountered %d error(s) during processing:\n", len(errors))
-		for i, e := range errors {
-			_, _ = fmt.Fprintf(os.Stderr, "  %d. %v\n", i+1, e)
-		}
-		return fmt.Errorf("directory processing completed with %d error(s)", len(errors))
+	parser, err := newParser(in, fileOpts, pipe)
+	if err != nil {
+		return fmt.Errorf("failed to process %s: %w", path, err)
 	}
+	defer closeParser(parser)
 
+	// Process the file with metadata (filename and row tracking)
+	if err := processWithMetadata(parser, write, pipe, path, formatName); err != nil {
+		return fmt.Errorf("failed to process %s: %w", path, err)
+	}
 	return nil
 }
 
+// reportErrors prints errors collected while processing several files to
+// stderr and returns a summary error, or nil if there are none.
+func reportErrors(errors []error) error {
+	if len(errors) == 0 {
+		return nil
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "\nEncountered %d error(s) during processing:\n", len(errors))
+	for i, e := range errors {
+		_, _ = fmt.Fprintf(os.Stderr, "  %d. %v\n", i+1, e)
+	}
+	return fmt.Errorf("directory processing completed with %d error(s)", len(errors))
+}
+
 func buildPipeline(opts *cli.Flags) (*operation.Pipeline, error) {
 	var ops []operation.Operation
 
@@ -180,6 +271,14 @@ func buildPipeline(opts *cli.Flags) (*operation.Pipeline, error) {
 		ops = append(ops, whereOp)
 	}
 
+	if opts.FilterExpr != "" {
+		filterOp, err := operation.NewFilter(opts.FilterExpr)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, filterOp)
+	}
+
 	if len(opts.PickPaths) > 0 {
 		ops = append(ops, operation.NewPick(opts.PickPaths, opts.PreserveHierarchy))
 	}
@@ -209,15 +308,8 @@ func determineInputFormat(opts *cli.Flags) string {
 
 	// If reading from a file, check extension
 	if opts.InputFile != "" {
-		ext := strings.ToLower(filepath.Ext(opts.InputFile))
-		if ext == ".yaml" || ext == ".yml" {
-			return "yaml"
-		}
-		if ext == ".avro" {
-			return "avro"
-		}
-		if ext == ".parquet" {
-			return "parquet"
+		if name, ok := formatExtensions[compression.Ext(opts.InputFile)]; ok {
+			return name
 		}
 	}
 
@@ -227,45 +319,31 @@ func determineInputFormat(opts *cli.Flags) string {
 
 // run executes one full pass: parse stream -> apply pipeline -> print.
 func run(in io.Reader, out io.Writer, opts *cli.Flags) error {
+	return runTo(in, opts, func() (format.Formatter, error) {
+		return newFormatter(out, opts)
+	})
+}
+
+// runTo is run with the formatter made by newFmt, which is called once the
+// pipeline and parser are ready.
+func runTo(in io.Reader, opts *cli.Flags, newFmt func() (format.Formatter, error)) (err error) {
 	// Build operation pipeline
 	pipe, err := buildPipeline(opts)
 	if err != nil {
 		return err
 	}
 
-	// Determine input format
-	inputFormatName := determineInputFormat(opts)
-
-	// Get input format
-	inputFormat, err := format.Get(inputFormatName)
-	if err != nil {
-		return fmt.Errorf("unknown input format %q: %w", inputFormatName, err)
-	}
-
-	// Create parser
-	parser, err := inputFormat.NewParser(in)
+	parser, err := newParser(in, opts, pipe)
 	if err != nil {
-		return fmt.Errorf("failed to create parser: %w", err)
-	}
-
-	// Determine output format (default to json if not specified)
-	outputFormatName := opts.ToFormat
-	if outputFormatName == "" {
-		outputFormatName = "json"
+		return err
 	}
+	defer closeParser(parser)
 
-	// Get output format
-	outputFormat, err := format.Get(outputFormatName)
+	formatter, err := newFmt()
 	if err != nil {
-		return fmt.Errorf("unknown output format %q: %w", outputFormatName, err)
+		return err
 	}
-
-	// Create formatter for output
-	formatter := outputFormat.NewFormatter(out, format.FormatterOptions{
-		Color:   opts.Color,
-		Compact: opts.Compact,
-	})
-	defer formatter.Close()
+	defer closeFormatter(formatter, &err)
 
 	// Process stream: parse -> transform -> format
 	return parser.ForEach(func(doc any) error {
@@ -287,47 +365,33 @@ func run(in io.Reader, out io.Writer, opts *cli.Flags) error {
 
 // runWithMetadata is like run but wraps each output document with metadata (_file, _row, data).
 // This is used when processing directories to track which file and row each result came from.
-func runWithMetadata(in io.Reader, out io.Writer, opts *cli.Flags, filename string) error {
+func runWithMetadata(in io.Reader, out io.Writer, opts *cli.Flags, filename string) (err error) {
 	// Build operation pipeline
 	pipe, err := buildPipeline(opts)
 	if err != nil {
 		return err
 	}
 
-	// Determine input format
-	inputFormatName := determineInputFormat(opts)
-
-	// Get input format
-	inputFormat, err := format.Get(inputFormatName)
+	parser, err := newParser(in, opts, pipe)
 	if err != nil {
-		return fmt.Errorf("unknown input format %q: %w", inputFormatName, err)
-	}
-
-	// Create parser
-	parser, err := inputFormat.NewParser(in)
-	if err != nil {
-		return fmt.Errorf("failed to create parser: %w", err)
-	}
-
-	// Determine output format (default to json if not specified)
-	outputFormatName := opts.ToFormat
-	if outputFormatName == "" {
-		outputFormatName = "json"
+		return err
 	}
+	defer closeParser(parser)
 
-	// Get output format
-	outputFormat, err := format.Get(outputFormatName)
+	formatter, err := newFormatter(out, opts)
 	if err != nil {
-		return fmt.Errorf("unknown output format %q: %w", outputFormatName, err)
+		return err
 	}
+	defer closeFormatter(formatter, &err)
 
-	// Create formatter for output
-	formatter := outputFormat.NewFormatter(out, format.FormatterOptions{
-		Color:   opts.Color,
-		Compact: opts.Compact,
-	})
-	defer formatter.Close()
+	return processWithMetadata(parser, formatter.Write, pipe, filename, "")
+}
 
+// processWithMetadata streams documents from parser through pipe and passes each
+// surviving document to write wrapped with metadata (_file, _row, data, and
+// _format unless formatName is empty).
+// write is usually a shared formatter's Write, which is not closed here.
+func processWithMetadata(parser format.Parser, write func(any) error, pipe *operation.Pipeline, filename, formatName string) error {
 	// Track row number
 	rowNum := 0
 
@@ -355,9 +419,155 @@ func runWithMetadata(in io.Reader, out io.Writer, opts *cli.Flags, filename stri
 			"_row":  rowNum,
 			"data":  outDoc,
 		}
+		if formatName != "" {
+			wrapped["_format"] = formatName
+		}
+
+		return write(wrapped)
+	})
+}
+
+// newParser creates a parser for the input format selected by opts. It tells
+// the parser which fields pipe reads and which conditions it filters on, so
+// columnar formats can skip the rest.
+func newParser(in io.Reader, opts *cli.Flags, pipe *operation.Pipeline) (format.Parser, error) {
+	// Determine input format
+	inputFormatName := determineInputFormat(opts)
+
+	// Get input format
+	inputFormat, err := format.Get(inputFormatName)
+	if err != nil {
+		return nil, fmt.Errorf("unknown input format %q: %w", inputFormatName, err)
+	}
+
+	var columns [][]string
+	if fields, all := pipe.Fields(); !all {
+		columns = fields
+	}
+	var predicates []format.Predicate
+	for _, pred := range pipe.Predicates() {
+		predicates = append(predicates, pred)
+	}
+
+	var schema string
+	if inputFormatName == "avro" && opts.AvroReaderSchema != "" {
+		// #nosec G304 - CLI tool trusts user-provided file paths
+		data, err := os.ReadFile(opts.AvroReaderSchema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read avro reader schema: %w", err)
+		}
+		schema = string(data)
+	}
 
-		return formatter.Write(wrapped)
+	// Create parser
+	parser, err := inputFormat.NewParser(in, format.ParserOptions{
+		Delimiter:  csvDelimiter(opts),
+		NoHeader:   opts.CSVNoHeader,
+		InferTypes: opts.CSVInferTypes,
+		// Edit YAML documents in place when they go straight back out as YAML.
+		// Directory mode wraps documents with metadata, so it keeps plain values.
+		YAMLNodes:  inputFormatName == "yaml" && opts.ToFormat == "yaml" && opts.InputDir == "",
+		SpoolLimit: opts.ParquetSpoolLimit,
+		Columns:    columns,
+		Predicates: predicates,
+		Schema:     schema,
+		RawUnions:  opts.AvroRawUnions,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parser: %w", err)
+	}
+	return parser, nil
+}
+
+// newFormatter creates a formatter for the output format selected by opts.
+func newFormatter(out io.Writer, opts *cli.Flags) (format.Formatter, error) {
+	// Determine output format (default to json if not specified)
+	outputFormatName := opts.ToFormat
+	if outputFormatName == "" {
+		outputFormatName = "json"
+	}
+
+	// Get output format
+	outputFormat, err := format.Get(outputFormatName)
+	if err != nil {
+		return nil, fmt.Errorf("unknown output format %q: %w", outputFormatName, err)
+	}
+
+	formatterOpts, err := formatterOptions(outputFormatName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create formatter for output
+	formatter, err := outputFormat.NewFormatter(out, formatterOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s formatter: %w", outputFormatName, err)
+	}
+	return formatter, nil
+}
+
+// formatterOptions maps CLI flags onto the options for the named output format.
+func formatterOptions(name string, opts *cli.Flags) (format.FormatterOptions, error) {
+	fo := format.FormatterOptions{
+		Color:   opts.Color,
+		Compact: opts.Compact,
+	}
+
+	switch name {
+	case "avro":
+		if opts.AvroSchemaFile != "" {
+			// #nosec G304 - CLI tool trusts user-provided file paths
+			schema, err := os.ReadFile(opts.AvroSchemaFile)
+			if err != nil {
+				return fo, fmt.Errorf("failed to read avro schema: %w", err)
+			}
+			fo.Schema = string(schema)
+		}
+		fo.Codec = opts.AvroCodec
+		fo.SampleSize = opts.AvroSampleSize
+	case "parquet":
+		fo.Codec = opts.ParquetCodec
+		fo.RowGroupSize = opts.ParquetRowGroup
+	case "csv", "tsv":
+		fo.Delimiter = csvDelimiter(opts)
+		fo.NoHeader = opts.CSVNoHeader
+	}
+
+	return fo, nil
+}
+
+// csvDelimiter returns the -csv-delimiter rune, or 0 to use the format's default.
+func csvDelimiter(opts *cli.Flags) rune {
+	if opts.CSVDelimiter == "" {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(opts.CSVDelimiter)
+	return r
+}
+
+// closeFormatter closes f and reports its error through err unless an earlier
+// error is already being returned. Binary formats write their trailer on
+// Close, so its error must not be dropped.
+func closeFormatter(f format.Formatter, err *error) {
+	if cerr := f.Close(); cerr != nil && *err == nil {
+		*err = fmt.Errorf("failed to finalize output: %w", cerr)
+	}
+}
+
+// closeOutput calls the function openOutput returned, and reports its error in
+// *err if nothing else failed first.
+func closeOutput(closeOut func() error, err *error) {
+	if cerr := closeOut(); cerr != nil && *err == nil {
+		*err = fmt.Errorf("failed to finalize output: %w", cerr)
+	}
+}
+
+// closeParser releases what a parser holds on to, such as input spooled to a
+// temporary file, for parsers that need it.
+func closeParser(p format.Parser) {
+	if c, ok := p.(io.Closer); ok {
+		_ = c.Close()
+	}
 }
 
 func fatalf(format string, a ...any) {
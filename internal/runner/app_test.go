@@ -2,12 +2,17 @@ package runner
 
 import (
 	"bytes"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/GeoffMall/flow/internal/cli"
+	"github.com/GeoffMall/flow/internal/compression"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const fakeJSONPath = "testdata/fake.json"
@@ -214,6 +219,60 @@ func Test_run_JSONIn_YAMLOut(t *testing.T) {
 	assert.Contains(t, got, "b:\n  - 2\n  - 3\n")
 }
 
+func Test_run_YAMLOut_NoDocuments(t *testing.T) {
+	var out bytes.Buffer
+	err := run(strings.NewReader(`{"a":1}`), &out, &cli.Flags{ToFormat: "yaml", WherePairs: []string{"a=2"}})
+	assert.NoError(t, err)
+	assert.Empty(t, out.String())
+
+	out.Reset()
+	err = run(strings.NewReader(""), &out, &cli.Flags{FromFormat: "yaml", ToFormat: "yaml"})
+	assert.NoError(t, err)
+	assert.Empty(t, out.String())
+}
+
+func Test_run_PreservesKeyOrder(t *testing.T) {
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - name: web
+          image: web:1.0
+`
+	var out bytes.Buffer
+	opts := &cli.Flags{
+		FromFormat:  "yaml",
+		ToFormat:    "yaml",
+		SetPairs:    []string{"spec.replicas=3", "spec.template.spec.containers[0].image=web:1.1", "metadata.labels.app=web"},
+		DeletePaths: []string{"kind"},
+	}
+	err := run(strings.NewReader(manifest), &out, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, `apiVersion: apps/v1
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+        - name: web
+          image: web:1.1
+`, out.String())
+
+	out.Reset()
+	err = run(strings.NewReader(`{"z":1,"a":{"y":2,"b":3}}`), &out, &cli.Flags{Compact: true, SetPairs: []string{"a.c=4"}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"z":1,"a":{"y":2,"b":3,"c":4}}`+"\n", out.String())
+}
+
 func TestBuildPipeline_InvalidSet(t *testing.T) {
 	opts := &cli.Flags{
 		SetPairs: []string{"not-a-pair-with-equals"},
@@ -353,6 +412,108 @@ func Test_processDirectory_Parquet_WithWhere_MultipleMatches(t *testing.T) {
 	assert.Contains(t, output, "products2.parquet")
 }
 
+func Test_run_Parquet_PickAndWhereReadOnlyTheirColumns(t *testing.T) {
+	file, err := os.Open("../../testdata/dir-test/products1.parquet")
+	require.NoError(t, err)
+	defer file.Close()
+
+	var out bytes.Buffer
+	err = run(file, &out, &cli.Flags{
+		FromFormat: "parquet",
+		WherePairs: []string{"category=Electronics", "price>1000"},
+		PickPaths:  []string{"name", "price"},
+		Compact:    true,
+	})
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.NotEmpty(t, lines)
+	for _, line := range lines {
+		assert.Contains(t, line, `"name":`)
+		assert.NotContains(t, line, "sku")
+	}
+	assert.Contains(t, out.String(), "Dell XPS 15")
+}
+
+func Test_run_Parquet_RowGroupStatistics(t *testing.T) {
+	var docs strings.Builder
+	for i := range 10 {
+		fmt.Fprintf(&docs, "{\"id\": %d}\n", i)
+	}
+	path := filepath.Join(t.TempDir(), "ids.parquet")
+	out, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, run(strings.NewReader(docs.String()), out, &cli.Flags{ToFormat: "parquet", ParquetRowGroup: 3}))
+	require.NoError(t, out.Close())
+
+	for _, where := range []string{"id=4", "id>=8", "id<1"} {
+		file, err := os.Open(path)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		err = run(file, &buf, &cli.Flags{FromFormat: "parquet", WherePairs: []string{where}, Compact: true})
+		_ = file.Close()
+		require.NoError(t, err)
+
+		want := map[string]string{"id=4": "{\"id\":4}\n", "id>=8": "{\"id\":8}\n{\"id\":9}\n", "id<1": "{\"id\":0}\n"}
+		assert.Equal(t, want[where], buf.String(), where)
+	}
+}
+
+func Test_processDirectory_AvroReaderSchema(t *testing.T) {
+	dir := t.TempDir()
+	writeAvro := func(name, schema, doc string) {
+		schemaFile := filepath.Join(dir, name+".avsc")
+		require.NoError(t, os.WriteFile(schemaFile, []byte(schema), 0o600))
+		out, err := os.Create(filepath.Join(dir, name+".avro"))
+		require.NoError(t, err)
+		defer out.Close()
+		require.NoError(t, run(strings.NewReader(doc), out, &cli.Flags{ToFormat: "avro", AvroSchemaFile: schemaFile}))
+	}
+	writeAvro("v1", `{"type":"record","name":"User","fields":[{"name":"name","type":"string"},{"name":"age","type":"int"}]}`,
+		`{"name":"Alice","age":30}`)
+	writeAvro("v2", `{"type":"record","name":"User","fields":[{"name":"name","type":"string"},{"name":"age","type":"long"},{"name":"team","type":"string","default":"none"}]}`,
+		`{"name":"Bob","age":25,"team":"core"}`)
+
+	output := filepath.Join(t.TempDir(), "out.json")
+	err := processDirectory(&cli.Flags{
+		InputDir:         dir,
+		FromFormat:       "avro",
+		AvroReaderSchema: filepath.Join(dir, "v2.avsc"),
+		Compact:          true,
+		OutputFile:       output,
+	})
+	require.NoError(t, err)
+
+	got, err := os.ReadFile(output)
+	require.NoError(t, err)
+	// Both files come out in the reader schema's shape: v1 gets the default team, and age is a long either way
+	assert.Contains(t, string(got), `"data":{"age":30,"name":"Alice","team":"none"}`)
+	assert.Contains(t, string(got), `"data":{"age":25,"name":"Bob","team":"core"}`)
+
+	// Without a readable reader schema nothing is read
+	err = processDirectory(&cli.Flags{InputDir: dir, FromFormat: "avro", AvroReaderSchema: filepath.Join(dir, "missing.avsc"), OutputFile: output})
+	assert.Error(t, err)
+}
+
+func Test_run_AvroLogicalTypesAndUnions(t *testing.T) {
+	schemaFile := filepath.Join(t.TempDir(), "event.avsc")
+	require.NoError(t, os.WriteFile(schemaFile, []byte(`{"type":"record","name":"Event","fields":[
+		{"name":"ts","type":{"type":"long","logicalType":"timestamp-millis"}},
+		{"name":"owner","type":["null",{"type":"record","name":"Owner","fields":[{"name":"name","type":"string"}]}]}]}`), 0o600))
+
+	var avroOut bytes.Buffer
+	in := `{"ts":"2024-01-02T03:04:05.5Z","owner":{"name":"Ann"}}`
+	require.NoError(t, run(strings.NewReader(in), &avroOut, &cli.Flags{ToFormat: "avro", AvroSchemaFile: schemaFile}))
+
+	var out bytes.Buffer
+	require.NoError(t, run(bytes.NewReader(avroOut.Bytes()), &out, &cli.Flags{FromFormat: "avro", Compact: true, WherePairs: []string{"ts>2024-01-01T00:00:00Z"}}))
+	assert.Equal(t, `{"owner":{"name":"Ann"},"ts":"2024-01-02T03:04:05.5Z"}`+"\n", out.String())
+
+	out.Reset()
+	require.NoError(t, run(bytes.NewReader(avroOut.Bytes()), &out, &cli.Flags{FromFormat: "avro", Compact: true, AvroRawUnions: true}))
+	assert.Equal(t, `{"owner":{"Owner":{"name":"Ann"}},"ts":"2024-01-02T03:04:05.5Z"}`+"\n", out.String())
+}
+
 func Test_processDirectory_WithMultipleWhereConditions(t *testing.T) {
 	tmpFile := "../../testdata/dir-test/test-multi-where.json"
 	defer func() {
@@ -457,6 +618,32 @@ age: 30`)
 	assert.Contains(t, output, "age: 30")
 }
 
+func Test_run_YAMLToYAML_PreservesComments(t *testing.T) {
+	input := `# Default values
+replicaCount: 1 # bump for production
+image:
+  repository: "nginx"
+  tag: '1.25'
+debug: true
+`
+	opts := &cli.Flags{
+		FromFormat:  "yaml",
+		ToFormat:    "yaml",
+		SetPairs:    []string{"image.tag=v2"},
+		DeletePaths: []string{"debug"},
+		WherePairs:  []string{"replicaCount=1"},
+	}
+
+	got, err := runTest(t, input, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, `# Default values
+replicaCount: 1 # bump for production
+image:
+  repository: "nginx"
+  tag: 'v2'
+`, got)
+}
+
 func Test_run_AvroFormat(t *testing.T) {
 	file, err := os.Open("../../testdata/dir-test/employees1.avro")
 	assert.NoError(t, err)
@@ -497,6 +684,170 @@ func Test_run_ParquetFormat(t *testing.T) {
 	assert.Contains(t, output, "Electronics")
 }
 
+func Test_run_ParquetFromStream(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/dir-test/products1.parquet")
+	require.NoError(t, err)
+
+	// A reader without random access, like stdin, is spooled
+	var out bytes.Buffer
+	err = run(io.MultiReader(bytes.NewReader(data)), &out, &cli.Flags{FromFormat: "parquet", Compact: true})
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "Dell XPS 15")
+
+	err = run(io.MultiReader(bytes.NewReader(data)), io.Discard, &cli.Flags{FromFormat: "parquet", ParquetSpoolLimit: 100})
+	assert.ErrorContains(t, err, "spool limit")
+}
+
+func Test_processDirectory_CompressedParquet(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/dir-test/products1.parquet")
+	require.NoError(t, err)
+	dir := t.TempDir()
+	compressFile(t, filepath.Join(dir, "products.parquet.gz"), "gzip", string(data))
+
+	lines, err := runDirectory(t, &cli.Flags{InputDir: dir, FromFormat: "parquet"})
+	require.NoError(t, err)
+	assert.Contains(t, lines[0], "products.parquet.gz")
+	assert.Contains(t, strings.Join(lines, "\n"), "Dell XPS 15")
+}
+
+func Test_run_JSONToAvro_RoundTrip(t *testing.T) {
+	in := strings.NewReader(`{"name":"Alice","age":30}
+{"name":"Bob","age":25}`)
+	var avroOut bytes.Buffer
+
+	opts := &cli.Flags{
+		ToFormat:   "avro",
+		WherePairs: []string{"name=Bob"},
+		AvroCodec:  "deflate",
+	}
+	err := run(in, &avroOut, opts)
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	err = run(&avroOut, &out, &cli.Flags{FromFormat: "avro", Compact: true})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"age":25,"name":"Bob"}`+"\n", out.String())
+}
+
+func Test_run_AvroOutput_SchemaFileMissing(t *testing.T) {
+	in := strings.NewReader(`{"name":"Alice"}`)
+	var out bytes.Buffer
+
+	opts := &cli.Flags{
+		ToFormat:       "avro",
+		AvroSchemaFile: "testdata/nonexistent.avsc",
+	}
+	err := run(in, &out, opts)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read avro schema")
+}
+
+func Test_run_AvroOutput_DocumentDoesNotFit(t *testing.T) {
+	in := strings.NewReader(`{"age":30}
+{"age":"thirty"}`)
+	var out bytes.Buffer
+
+	opts := &cli.Flags{
+		ToFormat:       "avro",
+		AvroSampleSize: 1,
+	}
+	err := run(in, &out, opts)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match avro schema")
+}
+
+func Test_processDirectory_AvroOutput_SingleContainer(t *testing.T) {
+	outFile := t.TempDir() + "/out.avro"
+	opts := &cli.Flags{
+		InputDir:   "../../testdata/dir-test",
+		FromFormat: "avro",
+		ToFormat:   "avro",
+		OutputFile: outFile,
+	}
+
+	err := processDirectory(opts)
+	assert.NoError(t, err)
+
+	// All files must land in one readable container
+	file, err := os.Open(outFile)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	var out bytes.Buffer
+	err = run(file, &out, &cli.Flags{FromFormat: "avro", Compact: true})
+	assert.NoError(t, err)
+	assert.Len(t, strings.Split(strings.TrimSpace(out.String()), "\n"), 13)
+}
+
+func Test_processDirectory_AvroToParquet(t *testing.T) {
+	outFile := t.TempDir() + "/slim.parquet"
+	opts := &cli.Flags{
+		InputDir:     "../../testdata/dir-test",
+		FromFormat:   "avro",
+		ToFormat:     "parquet",
+		WherePairs:   []string{"department=Engineering"},
+		OutputFile:   outFile,
+		ParquetCodec: "zstd",
+	}
+
+	err := processDirectory(opts)
+	assert.NoError(t, err)
+
+	file, err := os.Open(outFile)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	var out bytes.Buffer
+	err = run(file, &out, &cli.Flags{FromFormat: "parquet", Compact: true, PickPaths: []string{"data.department"}})
+	assert.NoError(t, err)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		assert.Equal(t, `"Engineering"`, line)
+	}
+}
+
+func Test_run_CSVIn_JSONOut(t *testing.T) {
+	in := strings.NewReader("name,age,active\nAlice,30,true\nBob,,false\n")
+	var out bytes.Buffer
+
+	opts := &cli.Flags{
+		FromFormat:    "csv",
+		CSVInferTypes: true,
+		WherePairs:    []string{"active=true"},
+		Compact:       true,
+	}
+	err := run(in, &out, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"Alice","age":30,"active":true}`, strings.TrimSpace(out.String()))
+}
+
+func Test_run_JSONIn_CSVOut(t *testing.T) {
+	in := strings.NewReader(`{"user":{"id":1,"name":"a"},"tags":["x","y"]} {"user":{"id":2,"name":"b"},"tags":["z"]}`)
+	var out bytes.Buffer
+
+	opts := &cli.Flags{
+		ToFormat:     "csv",
+		CSVDelimiter: ";",
+	}
+	err := run(in, &out, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, "user.id;user.name;tags[0];tags[1]\n1;a;x;y\n2;b;z;\n", out.String())
+}
+
+func Test_run_TSVNoHeader(t *testing.T) {
+	in := strings.NewReader("a\t1\nb\t2\n")
+	var out bytes.Buffer
+
+	opts := &cli.Flags{
+		FromFormat:  "tsv",
+		CSVNoHeader: true,
+		PickPaths:   []string{"col2"},
+		Compact:     true,
+	}
+	err := run(in, &out, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, "\"1\"\n\"2\"\n", out.String())
+}
+
 func Test_runWithMetadata_AvroFormat(t *testing.T) {
 	file, err := os.Open("../../testdata/dir-test/employees1.avro")
 	assert.NoError(t, err)
@@ -586,6 +937,16 @@ func Test_determineInputFormat(t *testing.T) {
 			opts:        &cli.Flags{InputFile: "data.parquet"},
 			expectedFmt: "parquet",
 		},
+		{
+			name:        "csv_extension",
+			opts:        &cli.Flags{InputFile: "data.csv"},
+			expectedFmt: "csv",
+		},
+		{
+			name:        "tsv_extension",
+			opts:        &cli.Flags{InputFile: "data.tsv"},
+			expectedFmt: "tsv",
+		},
 		{
 			name:        "json_extension",
 			opts:        &cli.Flags{InputFile: "data.json"},
@@ -606,6 +967,11 @@ func Test_determineInputFormat(t *testing.T) {
 			opts:        &cli.Flags{InputFile: "CONFIG.YAML"},
 			expectedFmt: "yaml",
 		},
+		{
+			name:        "compressed_yaml",
+			opts:        &cli.Flags{InputFile: "config.yaml.gz"},
+			expectedFmt: "yaml",
+		},
 		{
 			name:        "explicit_flag_overrides_extension",
 			opts:        &cli.Flags{InputFile: "data.json", FromFormat: "yaml"},
@@ -635,16 +1001,42 @@ func Test_openInput_Stdin(t *testing.T) {
 }
 
 func Test_openOutput_Error(t *testing.T) {
-	_, _, err := openOutput("/nonexistent/directory/output.json")
+	_, _, err := openOutput("/nonexistent/directory/output.json", nil)
 	assert.Error(t, err)
 }
 
 func Test_openOutput_Stdout(t *testing.T) {
-	writer, closeFunc, err := openOutput("")
+	writer, closeFunc, err := openOutput("", nil)
 	assert.NoError(t, err)
 	assert.NotNil(t, writer)
 	assert.NotNil(t, closeFunc)
-	closeFunc() // Should not panic
+	assert.NoError(t, closeFunc())
+}
+
+func Test_openOutput_ReportsCompressedCloseError(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("needs /dev/full")
+	}
+	codec, err := compression.Get("zstd")
+	require.NoError(t, err)
+
+	// zstd buffers small writes, so they only reach the device on close
+	writer, closeFunc, err := openOutput("/dev/full", codec)
+	require.NoError(t, err)
+	_, err = writer.Write([]byte(`{"a":1}`))
+	require.NoError(t, err)
+	assert.Error(t, closeFunc())
+}
+
+func Test_processDirectory_ReportsCompressedCloseError(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("needs /dev/full")
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"a":1}`), 0o600))
+
+	err := processDirectory(&cli.Flags{InputDir: dir, FromFormat: "json", OutputFile: "/dev/full", Compress: "zstd"})
+	assert.ErrorContains(t, err, "failed to finalize output")
 }
 
 func Test_buildPipeline_WithWhere(t *testing.T) {
@@ -713,6 +1105,31 @@ func Test_run_ParserCreationError(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func Test_buildPipeline_InvalidFilter(t *testing.T) {
+	opts := &cli.Flags{
+		FilterExpr: "status=ERROR or",
+	}
+	_, err := buildPipeline(opts)
+	assert.ErrorContains(t, err, "invalid filter")
+}
+
+func Test_run_WithFilter(t *testing.T) {
+	in := strings.NewReader(`{"status":"ERROR","latency":10}
+{"status":"OK","latency":900}
+{"status":"OK","latency":20}`)
+	var out bytes.Buffer
+
+	opts := &cli.Flags{
+		FilterExpr: "status=ERROR or latency>500",
+		PickPaths:  []string{"latency"},
+		Compact:    true,
+	}
+
+	err := run(in, &out, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, "10\n900\n", out.String())
+}
+
 func Test_run_WithWhere_FiltersOut(t *testing.T) {
 	in := strings.NewReader(`{"name":"Alice","age":30}
 {"name":"Bob","age":25}`)
@@ -940,3 +1357,80 @@ func Test_run_JSONDataTypes_Comprehensive(t *testing.T) {
 		})
 	}
 }
+
+// compressFile writes content to path compressed with the named codec.
+func compressFile(t *testing.T, path, codecName, content string) {
+	t.Helper()
+	codec, err := compression.Get(codecName)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := codec.NewWriter(&buf)
+	require.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+}
+
+// decompressFile returns the decompressed content of path and the codec it used.
+func decompressFile(t *testing.T, path string) (string, *compression.Codec) {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	r, codec, err := compression.NewReader(f)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(data), codec
+}
+
+func Test_processDirectory_CompressedInput(t *testing.T) {
+	dir := t.TempDir()
+	compressFile(t, filepath.Join(dir, "a.json.gz"), "gzip", `{"n": 1}`)
+	compressFile(t, filepath.Join(dir, "b.json.zst"), "zstd", `{"n": 2}`)
+	writeFile(t, filepath.Join(dir, "c.json"), `{"n": 3}`, 0o600)
+	compressFile(t, filepath.Join(dir, "d.yaml.gz"), "gzip", "n: 4\n")
+
+	lines, err := runDirectory(t, &cli.Flags{InputDir: dir})
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+	for i, line := range lines {
+		assert.Contains(t, line, fmt.Sprintf(`"data":{"n":%d}`, i+1))
+	}
+}
+
+func Test_processDirectory_CompressedOutput(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), `{"n": 1}`, 0o600)
+
+	tests := []struct {
+		name     string
+		out      string
+		compress string
+		codec    string
+	}{
+		{name: "by_extension", out: "out.json.gz", codec: "gzip"},
+		{name: "by_flag", out: "out.json", compress: "zstd", codec: "zstd"},
+		{name: "none_overrides_extension", out: "out.json.gz", compress: "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), tt.out)
+			err := processDirectory(&cli.Flags{InputDir: dir, OutputFile: out, Compress: tt.compress, Compact: true})
+			require.NoError(t, err)
+
+			content, codec := decompressFile(t, out)
+			assert.Contains(t, content, `"data":{"n":1}`)
+			if tt.codec == "" {
+				assert.Nil(t, codec)
+			} else {
+				require.NotNil(t, codec)
+				assert.Equal(t, tt.codec, codec.Name)
+			}
+		})
+	}
+}
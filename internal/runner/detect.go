@@ -0,0 +1,173 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/GeoffMall/flow/internal/cli"
+	"github.com/GeoffMall/flow/internal/compression"
+)
+
+// autoFormat is the -from value that detects each file's format.
+const autoFormat = "auto"
+
+// formatExtensions maps file extensions to the input formats they hold.
+var formatExtensions = map[string]string{
+	".json":    "json",
+	".yaml":    "yaml",
+	".yml":     "yaml",
+	".avro":    "avro",
+	".parquet": "parquet",
+	".csv":     "csv",
+	".tsv":     "tsv",
+}
+
+// sniffSize is how many leading bytes are read to detect a format.
+const sniffSize = 512
+
+// autoExtensions returns the extensions -in-dir processes with -from auto:
+// every known extension, plus files without one (detected from their content).
+func autoExtensions() []string {
+	exts := make([]string, 0, len(formatExtensions)+1)
+	for ext := range formatExtensions {
+		exts = append(exts, ext)
+	}
+	slices.Sort(exts)
+	return append(exts, "")
+}
+
+// resolveAutoFormat returns opts for reading the file at path. With -from auto
+// that is a copy with the file's detected format, otherwise opts itself.
+func resolveAutoFormat(opts *cli.Flags, path string) (*cli.Flags, error) {
+	if opts.FromFormat != autoFormat {
+		return opts, nil
+	}
+	name, err := detectFileFormat(path)
+	if err != nil {
+		return nil, err
+	}
+	fileOpts := *opts
+	fileOpts.FromFormat = name
+	return &fileOpts, nil
+}
+
+// detectFileFormat reads the start of the file at path, decompressed, and detects its format.
+func detectFileFormat(path string) (string, error) {
+	// #nosec G304 - CLI tool trusts user-provided file paths
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	in, _, err := compression.NewReader(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(in, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	name := detectFormat(path, head[:n])
+	if name == "" {
+		return "", fmt.Errorf("cannot detect the format of %s (use -from to set it)", path)
+	}
+	return name, nil
+}
+
+// detectFormat returns the format of data named name, given its first bytes:
+// binary magic numbers win, then the file extension, then a look at the text.
+// It returns "" if the format cannot be told.
+func detectFormat(name string, head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("Obj\x01")):
+		return "avro"
+	case bytes.HasPrefix(head, []byte("PAR1")):
+		return "parquet"
+	}
+
+	if f, ok := formatExtensions[compression.Ext(name)]; ok {
+		return f
+	}
+	return sniffText(head)
+}
+
+// sniffText tells JSON from YAML by the first meaningful characters. Anything
+// else, including binary data, is not recognized.
+func sniffText(head []byte) string {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return ""
+	}
+
+	text := bytes.TrimLeft(head, " \t\r\n\ufeff")
+	if len(text) == 0 {
+		return ""
+	}
+	if text[0] == '{' || text[0] == '[' {
+		return "json"
+	}
+
+	// A YAML document marker, comment or list item, or a first line of the
+	// form "key: value"
+	if bytes.HasPrefix(text, []byte("---")) || text[0] == '#' || bytes.HasPrefix(text, []byte("- ")) {
+		return "yaml"
+	}
+	line, _, _ := bytes.Cut(text, []byte("\n"))
+	line = bytes.TrimRight(line, " \t\r")
+	if bytes.Contains(line, []byte(": ")) || bytes.HasSuffix(line, []byte(":")) {
+		return "yaml"
+	}
+	return ""
+}
+
+// sniffInput detects the format of a stream that has no file name (stdin).
+// It peeks at the stream through a buffered reader and returns a reader that
+// still yields the whole stream, along with the format: Avro and Parquet magic
+// numbers, or JSON and YAML text (see sniffText). Undetected input is taken to
+// be JSON. Compressed input must already be decompressed (see openInput).
+//
+// It reads only as much as it needs to decide, so a slow stream (e.g. a log
+// tail) is not held up waiting for a full sniffSize bytes.
+func sniffInput(r io.Reader) (io.Reader, string, error) {
+	br := bufio.NewReaderSize(r, sniffSize)
+	head, err := peekUntil(br, func(head []byte) bool {
+		return detectFormat("", head) != ""
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	name := detectFormat("", head)
+	if name == "" {
+		name = "json"
+	}
+	return br, name, nil
+}
+
+// peekUntil peeks at more and more of br until decided reports true for the
+// bytes seen, the buffer is full or the stream ends, and returns those bytes.
+func peekUntil(br *bufio.Reader, decided func(head []byte) bool) ([]byte, error) {
+	for n := 1; ; n = br.Buffered() + 1 {
+		head, err := br.Peek(n)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, bufio.ErrBufferFull) {
+				return head, nil
+			}
+			return nil, err
+		}
+
+		// Look at everything already read, without waiting for more
+		head, _ = br.Peek(br.Buffered())
+		if decided(head) || len(head) >= sniffSize {
+			return head, nil
+		}
+	}
+}
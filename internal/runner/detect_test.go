@@ -0,0 +1,153 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/GeoffMall/flow/internal/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_detectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"data.bin", "Obj\x01\x04\x14avro.schema", "avro"},
+		{"data.json", "Obj\x01\x04", "avro"}, // magic bytes win over the extension
+		{"part-0", "PAR1\x15\x04", "parquet"},
+		{"config.yml", "{}", "yaml"},
+		{"rows.TSV", "a\tb\n", "tsv"},
+		{"events", "  \n[{\"a\": 1}]", "json"},
+		{"events", "\ufeff{\"a\": 1}", "json"},
+		{"values", "# comment\nkey: value\n", "yaml"},
+		{"values", "---\nkey: value\n", "yaml"},
+		{"values", "- a\n- b\n", "yaml"},
+		{"values", "server:\n  port: 80\n", "yaml"},
+		{"notes", "just some text\n", ""},
+		{"blob", "\x00\x01\x02", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, detectFormat(tt.name, []byte(tt.head)), "%s: %q", tt.name, tt.head)
+	}
+}
+
+func copyTestFile(t *testing.T, src, dst string) {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst, data, 0o644))
+}
+
+func Test_processDirectory_AutoFormat(t *testing.T) {
+	dir := t.TempDir()
+	copyTestFile(t, "../../testdata/dir-test/users.avro", filepath.Join(dir, "a.avro"))
+	copyTestFile(t, "../../testdata/dir-test/single.parquet", filepath.Join(dir, "b.parquet"))
+	// Binary files are recognized by their magic bytes whatever their name
+	copyTestFile(t, "../../testdata/dir-test/users.avro", filepath.Join(dir, "c-avro-export"))
+	writeFile(t, filepath.Join(dir, "d.json"), `{"id": 1}`, 0o644)
+	writeFile(t, filepath.Join(dir, "e.yaml"), "id: 2\n", 0o644)
+	writeFile(t, filepath.Join(dir, "f-noext"), `{"id": 3}`, 0o644)
+	writeFile(t, filepath.Join(dir, "notes.txt"), "not selected", 0o644)
+
+	lines, err := runDirectory(t, &cli.Flags{InputDir: dir, FromFormat: "auto"})
+	require.NoError(t, err)
+
+	formats := map[string]string{}
+	for _, line := range lines {
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &doc))
+		formats[filepath.Base(doc["_file"].(string))] = doc["_format"].(string)
+	}
+	assert.Equal(t, map[string]string{
+		"a.avro":        "avro",
+		"b.parquet":     "parquet",
+		"c-avro-export": "avro",
+		"d.json":        "json",
+		"e.yaml":        "yaml",
+		"f-noext":       "json",
+	}, formats)
+}
+
+func Test_processDirectory_AutoFormat_Undetectable(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), `{"id": 1}`, 0o644)
+	writeFile(t, filepath.Join(dir, "LICENSE"), "All rights reserved.\n", 0o644)
+
+	lines, err := runDirectory(t, &cli.Flags{InputDir: dir, FromFormat: "auto"})
+	assert.ErrorContains(t, err, "completed with 1 error(s)")
+	assert.Len(t, lines, 1)
+}
+
+func Test_processDirectory_FixedFormatHasNoFormatField(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), `{"id": 1}`, 0o644)
+
+	lines, err := runDirectory(t, &cli.Flags{InputDir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, []string{`{"_file":"` + filepath.Join(dir, "a.json") + `","_row":1,"data":{"id":1}}`}, lines)
+}
+
+func Test_sniffInput(t *testing.T) {
+	avro, err := os.ReadFile("../../testdata/dir-test/users.avro")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{"json object", []byte(`{"a": 1}`), "json"},
+		{"json array", []byte("\n  [1, 2]"), "json"},
+		{"yaml", []byte("name: flow\nitems:\n  - a\n"), "yaml"},
+		{"yaml document marker", []byte("---\n- a\n"), "yaml"},
+		{"avro", avro, "avro"},
+		{"undetected falls back to json", []byte("42"), "json"},
+		{"empty", []byte{}, "json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// One byte at a time, so detection has to keep reading
+			r, name, err := sniffInput(iotest.OneByteReader(bytes.NewReader(tt.input)))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, name)
+
+			// Nothing consumed by sniffing is lost
+			got, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, tt.input, got)
+		})
+	}
+}
+
+func Test_sniffInput_DoesNotWaitForMoreThanItNeeds(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	done := make(chan string)
+	go func() {
+		_, name, err := sniffInput(pr)
+		assert.NoError(t, err)
+		done <- name
+	}()
+
+	// A stream that has produced one document and then stalls
+	_, err := pw.Write([]byte(`{"level": "info"}` + "\n"))
+	require.NoError(t, err)
+
+	select {
+	case name := <-done:
+		assert.Equal(t, "json", name)
+	case <-time.After(5 * time.Second):
+		t.Fatal("sniffInput blocked waiting for more input")
+	}
+}
@@ -0,0 +1,259 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/GeoffMall/flow/internal/cli"
+	"github.com/GeoffMall/flow/internal/compression"
+	"github.com/GeoffMall/flow/internal/format"
+)
+
+// binaryFormats are read-only for -in-place: writing them back would
+// re-infer the schema and encoding instead of keeping the original ones.
+var binaryFormats = []string{"avro", "parquet"}
+
+// processInPlace rewrites the -in file, or every matching file under -in-dir,
+// with the result of the pipeline.
+func processInPlace(opts *cli.Flags) error {
+	if opts.InputDir == "" {
+		return editInPlace(opts.InputFile, opts)
+	}
+
+	selector, err := newFileSelector(opts)
+	if err != nil {
+		return err
+	}
+
+	files, errs, err := selector.findFiles(opts.InputDir)
+	if err != nil {
+		return fmt.Errorf("error walking directory: %w", err)
+	}
+	if len(files) == 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "Warning: no files with extensions %v found in %s\n", selector.extensions, opts.InputDir)
+	}
+
+	errs = append(errs, forEachFile(files, opts.Jobs, func(path string) error {
+		return editInPlace(path, opts)
+	})...)
+	return reportErrors(errs)
+}
+
+// editInPlace runs the pipeline over the file at path and atomically replaces
+// the file with the output. The output goes to a temporary file in the same
+// directory, which is synced and renamed over the original with the original's
+// permissions, so the file is never left half-written.
+func editInPlace(path string, opts *cli.Flags) (err error) {
+	fileOpts, err := inPlaceOptions(path, opts)
+	if err != nil {
+		return err
+	}
+
+	// Edit the target of a symlink rather than replacing the link
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	// #nosec G304 - CLI tool trusts user-provided file paths
+	in, err := os.Open(target)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".flow-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", path, err)
+	}
+	defer func() {
+		// Leave nothing behind if anything before the rename failed
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := runCompressed(in, tmp, fileOpts); err != nil {
+		return fmt.Errorf("failed to process %s: %w", path, err)
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to set permissions for %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if opts.Backup {
+		if err := copyFile(target, target+".bak", info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	syncDir(filepath.Dir(target))
+	return nil
+}
+
+// inPlaceOptions returns the options for rewriting the file at path. It
+// refuses binary formats, and a different output format unless -force is
+// given. Output defaults to the input format and is never colored.
+func inPlaceOptions(path string, opts *cli.Flags) (*cli.Flags, error) {
+	opts, err := resolveAutoFormat(opts, path)
+	if err != nil {
+		return nil, err
+	}
+
+	fileOpts := *opts
+	fileOpts.InputFile = path
+	fileOpts.InputDir = ""
+	fileOpts.Color = false
+
+	from := determineInputFormat(&fileOpts)
+	if fileOpts.ToFormat == "" {
+		fileOpts.ToFormat = from
+	}
+
+	for _, name := range []string{from, fileOpts.ToFormat} {
+		if slices.Contains(binaryFormats, name) {
+			return nil, fmt.Errorf("cannot edit %s in place: %s files are read-only for -in-place", path, name)
+		}
+	}
+	if fileOpts.ToFormat != from && !opts.Force {
+		return nil, fmt.Errorf("cannot edit %s in place: output format %s differs from input format %s (use -force to convert anyway)", path, fileOpts.ToFormat, from)
+	}
+	return &fileOpts, nil
+}
+
+// runCompressed is run for a file that may be compressed. A compressed file
+// is written back with the same codec, unless -compress chooses another one
+// (or none).
+func runCompressed(in io.Reader, out io.Writer, opts *cli.Flags) error {
+	r, codec, err := compression.NewReader(in)
+	if err != nil {
+		return err
+	}
+	if opts.Compress != "" {
+		if codec, err = outputCodec(opts); err != nil {
+			return err
+		}
+	}
+	if codec == nil {
+		return runKeepingShape(r, out, opts)
+	}
+
+	w, err := codec.NewWriter(out)
+	if err != nil {
+		return err
+	}
+	if err := runKeepingShape(r, w, opts); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// runKeepingShape is run for a file that is rewritten. The JSON parser
+// streams the elements of a top-level array as separate documents; for JSON
+// or YAML output they are collected and written back as one array, so the
+// file stays an array instead of becoming a sequence of documents.
+func runKeepingShape(in io.Reader, out io.Writer, opts *cli.Flags) error {
+	br := bufio.NewReader(in)
+	if determineInputFormat(opts) != "json" || (opts.ToFormat != "json" && opts.ToFormat != "yaml") || !startsWithArray(br) {
+		return run(br, out, opts)
+	}
+
+	return runTo(br, opts, func() (format.Formatter, error) {
+		f, err := newFormatter(out, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &arrayFormatter{out: f, docs: []any{}}, nil
+	})
+}
+
+// startsWithArray skips leading whitespace in br and reports whether the
+// next value starts with '['.
+func startsWithArray(br *bufio.Reader) bool {
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			return false
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = br.ReadByte()
+		default:
+			return b[0] == '['
+		}
+	}
+}
+
+// arrayFormatter collects the documents written to it and writes them to
+// out as a single array when it is closed.
+type arrayFormatter struct {
+	out  format.Formatter
+	docs []any
+}
+
+func (f *arrayFormatter) Write(doc any) error {
+	f.docs = append(f.docs, doc)
+	return nil
+}
+
+func (f *arrayFormatter) Close() error {
+	if err := f.out.Write(f.docs); err != nil {
+		_ = f.out.Close()
+		return err
+	}
+	return f.out.Close()
+}
+
+// copyFile copies src to dst, replacing dst, and syncs it.
+func copyFile(src, dst string, perm os.FileMode) (err error) {
+	// #nosec G304 - CLI tool trusts user-provided file paths
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// #nosec G304 - CLI tool trusts user-provided file paths
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Sync()
+}
+
+// syncDir makes a rename in dir durable. Not every platform can sync a
+// directory, so failures are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir) // #nosec G304 - directory of a user-provided file
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
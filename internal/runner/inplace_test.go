@@ -0,0 +1,203 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GeoffMall/flow/internal/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string, perm os.FileMode) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), perm))
+	require.NoError(t, os.Chmod(path, perm))
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func Test_editInPlace_YAMLWithBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "values.yaml")
+	original := "# image settings\nimage:\n  tag: v1 # pinned\n"
+	writeFile(t, path, original, 0o640)
+
+	err := processInPlace(&cli.Flags{
+		InputFile: path,
+		SetPairs:  []string{"image.tag=v2"},
+		InPlace:   true,
+		Backup:    true,
+		Color:     true,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "# image settings\nimage:\n  tag: v2 # pinned\n", readFile(t, path))
+	assert.Equal(t, original, readFile(t, path+".bak"))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+
+	// Only the file and its backup remain, no temporary files
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func Test_editInPlace_JSONKeepsFormatWithoutColor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeFile(t, path, `{"b": 1, "a": 2}`, 0o600)
+
+	err := processInPlace(&cli.Flags{InputFile: path, DeletePaths: []string{"a"}, InPlace: true, Color: true})
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"b\": 1\n}\n", readFile(t, path))
+	assert.NoFileExists(t, path+".bak")
+}
+
+func Test_editInPlace_FormatChangeNeedsForce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeFile(t, path, `{"a": 1}`, 0o600)
+
+	err := processInPlace(&cli.Flags{InputFile: path, ToFormat: "yaml", InPlace: true})
+	assert.ErrorContains(t, err, "use -force")
+	assert.Equal(t, `{"a": 1}`, readFile(t, path))
+
+	err = processInPlace(&cli.Flags{InputFile: path, ToFormat: "yaml", InPlace: true, Force: true})
+	require.NoError(t, err)
+	assert.Equal(t, "a: 1\n", readFile(t, path))
+}
+
+func Test_editInPlace_RefusesBinaryFormats(t *testing.T) {
+	err := processInPlace(&cli.Flags{InputFile: filepath.Join(t.TempDir(), "users.avro"), InPlace: true})
+	assert.ErrorContains(t, err, "read-only")
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeFile(t, path, `{"a": 1}`, 0o600)
+	err = processInPlace(&cli.Flags{InputFile: path, ToFormat: "parquet", InPlace: true, Force: true})
+	assert.ErrorContains(t, err, "read-only")
+}
+
+func Test_editInPlace_FailureLeavesFileUntouched(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broken.json")
+	writeFile(t, path, `{"a": `, 0o600)
+
+	err := processInPlace(&cli.Flags{InputFile: path, SetPairs: []string{"a=1"}, InPlace: true, Backup: true})
+	assert.Error(t, err)
+	assert.Equal(t, `{"a": `, readFile(t, path))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func Test_editInPlace_FollowsSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "real.json")
+	link := filepath.Join(dir, "link.json")
+	writeFile(t, target, `{"a": 1}`, 0o600)
+	require.NoError(t, os.Symlink(target, link))
+
+	err := processInPlace(&cli.Flags{InputFile: link, SetPairs: []string{"a=2"}, InPlace: true, Compact: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, "{\"a\":2}\n", readFile(t, target))
+	fi, err := os.Lstat(link)
+	require.NoError(t, err)
+	assert.Equal(t, os.ModeSymlink, fi.Mode().Type())
+}
+
+func Test_processInPlace_Directory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0o755))
+	first := filepath.Join(dir, "a.yaml")
+	second := filepath.Join(dir, "nested", "b.yml")
+	skipped := filepath.Join(dir, "c.json")
+	writeFile(t, first, "env: dev # first\n", 0o644)
+	writeFile(t, second, "env: dev\n", 0o644)
+	writeFile(t, skipped, `{"env": "dev"}`, 0o644)
+
+	err := processInPlace(&cli.Flags{InputDir: dir, FromFormat: "yaml", SetPairs: []string{"env=prod"}, InPlace: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, "env: prod # first\n", readFile(t, first))
+	assert.Equal(t, "env: prod\n", readFile(t, second))
+	assert.Equal(t, `{"env": "dev"}`, readFile(t, skipped))
+}
+
+func Test_editInPlace_KeepsCompression(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json.gz")
+	compressFile(t, path, "gzip", `{"a": 1}`)
+
+	err := processInPlace(&cli.Flags{InputFile: path, SetPairs: []string{"a=2"}, InPlace: true, Compact: true})
+	require.NoError(t, err)
+
+	content, codec := decompressFile(t, path)
+	require.NotNil(t, codec)
+	assert.Equal(t, "gzip", codec.Name)
+	assert.Equal(t, "{\"a\":2}\n", content)
+
+	// -compress changes the codec
+	err = processInPlace(&cli.Flags{InputFile: path, InPlace: true, Compact: true, Compress: "none"})
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":2}\n", readFile(t, path))
+}
+
+func Test_editInPlace_KeepsTopLevelArray(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.json")
+	writeFile(t, path, `[{"a": 1}, {"a": 2}]`, 0o600)
+
+	err := processInPlace(&cli.Flags{InputFile: path, SetPairs: []string{"b=1"}, InPlace: true, Backup: true})
+	require.NoError(t, err)
+
+	var got any
+	require.NoError(t, json.Unmarshal([]byte(readFile(t, path)), &got))
+	assert.Equal(t, []any{
+		map[string]any{"a": float64(1), "b": float64(1)},
+		map[string]any{"a": float64(2), "b": float64(1)},
+	}, got)
+	assert.Equal(t, `[{"a": 1}, {"a": 2}]`, readFile(t, path+".bak"))
+
+	// Documents that are filtered out leave the array, which stays an array
+	err = processInPlace(&cli.Flags{InputFile: path, WherePairs: []string{"a=3"}, InPlace: true})
+	require.NoError(t, err)
+	assert.Equal(t, "[]\n", readFile(t, path))
+}
+
+func Test_editInPlace_RejectsAliasBomb(t *testing.T) {
+	// Each anchor is a list of ten aliases to the one before: 10^8 values when expanded
+	var sb strings.Builder
+	sb.WriteString("a0: &a0 [lol, lol, lol, lol, lol, lol, lol, lol, lol, lol]\n")
+	for i := 1; i <= 8; i++ {
+		prev := fmt.Sprintf("*a%d", i-1)
+		_, _ = fmt.Fprintf(&sb, "a%d: &a%d [%s]\n", i, i, strings.Repeat(prev+", ", 9)+prev)
+	}
+	bomb := sb.String()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bomb.yaml")
+	writeFile(t, path, bomb, 0o644)
+
+	for _, flags := range []cli.Flags{
+		{WherePairs: []string{"a0[0]=lol"}},
+		{FilterExpr: "exists(a8)"},
+		{PickPaths: []string{"a1"}},
+		{SetPairs: []string{"a8[*]=x"}},
+		{DeletePaths: []string{"a8[*]"}},
+	} {
+		flags.InputFile, flags.InPlace = path, true
+		err := processInPlace(&flags)
+		assert.ErrorContains(t, err, "excessive aliasing")
+		assert.Equal(t, bomb, readFile(t, path), "the file is left untouched")
+	}
+}
@@ -0,0 +1,167 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/GeoffMall/flow/internal/cli"
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/operation"
+)
+
+// docBuffer is how many finished documents a file may queue ahead of the writer.
+const docBuffer = 64
+
+// errAbandoned stops a worker once the writer has given up on its file.
+var errAbandoned = errors.New("file abandoned after a write error")
+
+// fileJob is one file of a directory run. A worker parses and transforms it and
+// sends the wrapped documents to out; the calling goroutine writes them.
+type fileJob struct {
+	path string
+	out  chan docItem  // the file's own channel (ordered) or one shared by all files (unordered)
+	stop chan struct{} // closed by the writer when a write fails, to stop the worker
+	err  error         // processing error, set by the worker
+	werr error         // first write error, set by the writer
+}
+
+// docItem is a wrapped document on its way to the formatter.
+type docItem struct {
+	job *fileJob
+	doc any
+}
+
+// emit hands a document to the writer, or gives up if the writer abandoned the file.
+func (j *fileJob) emit(doc any) error {
+	select {
+	case j.out <- docItem{job: j, doc: doc}:
+		return nil
+	case <-j.stop:
+		return errAbandoned
+	}
+}
+
+// write passes a document to formatter unless an earlier one from the same file
+// failed. The first failure abandons the rest of the file, as the sequential
+// loop did by stopping at the first error.
+func (j *fileJob) write(formatter format.Formatter, doc any) {
+	if j.werr != nil {
+		return
+	}
+	if err := formatter.Write(doc); err != nil {
+		j.werr = err
+		close(j.stop)
+	}
+}
+
+// result returns the file's error, if any.
+func (j *fileJob) result() error {
+	if j.werr != nil {
+		return fmt.Errorf("failed to process %s: %w", j.path, j.werr)
+	}
+	return j.err
+}
+
+// processFiles runs files through pipe on opts.Jobs workers and writes the
+// wrapped documents to formatter from the calling goroutine, since formatters
+// are not safe for concurrent use. By default the output follows the order of
+// files, as a sequential run would; with opts.Unordered each document is
+// written as soon as it is ready. Per-file errors are returned in file order.
+func processFiles(files []string, opts *cli.Flags, formatter format.Formatter, pipe *operation.Pipeline) []error {
+	shared := make(chan docItem, docBuffer)
+	jobs := make([]*fileJob, len(files))
+	for i, path := range files {
+		jobs[i] = &fileJob{path: path, out: shared, stop: make(chan struct{})}
+		if !opts.Unordered {
+			jobs[i].out = make(chan docItem, docBuffer)
+		}
+	}
+
+	// Workers take files in order, so in ordered mode the file the writer is
+	// waiting on is always being processed and the run can't deadlock
+	work := make(chan *fileJob)
+	go func() {
+		for _, j := range jobs {
+			work <- j
+		}
+		close(work)
+	}()
+
+	var wg sync.WaitGroup
+	for range workerCount(opts.Jobs, len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range work {
+				j.err = processFileWithMetadata(j.path, opts, j.emit, pipe)
+				if !opts.Unordered {
+					close(j.out)
+				}
+			}
+		}()
+	}
+
+	if opts.Unordered {
+		go func() {
+			wg.Wait()
+			close(shared)
+		}()
+		for item := range shared {
+			item.job.write(formatter, item.doc)
+		}
+	} else {
+		for _, j := range jobs {
+			for item := range j.out {
+				j.write(formatter, item.doc)
+			}
+		}
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, j := range jobs {
+		if err := j.result(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// forEachFile calls fn for each file on up to jobs goroutines and returns the
+// errors in file order. It is for files that produce no shared output.
+func forEachFile(files []string, jobs int, fn func(path string) error) []error {
+	results := make([]error, len(files))
+	work := make(chan int)
+	go func() {
+		for i := range files {
+			work <- i
+		}
+		close(work)
+	}()
+
+	var wg sync.WaitGroup
+	for range workerCount(jobs, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				results[i] = fn(files[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, err := range results {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// workerCount caps jobs at the number of files, with at least one worker.
+func workerCount(jobs, files int) int {
+	return max(1, min(jobs, files))
+}
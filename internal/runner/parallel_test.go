@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/GeoffMall/flow/internal/cli"
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/operation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeJSONFiles creates count JSON files with rows documents each and returns their paths in walk order.
+func writeJSONFiles(t *testing.T, dir string, count, rows int) []string {
+	t.Helper()
+	var paths []string
+	for i := range count {
+		var b strings.Builder
+		for r := range rows {
+			fmt.Fprintf(&b, "{\"file\": %d, \"row\": %d}\n", i, r)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("part-%03d.json", i))
+		writeFile(t, path, b.String(), 0o644)
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func runDirectory(t *testing.T, opts *cli.Flags) ([]string, error) {
+	t.Helper()
+	opts.OutputFile = filepath.Join(t.TempDir(), "out.json")
+	opts.Compact = true
+	err := processDirectory(opts)
+	content, rerr := os.ReadFile(opts.OutputFile)
+	require.NoError(t, rerr)
+	return strings.Split(strings.TrimSpace(string(content)), "\n"), err
+}
+
+func Test_processDirectory_ParallelOrdered(t *testing.T) {
+	dir := t.TempDir()
+	writeJSONFiles(t, dir, 20, 100)
+
+	sequential, err := runDirectory(t, &cli.Flags{InputDir: dir, Jobs: 1})
+	require.NoError(t, err)
+	require.Len(t, sequential, 2000)
+
+	parallel, err := runDirectory(t, &cli.Flags{InputDir: dir, Jobs: 8})
+	require.NoError(t, err)
+	assert.Equal(t, sequential, parallel)
+}
+
+func Test_processDirectory_ParallelUnordered(t *testing.T) {
+	dir := t.TempDir()
+	writeJSONFiles(t, dir, 20, 100)
+
+	sequential, err := runDirectory(t, &cli.Flags{InputDir: dir, Jobs: 1})
+	require.NoError(t, err)
+
+	unordered, err := runDirectory(t, &cli.Flags{InputDir: dir, Jobs: 8, Unordered: true})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, sequential, unordered)
+}
+
+func Test_processDirectory_ParallelCollectsErrors(t *testing.T) {
+	for _, unordered := range []bool{false, true} {
+		t.Run(fmt.Sprintf("unordered=%v", unordered), func(t *testing.T) {
+			dir := t.TempDir()
+			writeJSONFiles(t, dir, 6, 10)
+			writeFile(t, filepath.Join(dir, "part-002.json"), `{"file": 2, "row": `, 0o644)
+
+			lines, err := runDirectory(t, &cli.Flags{InputDir: dir, Jobs: 3, Unordered: unordered})
+			assert.ErrorContains(t, err, "completed with 1 error(s)")
+			assert.Len(t, lines, 50)
+			for _, line := range lines {
+				assert.NotContains(t, line, "part-002.json")
+			}
+		})
+	}
+}
+
+// failingFormatter fails on a given document, like a schema mismatch would.
+type failingFormatter struct {
+	written []any
+	failOn  func(doc any) bool
+}
+
+func (f *failingFormatter) Write(doc any) error {
+	if f.failOn(doc) {
+		return fmt.Errorf("does not fit")
+	}
+	f.written = append(f.written, doc)
+	return nil
+}
+
+func (f *failingFormatter) Close() error { return nil }
+
+var _ format.Formatter = (*failingFormatter)(nil)
+
+func Test_processFiles_WriteErrorAbandonsFile(t *testing.T) {
+	for _, unordered := range []bool{false, true} {
+		t.Run(fmt.Sprintf("unordered=%v", unordered), func(t *testing.T) {
+			files := writeJSONFiles(t, t.TempDir(), 4, 500)
+			bad := files[1]
+			formatter := &failingFormatter{failOn: func(doc any) bool {
+				m := doc.(map[string]any)
+				return m["_file"] == bad && m["_row"] == 3
+			}}
+
+			errs := processFiles(files, &cli.Flags{Jobs: 2, Unordered: unordered}, formatter, operation.NewPipeline())
+			require.Len(t, errs, 1)
+			assert.ErrorContains(t, errs[0], bad)
+			assert.ErrorContains(t, errs[0], "does not fit")
+
+			// Rows before the failure are kept, the rest of the file is dropped
+			var badRows []int
+			for _, doc := range formatter.written {
+				if m := doc.(map[string]any); m["_file"] == bad {
+					badRows = append(badRows, m["_row"].(int))
+				}
+			}
+			slices.Sort(badRows)
+			assert.Equal(t, []int{1, 2}, badRows)
+			assert.Len(t, formatter.written, 3*500+2)
+		})
+	}
+}
+
+func Test_forEachFile_ErrorsInFileOrder(t *testing.T) {
+	files := []string{"a", "b", "c", "d", "e"}
+	errs := forEachFile(files, 3, func(path string) error {
+		if path == "b" || path == "d" {
+			return fmt.Errorf("failed %s", path)
+		}
+		return nil
+	})
+	assert.Equal(t, []error{fmt.Errorf("failed b"), fmt.Errorf("failed d")}, errs)
+}
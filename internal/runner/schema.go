@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/GeoffMall/flow/internal/cli"
+	"github.com/GeoffMall/flow/internal/format"
+	"github.com/GeoffMall/flow/internal/inspect"
+	"github.com/GeoffMall/flow/internal/operation"
+	"github.com/GeoffMall/flow/internal/ordered"
+)
+
+// runSchema writes a description of the input to out instead of its
+// documents (flow schema). Avro and Parquet files describe themselves; for
+// other formats a schema is inferred from every document in the input.
+func runSchema(in io.Reader, out io.Writer, opts *cli.Flags) (err error) {
+	// -to picks how the report is written, not how the input is read
+	parseOpts := *opts
+	parseOpts.ToFormat = ""
+
+	parser, err := newParser(in, &parseOpts, operation.NewPipeline())
+	if err != nil {
+		return err
+	}
+	defer closeParser(parser)
+
+	report, err := describe(parser, determineInputFormat(opts))
+	if err != nil {
+		return err
+	}
+
+	formatter, err := newFormatter(out, opts)
+	if err != nil {
+		return err
+	}
+	defer closeFormatter(formatter, &err)
+
+	return formatter.Write(report)
+}
+
+// describe returns the schema report for the input of parser, which is in
+// the named format.
+func describe(parser format.Parser, formatName string) (any, error) {
+	if d, ok := parser.(format.Describer); ok {
+		report, err := d.Describe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe input: %w", err)
+		}
+		return report, nil
+	}
+
+	inferrer := inspect.NewInferrer()
+	if err := parser.ForEach(func(doc any) error {
+		inferrer.Add(doc)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	report := ordered.NewMap()
+	report.Set("format", formatName)
+	for k, v := range inferrer.Report().All() {
+		report.Set(k, v)
+	}
+	return report, nil
+}
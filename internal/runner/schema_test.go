@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/GeoffMall/flow/internal/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_runSchema_Inferred(t *testing.T) {
+	in := "name: Alice\nage: 30\n---\nname: Bob\n"
+	var out bytes.Buffer
+	err := runSchema(strings.NewReader(in), &out, &cli.Flags{FromFormat: "yaml", ToFormat: "yaml"})
+	require.NoError(t, err)
+
+	assert.Equal(t, `format: yaml
+documents: 2
+types:
+  - object
+fields:
+  - path: name
+    types:
+      - string
+    count: 2
+    optional: false
+    examples:
+      - Alice
+      - Bob
+  - path: age
+    types:
+      - integer
+    count: 1
+    optional: true
+    examples:
+      - 30
+`, out.String())
+}
+
+func Test_runSchema_Parquet(t *testing.T) {
+	file, err := os.Open("../../internal/format/parquet/testdata/users.parquet")
+	require.NoError(t, err)
+	defer file.Close()
+
+	var out bytes.Buffer
+	require.NoError(t, runSchema(file, &out, &cli.Flags{FromFormat: "parquet", Compact: true}))
+	assert.Contains(t, out.String(), `"format":"parquet","rows":3`)
+	assert.Contains(t, out.String(), `{"path":"age","type":"INT32","logical_type":"INT(32,true)","repetition":"required"}`)
+	assert.Contains(t, out.String(), `"min":"Alice","max":"Charlie"`)
+}
+
+func Test_runSchema_Avro(t *testing.T) {
+	file, err := os.Open("../../testdata/dir-test/users.avro")
+	require.NoError(t, err)
+	defer file.Close()
+
+	var out bytes.Buffer
+	require.NoError(t, runSchema(file, &out, &cli.Flags{FromFormat: "avro", Compact: true}))
+	assert.True(t, strings.HasPrefix(out.String(), `{"format":"avro","codec":`), out.String())
+	assert.Contains(t, out.String(), `"schema":{`)
+}
+
+func Test_runSchema_InvalidInput(t *testing.T) {
+	var out bytes.Buffer
+	err := runSchema(strings.NewReader(`{"a": `), &out, &cli.Flags{FromFormat: "json"})
+	assert.Error(t, err)
+}
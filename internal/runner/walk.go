@@ -0,0 +1,217 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/GeoffMall/flow/internal/cli"
+	"github.com/GeoffMall/flow/internal/compression"
+)
+
+// fileSelector decides which files under -in-dir are processed.
+type fileSelector struct {
+	extensions     []string // file extensions of the input format
+	include        []string // glob patterns a file must match one of (all files if empty)
+	exclude        []string // glob patterns of files and directories to skip
+	maxDepth       int      // directory levels to descend, 1 being only the files directly in the root (0 = unlimited)
+	followSymlinks bool     // walk into symlinked directories
+	hidden         bool     // include files and directories whose name starts with a dot
+}
+
+// newFileSelector builds the selector for the directory flags in opts.
+func newFileSelector(opts *cli.Flags) (*fileSelector, error) {
+	extensions, err := directoryExtensions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pattern := range slices.Concat(opts.IncludeGlobs, opts.ExcludeGlobs) {
+		if err := validateGlob(pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	return &fileSelector{
+		extensions:     extensions,
+		include:        opts.IncludeGlobs,
+		exclude:        opts.ExcludeGlobs,
+		maxDepth:       opts.MaxDepth,
+		followSymlinks: opts.FollowSymlinks,
+		hidden:         opts.Hidden,
+	}, nil
+}
+
+// directoryExtensions returns the file extensions -in-dir processes for the -from format.
+func directoryExtensions(opts *cli.Flags) ([]string, error) {
+	switch opts.FromFormat {
+	case "avro":
+		return []string{".avro"}, nil
+	case "parquet":
+		return []string{".parquet"}, nil
+	case "yaml":
+		return []string{".yaml", ".yml"}, nil
+	case "csv":
+		return []string{".csv"}, nil
+	case "tsv":
+		return []string{".tsv"}, nil
+	case "json", "":
+		return []string{".json"}, nil
+	case autoFormat:
+		return autoExtensions(), nil
+	default:
+		return nil, fmt.Errorf("unknown format for directory processing: %s", opts.FromFormat)
+	}
+}
+
+// findFiles walks root and returns the selected files in lexical order, like
+// filepath.WalkDir. Entries that cannot be read are collected as errors and
+// skipped; the returned error is only set if root itself cannot be read.
+func (s *fileSelector) findFiles(root string) ([]string, []error, error) {
+	w := &walker{sel: s, visited: map[string]bool{}}
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		w.visited[real] = true
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	w.walkEntries(root, "", entries, 1)
+	return w.files, w.errors, nil
+}
+
+// walker holds the state of one findFiles call.
+type walker struct {
+	sel     *fileSelector
+	files   []string
+	errors  []error
+	visited map[string]bool // real paths of directories already walked, to stop symlink loops
+}
+
+// walkEntries visits the entries of the directory at dir, whose path relative
+// to the root is rel, depth levels below the root.
+//
+//nolint:cyclop // One check per selection rule
+func (w *walker) walkEntries(dir, rel string, entries []os.DirEntry, depth int) {
+	for _, entry := range entries {
+		name := entry.Name()
+		if !w.sel.hidden && strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		full := filepath.Join(dir, name)
+		relPath := path.Join(rel, name)
+		isDir := entry.IsDir()
+
+		if entry.Type()&os.ModeSymlink != 0 {
+			info, err := os.Stat(full)
+			if err != nil {
+				w.errors = append(w.errors, fmt.Errorf("error accessing %s: %w", full, err))
+				continue
+			}
+			isDir = info.IsDir()
+			if isDir && !w.sel.followSymlinks {
+				continue
+			}
+		}
+
+		if matchesAny(w.sel.exclude, relPath) {
+			continue
+		}
+
+		if isDir {
+			if w.sel.maxDepth == 0 || depth < w.sel.maxDepth {
+				w.walkDir(full, relPath, depth+1)
+			}
+			continue
+		}
+
+		// Compressed files are matched by the extension before the compression one
+		if slices.Contains(w.sel.extensions, compression.Ext(name)) &&
+			(len(w.sel.include) == 0 || matchesAny(w.sel.include, relPath)) {
+			w.files = append(w.files, full)
+		}
+	}
+}
+
+// walkDir reads a subdirectory and walks it, once per real directory.
+func (w *walker) walkDir(dir, rel string, depth int) {
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		w.errors = append(w.errors, fmt.Errorf("error accessing %s: %w", dir, err))
+		return
+	}
+	if w.visited[real] {
+		return
+	}
+	w.visited[real] = true
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		w.errors = append(w.errors, fmt.Errorf("error accessing %s: %w", dir, err))
+		return
+	}
+	w.walkEntries(dir, rel, entries, depth)
+}
+
+// ----------------------------- Globs -----------------------------
+
+// Patterns are matched against slash-separated paths relative to -in-dir.
+// Each segment uses path.Match syntax (*, ?, [...]) and a "**" segment matches
+// any number of directories, including none. A pattern without a slash is
+// matched against the file or directory name alone, at any depth.
+
+// validateGlob reports a malformed pattern.
+func validateGlob(pattern string) error {
+	for seg := range strings.SplitSeq(pattern, "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchesAny reports whether rel matches any of patterns.
+func matchesAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether the relative path rel matches pattern.
+func matchGlob(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments against pattern segments, backtracking over "**".
+func matchSegments(pattern, segs []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(pattern[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segs[0]); !ok {
+			return false
+		}
+		pattern, segs = pattern[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GeoffMall/flow/internal/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_matchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{"*.parquet", "a.parquet", true},
+		{"*.parquet", "x/y/a.parquet", true},
+		{"*.parquet", "a.avro", false},
+		{"date=2024-*/**/*.parquet", "date=2024-01/a.parquet", true},
+		{"date=2024-*/**/*.parquet", "date=2024-01/hour=03/part/a.parquet", true},
+		{"date=2024-*/**/*.parquet", "date=2023-12/a.parquet", false},
+		{"date=2024-*/**/*.parquet", "x/date=2024-01/a.parquet", false},
+		{"**/_tmp", "_tmp", true},
+		{"**/_tmp", "a/b/_tmp", true},
+		{"**/_tmp", "a/b/_tmp/c", false},
+		{"a/**", "a/b/c", true},
+		{"a/*/c", "a/b/c", true},
+		{"a/*/c", "a/b/x/c", false},
+		{"a/[0-9]?/c", "a/12/c", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchGlob(tt.pattern, tt.rel), "%s vs %s", tt.pattern, tt.rel)
+	}
+}
+
+func Test_newFileSelector_InvalidPattern(t *testing.T) {
+	_, err := newFileSelector(&cli.Flags{IncludeGlobs: []string{"a/[b"}})
+	assert.ErrorContains(t, err, "invalid pattern")
+}
+
+// makeTree creates the given files (with empty JSON objects) under a new directory.
+func makeTree(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, f := range files {
+		path := filepath.Join(root, filepath.FromSlash(f))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		writeFile(t, path, "{}", 0o644)
+	}
+	return root
+}
+
+func findRelative(t *testing.T, root string, opts *cli.Flags) []string {
+	t.Helper()
+	sel, err := newFileSelector(opts)
+	require.NoError(t, err)
+	files, errs, err := sel.findFiles(root)
+	require.NoError(t, err)
+	require.Empty(t, errs)
+
+	rel := make([]string, len(files))
+	for i, f := range files {
+		r, err := filepath.Rel(root, f)
+		require.NoError(t, err)
+		rel[i] = filepath.ToSlash(r)
+	}
+	return rel
+}
+
+func Test_findFiles_Selection(t *testing.T) {
+	root := makeTree(t,
+		"top.json",
+		"notes.txt",
+		".hidden.json",
+		".git/config.json",
+		"date=2024-01/a.json",
+		"date=2024-01/hour=1/b.json",
+		"date=2023-12/c.json",
+		"date=2024-02/_tmp/d.json",
+	)
+
+	assert.Equal(t, []string{
+		"date=2023-12/c.json",
+		"date=2024-01/a.json",
+		"date=2024-01/hour=1/b.json",
+		"date=2024-02/_tmp/d.json",
+		"top.json",
+	}, findRelative(t, root, &cli.Flags{}))
+
+	assert.Equal(t, []string{
+		"date=2024-01/a.json",
+		"date=2024-01/hour=1/b.json",
+	}, findRelative(t, root, &cli.Flags{IncludeGlobs: []string{"date=2024-*/**/*.json"}, ExcludeGlobs: []string{"_tmp"}}))
+
+	assert.Equal(t, []string{"top.json"}, findRelative(t, root, &cli.Flags{MaxDepth: 1}))
+	assert.Equal(t, []string{
+		"date=2023-12/c.json",
+		"date=2024-01/a.json",
+		"top.json",
+	}, findRelative(t, root, &cli.Flags{MaxDepth: 2}))
+
+	assert.Equal(t, []string{".git/config.json", ".hidden.json"},
+		findRelative(t, root, &cli.Flags{Hidden: true, IncludeGlobs: []string{".*", ".*/**"}}))
+}
+
+func Test_findFiles_Symlinks(t *testing.T) {
+	root := makeTree(t, "data/a.json")
+	outside := makeTree(t, "b.json")
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "linked")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "b.json"), filepath.Join(root, "file.json")))
+	// A loop back to the root must not be walked forever
+	require.NoError(t, os.Symlink(root, filepath.Join(root, "data", "loop")))
+
+	assert.Equal(t, []string{"data/a.json", "file.json"}, findRelative(t, root, &cli.Flags{}))
+	assert.Equal(t, []string{"data/a.json", "file.json", "linked/b.json"},
+		findRelative(t, root, &cli.Flags{FollowSymlinks: true}))
+}